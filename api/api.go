@@ -4,6 +4,7 @@ package api
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/aptly-dev/aptly/aptly"
@@ -105,6 +106,17 @@ func acquireDatabase(requests <-chan dbRequest) {
 	}
 }
 
+// Returns name of authenticated user (as passed by authenticating
+// reverse proxy in configured header) to be matched against uploaders rules
+func uploaderUser(c *gin.Context) string {
+	header := context.Config().UploadersUserHeader
+	if header == "" {
+		return ""
+	}
+
+	return c.Request.Header.Get(header)
+}
+
 // Common piece of code to show list of packages,
 // with searching & details if requested
 func showPackages(c *gin.Context, reflist *deb.PackageRefList) {
@@ -152,6 +164,51 @@ func showPackages(c *gin.Context, reflist *deb.PackageRefList) {
 		}
 	}
 
+	parseRetention := func(param string) (int, error) {
+		valueS := c.Request.URL.Query().Get(param)
+		if valueS == "" {
+			return 0, nil
+		}
+
+		value, err := strconv.Atoi(valueS)
+		if err != nil {
+			return 0, fmt.Errorf("unable to parse %s: %s", param, err)
+		}
+
+		if value < 0 {
+			return 0, fmt.Errorf("%s should not be negative", param)
+		}
+
+		return value, nil
+	}
+
+	keepVersions, err := parseRetention("keepVersions")
+	if err != nil {
+		c.AbortWithError(400, err)
+		return
+	}
+
+	keepDays, err := parseRetention("keepDays")
+	if err != nil {
+		c.AbortWithError(400, err)
+		return
+	}
+
+	if keepVersions > 0 || keepDays > 0 {
+		retained, err := deb.NewPackageRefListFromPackageList(list).RetainRefs(keepVersions,
+			time.Duration(keepDays)*24*time.Hour, context.CollectionFactory().PackageCollection())
+		if err != nil {
+			c.AbortWithError(500, err)
+			return
+		}
+
+		list, err = deb.NewPackageListFromRefList(retained, context.CollectionFactory().PackageCollection(), nil)
+		if err != nil {
+			c.AbortWithError(500, err)
+			return
+		}
+	}
+
 	if c.Request.URL.Query().Get("format") == "details" {
 		list.ForEach(func(p *deb.Package) error {
 			result = append(result, p)
@@ -163,3 +220,59 @@ func showPackages(c *gin.Context, reflist *deb.PackageRefList) {
 		c.JSON(200, list.Strings())
 	}
 }
+
+// reverseDependency is a package which depends on the package being looked up
+type reverseDependency struct {
+	Package    string
+	Key        string
+	Field      string
+	Dependency string
+}
+
+// showReverseDependencies lists packages from reflist depending on packages matching query
+func showReverseDependencies(c *gin.Context, reflist *deb.PackageRefList) {
+	queryS := c.Request.URL.Query().Get("q")
+	if queryS == "" {
+		c.AbortWithError(400, fmt.Errorf("query is required"))
+		return
+	}
+
+	q, err := query.Parse(queryS)
+	if err != nil {
+		c.AbortWithError(400, err)
+		return
+	}
+
+	list, err := deb.NewPackageListFromRefList(reflist, context.CollectionFactory().PackageCollection(), nil)
+	if err != nil {
+		c.AbortWithError(404, err)
+		return
+	}
+
+	list.PrepareIndex()
+
+	matched, err := list.Filter([]deb.PackageQuery{q}, false, nil, 0, nil)
+	if err != nil {
+		c.AbortWithError(500, fmt.Errorf("unable to search: %s", err))
+		return
+	}
+
+	index := deb.NewReverseDependencyIndex(list, context.DependencyOptions()|deb.DepFollowBuild)
+	result := map[string][]reverseDependency{}
+
+	matched.ForEach(func(p *deb.Package) error {
+		rdeps := []reverseDependency{}
+		for _, rdep := range index.Lookup(p) {
+			rdeps = append(rdeps, reverseDependency{
+				Package:    rdep.Package.String(),
+				Key:        string(rdep.Package.Key("")),
+				Field:      rdep.Field,
+				Dependency: rdep.Dependency,
+			})
+		}
+		result[string(p.Key(""))] = rdeps
+		return nil
+	})
+
+	c.JSON(200, result)
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"runtime"
+
+	"github.com/aptly-dev/aptly/aptly"
+	"github.com/aptly-dev/aptly/deb"
+	"github.com/aptly-dev/aptly/http"
+	"github.com/gin-gonic/gin"
+)
+
+// POST /api/db/verify
+func apiDbVerify(c *gin.Context) {
+	var b struct {
+		// Re-download missing and corrupt files of mirrored packages
+		Repair bool
+	}
+
+	if c.Request.ContentLength > 0 && c.Bind(&b) != nil {
+		return
+	}
+
+	collectionFactory := context.CollectionFactory()
+
+	report, err := deb.VerifyPoolFiles(collectionFactory.PackageCollection().AllPackageRefs(), collectionFactory.PackageCollection(),
+		context.PackagePool(), collectionFactory.ChecksumCollection(nil), runtime.NumCPU(), nil)
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+
+	if b.Repair && len(report.Problems) > 0 {
+		collection := collectionFactory.RemoteRepoCollection()
+		collection.Lock()
+		defer collection.Unlock()
+
+		mirrors := []*deb.RemoteRepo{}
+		err = collection.ForEach(func(repo *deb.RemoteRepo) error {
+			e := collection.LoadComplete(repo)
+			if e != nil {
+				return e
+			}
+
+			mirrors = append(mirrors, repo)
+			return nil
+		})
+		if err != nil {
+			c.AbortWithError(500, err)
+			return
+		}
+
+		var limiter *http.Limiter
+		limiter, err = context.DownloadLimiter()
+		if err != nil {
+			c.AbortWithError(500, err)
+			return
+		}
+		// quota is per repair, as it is per mirror update
+		limiter.ResetUsage()
+
+		err = deb.RepairPoolFiles(report.Problems, mirrors, func(repo *deb.RemoteRepo) (aptly.Downloader, error) {
+			var (
+				downloader aptly.Downloader
+				e          error
+			)
+
+			if repo.IsLocal() {
+				downloader, e = context.LocalDownloaderWithOptions(repo.DownloadOptions)
+			} else {
+				downloader, e = context.DownloaderWithOptions(repo.DownloadOptions)
+			}
+			if e != nil {
+				return nil, e
+			}
+
+			if len(repo.FallbackArchiveRoots) > 0 {
+				downloader = http.NewFailoverDownloader(downloader, append([]string{repo.ArchiveRoot}, repo.FallbackArchiveRoots...),
+					repo.RoundRobinArchiveRoots)
+			}
+
+			return downloader, nil
+		}, collectionFactory.PackageCollection(), context.PackagePool(), collectionFactory.ChecksumCollection(nil), nil)
+		if err != nil {
+			c.AbortWithError(500, err)
+			return
+		}
+	}
+
+	c.JSON(200, report)
+}
+
+// GET /api/db/usage
+func apiDbUsage(c *gin.Context) {
+	report, err := deb.ComputeUsage(context.CollectionFactory(), context.PackagePool(), nil)
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+
+	c.JSON(200, report)
+}
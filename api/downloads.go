@@ -0,0 +1,68 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limits are kept in memory of the API server process, so they apply to downloads made by
+// API server itself (pool repair), not to aptly commands run separately: there's no way yet
+// to change limits of running 'aptly mirror update'.
+//
+// All the values are in bytes (bytes/sec for speed), both in requests and responses.
+
+// GET /api/downloads/limits
+func apiDownloadsLimitsShow(c *gin.Context) {
+	limiter, err := context.DownloadLimiter()
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+
+	c.JSON(200, limiter.Status())
+}
+
+// PUT /api/downloads/limits
+func apiDownloadsLimitsUpdate(c *gin.Context) {
+	var b struct {
+		// Speed limit override (bytes/sec), 0 is unlimited
+		Limit *int64
+		// Return to scheduled (or default) speed limit
+		ResetLimit bool
+		// Download quota (bytes), 0 is unlimited
+		Quota *int64
+	}
+
+	if c.Bind(&b) != nil {
+		return
+	}
+
+	if b.Limit != nil && *b.Limit < 0 || b.Quota != nil && *b.Quota < 0 {
+		c.AbortWithError(400, fmt.Errorf("Limit and Quota should not be negative"))
+		return
+	}
+
+	if b.ResetLimit && b.Limit != nil {
+		c.AbortWithError(400, fmt.Errorf("Limit and ResetLimit can't be used together"))
+		return
+	}
+
+	limiter, err := context.DownloadLimiter()
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+
+	if b.ResetLimit {
+		limiter.ClearLimitOverride()
+	} else if b.Limit != nil {
+		limiter.SetLimitOverride(*b.Limit)
+	}
+
+	if b.Quota != nil {
+		limiter.SetQuota(*b.Quota)
+	}
+
+	c.JSON(200, limiter.Status())
+}
@@ -12,7 +12,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// GET /api/graph.:ext?layout=[vertical|horizontal(default)]
+// GET /api/graph.:ext?layout=[vertical|horizontal(default)]&name=name
+//
+// Formats json and html are rendered natively, other formats require graphviz
 func apiGraph(c *gin.Context) {
 	var (
 		err    error
@@ -21,6 +23,7 @@ func apiGraph(c *gin.Context) {
 
 	ext := c.Params.ByName("ext")
 	layout := c.Request.URL.Query().Get("layout")
+	name := c.Request.URL.Query().Get("name")
 
 	factory := context.CollectionFactory()
 
@@ -33,12 +36,37 @@ func apiGraph(c *gin.Context) {
 	factory.PublishedRepoCollection().Lock()
 	defer factory.PublishedRepoCollection().Unlock()
 
-	graph, err := deb.BuildGraph(factory, layout)
+	graphData, err := deb.BuildGraphData(factory)
 	if err != nil {
 		c.JSON(500, err)
 		return
 	}
 
+	if name != "" {
+		graphData = graphData.Filter(name)
+		if len(graphData.Nodes) == 0 {
+			c.AbortWithError(404, fmt.Errorf("unable to find mirror, local repo, snapshot or published repository %s", name))
+			return
+		}
+	}
+
+	switch ext {
+	case "json":
+		c.JSON(200, graphData)
+		return
+	case "html":
+		output, err = graphData.HTML()
+		if err != nil {
+			c.AbortWithError(500, err)
+			return
+		}
+
+		c.Data(200, "text/html; charset=utf-8", output)
+		return
+	}
+
+	graph := graphData.Dot(layout)
+
 	buf := bytes.NewBufferString(graph.String())
 
 	if ext == "dot" || ext == "gv" {
@@ -4,13 +4,32 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// GET /api/packages/:key
+// GET /api/packages/:key?provenance=1
 func apiPackagesShow(c *gin.Context) {
-	p, err := context.CollectionFactory().PackageCollection().ByKey([]byte(c.Params.ByName("key")))
+	key := []byte(c.Params.ByName("key"))
+
+	p, err := context.CollectionFactory().PackageCollection().ByKey(key)
 	if err != nil {
 		c.AbortWithError(404, err)
 		return
 	}
 
-	c.JSON(200, p)
+	if c.Request.URL.Query().Get("provenance") != "1" {
+		c.JSON(200, p)
+		return
+	}
+
+	provenance, err := context.CollectionFactory().PackageCollection().Provenance(key)
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+
+	result := map[string]interface{}{}
+	for k, v := range p.ExtendedStanza() {
+		result[k] = v
+	}
+	result["Provenance"] = provenance
+
+	c.JSON(200, result)
 }
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aptly-dev/aptly/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// GET /api/pool/sha256/:sha256/:filename?size=<bytes>
+//
+// Serves package file from the pool by its checksum, so that other aptly instances
+// could fetch files before going to upstream
+func apiPoolFile(c *gin.Context) {
+	size, err := strconv.ParseInt(c.Query("size"), 10, 64)
+	if err != nil {
+		c.AbortWithError(400, fmt.Errorf("invalid size: %s", err))
+		return
+	}
+
+	checksums := utils.ChecksumInfo{
+		Size:   size,
+		SHA256: c.Params.ByName("sha256"),
+	}
+	if _, err = hex.DecodeString(checksums.SHA256); err != nil || len(checksums.SHA256) != 64 {
+		c.AbortWithError(400, fmt.Errorf("invalid sha256: %s", checksums.SHA256))
+		return
+	}
+
+	filename := c.Params.ByName("filename")
+
+	poolPath, exists, err := context.PackagePool().Verify("", filename, &checksums, context.CollectionFactory().ChecksumCollection(nil))
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+	if !exists {
+		c.AbortWithError(404, fmt.Errorf("file %s (sha256 %s) not found in the pool", filename, checksums.SHA256))
+		return
+	}
+
+	file, err := context.PackagePool().Open(poolPath)
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+	defer file.Close()
+
+	http.ServeContent(c.Writer, c.Request, filename, time.Time{}, file)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/aptly-dev/aptly/aptly"
 	"github.com/aptly-dev/aptly/database"
@@ -181,6 +182,27 @@ func apiReposPackagesShow(c *gin.Context) {
 	showPackages(c, repo.RefList())
 }
 
+// GET /api/repos/:name/rdepends?q=<package-query>
+func apiReposReverseDependencies(c *gin.Context) {
+	collection := context.CollectionFactory().LocalRepoCollection()
+	collection.Lock()
+	defer collection.Unlock()
+
+	repo, err := collection.ByName(c.Params.ByName("name"))
+	if err != nil {
+		c.AbortWithError(404, err)
+		return
+	}
+
+	err = collection.LoadComplete(repo)
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+
+	showReverseDependencies(c, repo.RefList())
+}
+
 // Handler for both add and delete
 func apiReposPackagesAddDelete(c *gin.Context, cb func(list *deb.PackageList, p *deb.Package) error) {
 	var b struct {
@@ -260,6 +282,105 @@ func apiReposPackagesDelete(c *gin.Context) {
 	})
 }
 
+// POST /repos/:name/retain
+//
+// KeepDays counts from package provenance date, i.e. from the moment package
+// has been first seen by aptly, not from the moment it was added to the repo
+func apiReposPackagesRetain(c *gin.Context) {
+	var b struct {
+		Queries      []string
+		KeepVersions int
+		KeepDays     int
+		DryRun       bool
+	}
+
+	if c.Bind(&b) != nil {
+		return
+	}
+
+	if b.KeepVersions < 0 || b.KeepDays < 0 {
+		c.AbortWithError(400, fmt.Errorf("KeepVersions and KeepDays should not be negative"))
+		return
+	}
+
+	if b.KeepVersions == 0 && b.KeepDays == 0 {
+		c.AbortWithError(400, fmt.Errorf("either KeepVersions or KeepDays is required"))
+		return
+	}
+
+	queries := make([]deb.PackageQuery, len(b.Queries))
+	for i, q := range b.Queries {
+		var err error
+		queries[i], err = query.Parse(q)
+		if err != nil {
+			c.AbortWithError(400, fmt.Errorf("unable to parse query: %s", err))
+			return
+		}
+	}
+
+	if len(queries) == 0 {
+		queries = []deb.PackageQuery{&deb.MatchAllQuery{}}
+	}
+
+	collection := context.CollectionFactory().LocalRepoCollection()
+	collection.Lock()
+	defer collection.Unlock()
+
+	repo, err := collection.ByName(c.Params.ByName("name"))
+	if err != nil {
+		c.AbortWithError(404, err)
+		return
+	}
+
+	err = collection.LoadComplete(repo)
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+
+	list, err := deb.NewPackageListFromRefList(repo.RefList(), context.CollectionFactory().PackageCollection(), nil)
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+
+	list.PrepareIndex()
+	toRemove, err := list.Filter(queries, false, nil, 0, nil)
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+
+	retained, err := repo.RefList().RetainRefs(b.KeepVersions, time.Duration(b.KeepDays)*24*time.Hour,
+		context.CollectionFactory().PackageCollection())
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+
+	removed := []string{}
+
+	toRemove.ForEach(func(p *deb.Package) error {
+		if !retained.Has(p) {
+			list.Remove(p)
+			removed = append(removed, p.String())
+		}
+		return nil
+	})
+
+	if !b.DryRun {
+		repo.UpdateRefList(deb.NewPackageRefListFromPackageList(list))
+
+		err = collection.Update(repo)
+		if err != nil {
+			c.AbortWithError(500, fmt.Errorf("unable to save: %s", err))
+			return
+		}
+	}
+
+	c.JSON(200, gin.H{"Repo": repo, "Removed": removed})
+}
+
 // POST /repos/:name/file/:dir/:file
 func apiReposPackageFromFile(c *gin.Context) {
 	// redirect all work to dir method
@@ -326,8 +447,17 @@ func apiReposPackageFromDir(c *gin.Context) {
 		return
 	}
 
+	if repo.Uploaders != nil {
+		err = repo.Uploaders.Compile(query.Parse)
+		if err != nil {
+			c.AbortWithError(500, err)
+			return
+		}
+	}
+
 	processedFiles, failedFiles2, err = deb.ImportPackageFiles(list, packageFiles, forceReplace, verifier, context.PackagePool(),
-		context.CollectionFactory().PackageCollection(), reporter, nil, context.CollectionFactory().ChecksumCollection)
+		context.CollectionFactory().PackageCollection(), reporter, nil, context.CollectionFactory().ChecksumCollection,
+		repo.Uploaders, uploaderUser(c), deb.NewLocalRepoProvenance(repo, uploaderUser(c)))
 	failedFiles = append(failedFiles, failedFiles2...)
 
 	processedFiles = append(processedFiles, otherFiles...)
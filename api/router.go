@@ -67,6 +67,8 @@ func Router(c *ctx.AptlyContext) http.Handler {
 		root.GET("/repos/:name/packages", apiReposPackagesShow)
 		root.POST("/repos/:name/packages", apiReposPackagesAdd)
 		root.DELETE("/repos/:name/packages", apiReposPackagesDelete)
+		root.GET("/repos/:name/rdepends", apiReposReverseDependencies)
+		root.POST("/repos/:name/retain", apiReposPackagesRetain)
 
 		root.POST("/repos/:name/file/:dir/:file", apiReposPackageFromFile)
 		root.POST("/repos/:name/file/:dir", apiReposPackageFromDir)
@@ -78,6 +80,7 @@ func Router(c *ctx.AptlyContext) http.Handler {
 	}
 
 	{
+		root.GET("/mirrors/:name/history", apiMirrorsHistory)
 		root.POST("/mirrors/:name/snapshots", apiSnapshotsCreateFromMirror)
 	}
 
@@ -103,8 +106,10 @@ func Router(c *ctx.AptlyContext) http.Handler {
 		root.PUT("/snapshots/:name", apiSnapshotsUpdate)
 		root.GET("/snapshots/:name", apiSnapshotsShow)
 		root.GET("/snapshots/:name/packages", apiSnapshotsSearchPackages)
+		root.GET("/snapshots/:name/rdepends", apiSnapshotsReverseDependencies)
 		root.DELETE("/snapshots/:name", apiSnapshotsDrop)
 		root.GET("/snapshots/:name/diff/:withSnapshot", apiSnapshotsDiff)
+		root.GET("/snapshots/:name/verify", apiSnapshotsVerify)
 	}
 
 	{
@@ -115,5 +120,19 @@ func Router(c *ctx.AptlyContext) http.Handler {
 		root.GET("/graph.:ext", apiGraph)
 	}
 
+	{
+		root.GET("/pool/sha256/:sha256/:filename", apiPoolFile)
+	}
+
+	{
+		root.POST("/db/verify", apiDbVerify)
+		root.GET("/db/usage", apiDbUsage)
+	}
+
+	{
+		root.GET("/downloads/limits", apiDownloadsLimitsShow)
+		root.PUT("/downloads/limits", apiDownloadsLimitsUpdate)
+	}
+
 	return router
 }
@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/aptly-dev/aptly/database"
 	"github.com/aptly-dev/aptly/deb"
@@ -158,6 +159,7 @@ func apiSnapshotsCreate(c *gin.Context) {
 	}
 
 	snapshot = deb.NewSnapshotFromRefList(b.Name, sources, deb.NewPackageRefListFromPackageList(list), b.Description)
+	snapshot.ExtraFiles, _ = deb.MergeExtraFiles(sources)
 
 	err = snapshotCollection.Add(snapshot)
 	if err != nil {
@@ -409,3 +411,138 @@ func apiSnapshotsSearchPackages(c *gin.Context) {
 
 	showPackages(c, snapshot.RefList())
 }
+
+// GET /api/snapshots/:name/rdepends?q=<package-query>
+func apiSnapshotsReverseDependencies(c *gin.Context) {
+	collection := context.CollectionFactory().SnapshotCollection()
+	collection.Lock()
+	defer collection.Unlock()
+
+	snapshot, err := collection.ByName(c.Params.ByName("name"))
+	if err != nil {
+		c.AbortWithError(404, err)
+		return
+	}
+
+	err = collection.LoadComplete(snapshot)
+	if err != nil {
+		c.AbortWithError(500, err)
+		return
+	}
+
+	showReverseDependencies(c, snapshot.RefList())
+}
+
+// installabilityProblem is JSON representation of deb.InstallabilityProblem
+type installabilityProblem struct {
+	Package      string
+	Key          string
+	Architecture string
+	Path         []string
+	Reason       string
+}
+
+// GET /api/snapshots/:name/verify?source=<name>&installability=1
+func apiSnapshotsVerify(c *gin.Context) {
+	collection := context.CollectionFactory().SnapshotCollection()
+	collection.Lock()
+	defer collection.Unlock()
+
+	names := append([]string{c.Params.ByName("name")}, c.Request.URL.Query()["source"]...)
+	snapshots := make([]*deb.Snapshot, len(names))
+
+	for i, name := range names {
+		snapshot, err := collection.ByName(name)
+		if err != nil {
+			c.AbortWithError(404, err)
+			return
+		}
+
+		err = collection.LoadComplete(snapshot)
+		if err != nil {
+			c.AbortWithError(500, err)
+			return
+		}
+
+		snapshots[i] = snapshot
+	}
+
+	packageList, err := deb.NewPackageListFromRefList(snapshots[0].RefList(), context.CollectionFactory().PackageCollection(), nil)
+	if err != nil {
+		c.AbortWithError(500, fmt.Errorf("unable to load packages: %s", err))
+		return
+	}
+
+	sourcePackageList := deb.NewPackageList()
+	err = sourcePackageList.Append(packageList)
+	if err != nil {
+		c.AbortWithError(500, fmt.Errorf("unable to merge sources: %s", err))
+		return
+	}
+
+	for _, snapshot := range snapshots[1:] {
+		pL, err := deb.NewPackageListFromRefList(snapshot.RefList(), context.CollectionFactory().PackageCollection(), nil)
+		if err != nil {
+			c.AbortWithError(500, fmt.Errorf("unable to load packages: %s", err))
+			return
+		}
+
+		err = sourcePackageList.Append(pL)
+		if err != nil {
+			c.AbortWithError(500, fmt.Errorf("unable to merge sources: %s", err))
+			return
+		}
+	}
+
+	sourcePackageList.PrepareIndex()
+
+	architecturesList := context.ArchitecturesList()
+	if len(architecturesList) == 0 {
+		architecturesList = packageList.Architectures(true)
+	}
+
+	if len(architecturesList) == 0 {
+		c.AbortWithError(400, fmt.Errorf("unable to determine list of architectures, please specify explicitly"))
+		return
+	}
+
+	missing, err := packageList.VerifyDependencies(context.DependencyOptions(), architecturesList, sourcePackageList, nil)
+	if err != nil {
+		c.AbortWithError(500, fmt.Errorf("unable to verify dependencies: %s", err))
+		return
+	}
+
+	result := struct {
+		Missing       []string
+		Uninstallable []installabilityProblem `json:",omitempty"`
+	}{
+		Missing: []string{},
+	}
+
+	for _, dep := range missing {
+		result.Missing = append(result.Missing, dep.String())
+	}
+	sort.Strings(result.Missing)
+
+	if c.Request.URL.Query().Get("installability") == "1" {
+		checker := deb.NewInstallabilityChecker(sourcePackageList)
+		problems, err := checker.CheckAll(packageList, architecturesList, nil)
+		if err != nil {
+			c.AbortWithError(500, fmt.Errorf("unable to check installability: %s", err))
+			return
+		}
+
+		result.Uninstallable = []installabilityProblem{}
+		for _, problem := range problems {
+			result.Uninstallable = append(result.Uninstallable, installabilityProblem{
+				Package:      problem.Package.String(),
+				Key:          string(problem.Package.Key("")),
+				Architecture: problem.Architecture,
+				Path:         problem.Path,
+				Reason:       problem.Reason,
+			})
+		}
+	}
+
+	c.JSON(200, result)
+}
@@ -43,6 +43,8 @@ type PackagePool interface {
 	FilepathList(progress Progress) ([]string, error)
 	// Remove deletes file in package pool returns its size
 	Remove(path string) (size int64, err error)
+	// Purge deletes file in package pool immediately (bypassing trash) returns its size
+	Purge(path string) (size int64, err error)
 }
 
 // LocalPackagePool is implemented by PackagePools residing on the same filesystem
@@ -57,6 +59,10 @@ type LocalPackagePool interface {
 	//
 	// Please use with care: it's not supposed to be used to access files
 	FullPath(path string) string
+	// PurgeTrash deletes removed files which have been kept in trash longer than grace period
+	PurgeTrash(progress Progress) (count int, size int64, err error)
+	// MigrateLegacyFile places file stored at legacy (MD5-based) path at its SHA256-based location
+	MigrateLegacyFile(legacyPath, basename string, checksums *utils.ChecksumInfo, checksumStorage ChecksumStorage) (path string, err error)
 }
 
 // PublishedStorage is abstraction of filesystem storing all published repositories
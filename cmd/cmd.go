@@ -99,7 +99,9 @@ package environment to new version.`,
 			makeCmdConfig(),
 			makeCmdDb(),
 			makeCmdGraph(),
+			makeCmdIncoming(),
 			makeCmdMirror(),
+			makeCmdOverride(),
 			makeCmdRepo(),
 			makeCmdServe(),
 			makeCmdSnapshot(),
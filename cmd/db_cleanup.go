@@ -5,6 +5,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/aptly-dev/aptly/aptly"
 	"github.com/aptly-dev/aptly/deb"
 	"github.com/aptly-dev/aptly/utils"
 	"github.com/smira/commander"
@@ -21,13 +22,20 @@ func aptlyDbCleanup(cmd *commander.Command, args []string) error {
 
 	verbose := context.Flags().Lookup("verbose").Value.Get().(bool)
 	dryRun := context.Flags().Lookup("dry-run").Value.Get().(bool)
+	report := context.Flags().Lookup("report").Value.Get().(bool)
+
+	// sources are collected in verbose mode and for the report
+	collectSources := verbose || report
 
 	// collect information about references packages...
 	existingPackageRefs := deb.NewPackageRefList()
 
-	// used only in verbose mode to report package use source
+	// used only in verbose & report modes to report package use source
 	packageRefSources := map[string][]string{}
 
+	// files in package pool referenced directly by mirrors and snapshots (auxiliary metadata)
+	extraFiles := []string{}
+
 	context.Progress().ColoredPrintf("@{w!}Loading mirrors, local repos, snapshots and published repos...@|")
 	if verbose {
 		context.Progress().ColoredPrintf("@{y}Loading mirrors:@|")
@@ -41,10 +49,14 @@ func aptlyDbCleanup(cmd *commander.Command, args []string) error {
 		if e != nil {
 			return e
 		}
+
+		for _, file := range repo.ExtraFiles {
+			extraFiles = append(extraFiles, file.PoolPath)
+		}
 		if repo.RefList() != nil {
 			existingPackageRefs = existingPackageRefs.Merge(repo.RefList(), false, true)
 
-			if verbose {
+			if collectSources {
 				description := fmt.Sprintf("mirror %s", repo.Name)
 				repo.RefList().ForEach(func(key []byte) error {
 					packageRefSources[string(key)] = append(packageRefSources[string(key)], description)
@@ -77,7 +89,7 @@ func aptlyDbCleanup(cmd *commander.Command, args []string) error {
 		if repo.RefList() != nil {
 			existingPackageRefs = existingPackageRefs.Merge(repo.RefList(), false, true)
 
-			if verbose {
+			if collectSources {
 				description := fmt.Sprintf("local repo %s", repo.Name)
 				repo.RefList().ForEach(func(key []byte) error {
 					packageRefSources[string(key)] = append(packageRefSources[string(key)], description)
@@ -107,9 +119,13 @@ func aptlyDbCleanup(cmd *commander.Command, args []string) error {
 			return e
 		}
 
+		for _, file := range snapshot.ExtraFiles {
+			extraFiles = append(extraFiles, file.PoolPath)
+		}
+
 		existingPackageRefs = existingPackageRefs.Merge(snapshot.RefList(), false, true)
 
-		if verbose {
+		if collectSources {
 			description := fmt.Sprintf("snapshot %s", snapshot.Name)
 			snapshot.RefList().ForEach(func(key []byte) error {
 				packageRefSources[string(key)] = append(packageRefSources[string(key)], description)
@@ -141,7 +157,7 @@ func aptlyDbCleanup(cmd *commander.Command, args []string) error {
 
 		for _, component := range published.Components() {
 			existingPackageRefs = existingPackageRefs.Merge(published.RefList(component), false, true)
-			if verbose {
+			if collectSources {
 				description := fmt.Sprintf("published repository %s:%s/%s component %s",
 					published.Storage, published.Prefix, published.Distribution, component)
 				published.RefList(component).ForEach(func(key []byte) error {
@@ -164,6 +180,17 @@ func aptlyDbCleanup(cmd *commander.Command, args []string) error {
 
 	toDelete := allPackageRefs.Subtract(existingPackageRefs)
 
+	if report {
+		context.Progress().ColoredPrintf("@{y}Retained packages (%d):@|", existingPackageRefs.Len())
+		err = existingPackageRefs.ForEach(func(key []byte) error {
+			context.Progress().ColoredPrintf(" - @{g}%s@|: %s", string(key), strings.Join(packageRefSources[string(key)], ", "))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	// delete packages that are no longer referenced
 	context.Progress().ColoredPrintf("@{r!}Deleting unreferenced packages (%d)...@|", toDelete.Len())
 
@@ -204,7 +231,8 @@ func aptlyDbCleanup(cmd *commander.Command, args []string) error {
 
 	// now, build a list of files that should be present in Repository (package pool)
 	context.Progress().ColoredPrintf("@{w!}Building list of files referenced by packages...@|")
-	referencedFiles := make([]string, 0, existingPackageRefs.Len())
+	referencedFiles := make([]string, 0, existingPackageRefs.Len()+len(extraFiles))
+	referencedFiles = append(referencedFiles, extraFiles...)
 	context.Progress().InitBar(int64(existingPackageRefs.Len()), false)
 
 	err = existingPackageRefs.ForEach(func(key []byte) error {
@@ -273,13 +301,33 @@ func aptlyDbCleanup(cmd *commander.Command, args []string) error {
 			}
 			context.Progress().ShutdownBar()
 
-			context.Progress().ColoredPrintf("@{w!}Disk space freed: %s...@|", utils.HumanBytes(totalSize))
+			if context.Config().PoolGracePeriod > 0 {
+				context.Progress().ColoredPrintf("@{w!}Moved to trash: %s...@|", utils.HumanBytes(totalSize))
+			} else {
+				context.Progress().ColoredPrintf("@{w!}Disk space freed: %s...@|", utils.HumanBytes(totalSize))
+			}
 		} else {
 			context.Progress().ColoredPrintf("@{y!}Skipped file deletion, as -dry-run has been requested.@|")
 		}
 	}
 
 	if !dryRun {
+		if pool, ok := context.PackagePool().(aptly.LocalPackagePool); ok {
+			var (
+				count int
+				size  int64
+			)
+
+			count, size, err = pool.PurgeTrash(nil)
+			if err != nil {
+				return fmt.Errorf("unable to purge trash: %s", err)
+			}
+
+			if count > 0 {
+				context.Progress().ColoredPrintf("@{w!}Purged %d files from trash, disk space freed: %s...@|", count, utils.HumanBytes(size))
+			}
+		}
+
 		context.Progress().ColoredPrintf("@{w!}Compacting database...@|")
 		err = db.CompactDB()
 	} else {
@@ -298,6 +346,14 @@ func makeCmdDbCleanup() *commander.Command {
 Database cleanup removes information about unreferenced packages and removes
 files in the package pool that aren't used by packages anymore
 
+If poolGracePeriod is set in configuration file, removed files are moved to trash
+first and deleted by subsequent cleanups once grace period expires. Files which turn out
+to be still in use (e.g. imported concurrently) are restored from trash when they are
+imported again ('aptly repo add', 'aptly mirror update' or 'aptly db verify -repair').
+
+With -report, list of retained packages is printed along with mirrors, local repos,
+snapshots and published repositories referencing each package.
+
 Example:
 
   $ aptly db cleanup
@@ -306,6 +362,7 @@ Example:
 
 	cmd.Flag.Bool("verbose", false, "be verbose when loading objects/removing them")
 	cmd.Flag.Bool("dry-run", false, "don't delete anything")
+	cmd.Flag.Bool("report", false, "report which mirrors, repos and snapshots reference each retained package")
 
 	return cmd
 }
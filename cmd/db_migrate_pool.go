@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aptly-dev/aptly/deb"
+	"github.com/smira/commander"
+	"github.com/smira/flag"
+)
+
+// aptly db migrate-pool
+func aptlyDbMigratePool(cmd *commander.Command, args []string) error {
+	var err error
+
+	if len(args) != 0 {
+		cmd.Usage()
+		return commander.ErrCommandError
+	}
+
+	dryRun := context.Flags().Lookup("dry-run").Value.Get().(bool)
+
+	collectionFactory := context.CollectionFactory()
+
+	context.Progress().ColoredPrintf("@{w!}Loading list of all packages...@|")
+	allPackageRefs := collectionFactory.PackageCollection().AllPackageRefs()
+
+	symlinked, err := symlinkedPublishedRepos()
+	if err != nil {
+		return fmt.Errorf("unable to migrate package pool: %s", err)
+	}
+
+	if len(symlinked) > 0 && !dryRun {
+		// symlinks in published repositories point to legacy paths, and republishing doesn't
+		// update them, as legacy and migrated files are the same
+		report, e := deb.MigrateLegacyPoolFiles(allPackageRefs, collectionFactory.PackageCollection(), context.PackagePool(),
+			true, nil)
+		if e != nil {
+			return fmt.Errorf("unable to migrate package pool: %s", e)
+		}
+
+		if report.Files > 0 {
+			return fmt.Errorf("unable to migrate package pool: published repositories %s link to package pool with symlinks, "+
+				"drop them before migration and publish again afterwards", strings.Join(symlinked, ", "))
+		}
+	}
+
+	context.Progress().ColoredPrintf("@{w!}Migrating files of %d packages to new pool layout...@|", allPackageRefs.Len())
+	report, err := deb.MigrateLegacyPoolFiles(allPackageRefs, collectionFactory.PackageCollection(), context.PackagePool(),
+		dryRun, context.Progress())
+	if err != nil {
+		return fmt.Errorf("unable to migrate package pool: %s", err)
+	}
+
+	for _, failure := range report.Failed {
+		context.Progress().ColoredPrintf("@r[!]@| @!unable to migrate %s@|", failure)
+	}
+
+	if dryRun {
+		context.Progress().Printf("\n%d files found at legacy pool paths.\n", report.Files)
+		if len(symlinked) > 0 && report.Files > 0 {
+			context.Progress().ColoredPrintf("@{y!}Warning@|: published repositories %s link to package pool with symlinks, "+
+				"they should be dropped before migration and published again afterwards", strings.Join(symlinked, ", "))
+		}
+		context.Progress().ColoredPrintf("@{y!}Dry run, nothing has been migrated.@|")
+		return nil
+	}
+
+	context.Progress().Printf("\n%d files found at legacy pool paths, %d files migrated, %d legacy files removed.\n",
+		report.Files, report.Migrated, report.Removed)
+
+	if len(report.Failed) > 0 {
+		return fmt.Errorf("package pool migration failed: %d files couldn't be migrated", len(report.Failed))
+	}
+
+	return err
+}
+
+// symlinkedPublishedRepos lists published repositories in filesystem publish roots using symlinks
+func symlinkedPublishedRepos() ([]string, error) {
+	result := []string{}
+
+	err := context.CollectionFactory().PublishedRepoCollection().ForEach(func(published *deb.PublishedRepo) error {
+		if !strings.HasPrefix(published.Storage, "filesystem:") {
+			return nil
+		}
+
+		root, ok := context.Config().FileSystemPublishRoots[strings.TrimPrefix(published.Storage, "filesystem:")]
+		if ok && strings.EqualFold(root.LinkMethod, "symlink") {
+			result = append(result, fmt.Sprintf("%s:%s/%s", published.Storage, published.Prefix, published.Distribution))
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+func makeCmdDbMigratePool() *commander.Command {
+	cmd := &commander.Command{
+		Run:       aptlyDbMigratePool,
+		UsageLine: "migrate-pool",
+		Short:     "migrate package pool from legacy layout",
+		Long: `
+Migrate moves package files stored in the package pool using legacy (pre-1.1,
+MD5-based) layout to the current layout based on SHA256 checksums, updating
+package records and checksum database. Lookups of files at legacy paths slow down
+package pool operations, and once migration is complete, they could be disabled
+with skipLegacyPool configuration option.
+
+Every package is updated in a separate transaction, so migration could be
+interrupted and run again to continue. Legacy files are removed once all the
+packages have been migrated, leftovers of interrupted migration are removed
+by 'aptly db cleanup'.
+
+Published repositories in filesystem publish roots with linkMethod 'symlink'
+link to files at legacy paths, so migration is refused while such repositories
+exist: drop them before migration and publish again afterwards.
+
+Example:
+
+  $ aptly db migrate-pool
+`,
+		Flag: *flag.NewFlagSet("aptly-db-migrate-pool", flag.ExitOnError),
+	}
+
+	cmd.Flag.Bool("dry-run", false, "don't migrate anything, just show number of files at legacy paths")
+
+	return cmd
+}
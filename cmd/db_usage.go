@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aptly-dev/aptly/deb"
+	"github.com/aptly-dev/aptly/utils"
+	"github.com/smira/commander"
+	"github.com/smira/flag"
+)
+
+// aptly db usage
+func aptlyDbUsage(cmd *commander.Command, args []string) error {
+	if len(args) != 0 {
+		cmd.Usage()
+		return commander.ErrCommandError
+	}
+
+	raw := context.Flags().Lookup("raw").Value.Get().(bool)
+
+	var progress = context.Progress()
+	if raw {
+		progress = nil
+	} else {
+		progress.ColoredPrintf("@{w!}Computing storage usage of mirrors, local repos, snapshots and published repos...@|")
+	}
+
+	report, err := deb.ComputeUsage(context.CollectionFactory(), context.PackagePool(), progress)
+	if err != nil {
+		return fmt.Errorf("unable to compute storage usage: %s", err)
+	}
+
+	context.CloseDatabase()
+
+	if raw {
+		for _, entry := range report.Entries {
+			fmt.Printf("%s\t%s\t%d\t%d\t%d\t%d\n", entry.Kind, entry.Name, entry.Packages, entry.Files, entry.TotalSize, entry.UniqueSize)
+		}
+		return nil
+	}
+
+	fmt.Printf("\nPackage pool: %d files referenced, %s\n", report.Files, utils.HumanBytes(report.TotalSize))
+
+	if len(report.Entries) == 0 {
+		return nil
+	}
+
+	fmt.Printf("\nUsage (unique is space freed if object were dropped):\n")
+	for _, entry := range report.Entries {
+		fmt.Printf(" * %s %s: %d packages, %d files, total %s, unique %s\n", entry.Kind, entry.Name, entry.Packages, entry.Files,
+			utils.HumanBytes(entry.TotalSize), utils.HumanBytes(entry.UniqueSize))
+	}
+
+	return nil
+}
+
+func makeCmdDbUsage() *commander.Command {
+	cmd := &commander.Command{
+		Run:       aptlyDbUsage,
+		UsageLine: "usage",
+		Short:     "show storage usage of package pool",
+		Long: `
+Usage reports how much space in the package pool is used by every mirror,
+local repo, snapshot and published repository: total size of files referenced
+by the object and size of files referenced by that object only (space which
+would be freed by 'aptly db cleanup' if object were dropped). Objects are listed
+by unique size, largest first.
+
+Published repositories are counted on their own, so files of published snapshots
+are not unique to the snapshot.
+
+Example:
+
+  $ aptly db usage
+`,
+		Flag: *flag.NewFlagSet("aptly-db-usage", flag.ExitOnError),
+	}
+
+	cmd.Flag.Bool("raw", false, "display usage in machine-readable format (sizes in bytes)")
+
+	return cmd
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/aptly-dev/aptly/deb"
+	"github.com/smira/commander"
+	"github.com/smira/flag"
+)
+
+// aptly db verify
+func aptlyDbVerify(cmd *commander.Command, args []string) error {
+	var err error
+
+	if len(args) != 0 {
+		cmd.Usage()
+		return commander.ErrCommandError
+	}
+
+	repair := context.Flags().Lookup("repair").Value.Get().(bool)
+	concurrency := context.Flags().Lookup("concurrency").Value.Get().(int)
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	collectionFactory := context.CollectionFactory()
+
+	context.Progress().ColoredPrintf("@{w!}Loading list of all packages...@|")
+	allPackageRefs := collectionFactory.PackageCollection().AllPackageRefs()
+
+	context.Progress().ColoredPrintf("@{w!}Verifying files of %d packages in package pool...@|", allPackageRefs.Len())
+	report, err := deb.VerifyPoolFiles(allPackageRefs, collectionFactory.PackageCollection(), context.PackagePool(),
+		collectionFactory.ChecksumCollection(nil), concurrency, context.Progress())
+	if err != nil {
+		return fmt.Errorf("unable to verify package pool: %s", err)
+	}
+
+	for _, problem := range report.Problems {
+		context.Progress().ColoredPrintf("@r[!]@| @!%s@|", problem)
+	}
+
+	if repair && len(report.Problems) > 0 {
+		err = repairPoolFiles(report.Problems)
+		if err != nil {
+			return fmt.Errorf("unable to repair package pool: %s", err)
+		}
+	}
+
+	unrepaired := 0
+	for _, problem := range report.Problems {
+		if !problem.Repaired {
+			unrepaired++
+		}
+	}
+
+	context.Progress().Printf("\n%d files verified, %d problems found, %d files repaired.\n",
+		report.Files, len(report.Problems), len(report.Problems)-unrepaired)
+
+	if unrepaired > 0 {
+		return fmt.Errorf("package pool verification failed: %d missing or corrupt files", unrepaired)
+	}
+
+	return err
+}
+
+// repairPoolFiles re-downloads missing and corrupt files of mirrored packages
+func repairPoolFiles(problems []*deb.PoolFileProblem) error {
+	collectionFactory := context.CollectionFactory()
+
+	context.Progress().ColoredPrintf("@{w!}Loading mirrors...@|")
+	mirrors := []*deb.RemoteRepo{}
+	err := collectionFactory.RemoteRepoCollection().ForEach(func(repo *deb.RemoteRepo) error {
+		e := collectionFactory.RemoteRepoCollection().LoadComplete(repo)
+		if e != nil {
+			return e
+		}
+
+		mirrors = append(mirrors, repo)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return deb.RepairPoolFiles(problems, mirrors, mirrorDownloader, collectionFactory.PackageCollection(),
+		context.PackagePool(), collectionFactory.ChecksumCollection(nil), context.Progress())
+}
+
+func makeCmdDbVerify() *commander.Command {
+	cmd := &commander.Command{
+		Run:       aptlyDbVerify,
+		UsageLine: "verify",
+		Short:     "verify files in package pool",
+		Long: `
+Verify checks that every file referenced by packages in the database exists in the
+package pool and matches checksums recorded in package metadata and checksum database.
+Files are verified in parallel (-concurrency, defaults to number of CPUs).
+
+With -repair, missing and corrupt files of mirrored packages are downloaded again
+from the mirrors containing the packages.
+
+Command exits with error if any missing or corrupt files remain.
+
+Example:
+
+  $ aptly db verify -repair
+`,
+		Flag: *flag.NewFlagSet("aptly-db-verify", flag.ExitOnError),
+	}
+
+	cmd.Flag.Bool("repair", false, "re-download missing and corrupt files of mirrored packages")
+	cmd.Flag.Int("concurrency", 0, "number of files verified in parallel (0 means number of CPUs)")
+
+	return cmd
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/aptly-dev/aptly/deb"
 	"github.com/aptly-dev/aptly/utils"
+	"github.com/awalterschulze/gographviz"
 	"github.com/smira/commander"
 )
 
@@ -26,15 +28,21 @@ func aptlyGraph(cmd *commander.Command, args []string) error {
 	}
 
 	layout := context.Flags().Lookup("layout").Value.String()
+	name := context.Flags().Lookup("name").Value.String()
 
 	fmt.Printf("Generating graph...\n")
-	graph, err := deb.BuildGraph(context.CollectionFactory(), layout)
+	graph, err := deb.BuildGraphData(context.CollectionFactory())
 
 	if err != nil {
 		return err
 	}
 
-	buf := bytes.NewBufferString(graph.String())
+	if name != "" {
+		graph = graph.Filter(name)
+		if len(graph.Nodes) == 0 {
+			return fmt.Errorf("unable to find mirror, local repo, snapshot or published repository %s", name)
+		}
+	}
 
 	tempfile, err := ioutil.TempFile("", "aptly-graph")
 	if err != nil {
@@ -52,30 +60,25 @@ func aptlyGraph(cmd *commander.Command, args []string) error {
 
 	tempfilename := tempfile.Name() + "." + format
 
-	command := exec.Command("dot", "-T"+format, "-o"+tempfilename)
-	command.Stderr = os.Stderr
-
-	stdin, err := command.StdinPipe()
-	if err != nil {
-		return err
-	}
-
-	err = command.Start()
-	if err != nil {
-		return fmt.Errorf("unable to execute dot: %s (is graphviz package installed?)", err)
-	}
+	switch format {
+	case "json":
+		var contents []byte
 
-	_, err = io.Copy(stdin, buf)
-	if err != nil {
-		return err
-	}
+		contents, err = json.MarshalIndent(graph, "", "  ")
+		if err == nil {
+			err = ioutil.WriteFile(tempfilename, contents, 0644)
+		}
+	case "html":
+		var contents []byte
 
-	err = stdin.Close()
-	if err != nil {
-		return err
+		contents, err = graph.HTML()
+		if err == nil {
+			err = ioutil.WriteFile(tempfilename, contents, 0644)
+		}
+	default:
+		err = renderGraphDot(graph.Dot(layout), format, tempfilename)
 	}
 
-	err = command.Wait()
 	if err != nil {
 		return err
 	}
@@ -113,6 +116,36 @@ func aptlyGraph(cmd *commander.Command, args []string) error {
 	return err
 }
 
+// renderGraphDot renders graph with dot to filename in specified format
+func renderGraphDot(graph gographviz.Interface, format, filename string) error {
+	buf := bytes.NewBufferString(graph.String())
+
+	command := exec.Command("dot", "-T"+format, "-o"+filename)
+	command.Stderr = os.Stderr
+
+	stdin, err := command.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	err = command.Start()
+	if err != nil {
+		return fmt.Errorf("unable to execute dot: %s (is graphviz package installed?)", err)
+	}
+
+	_, err = io.Copy(stdin, buf)
+	if err != nil {
+		return err
+	}
+
+	err = stdin.Close()
+	if err != nil {
+		return err
+	}
+
+	return command.Wait()
+}
+
 // getOpenCommand tries to guess command to open image for OS
 func getOpenCommand() string {
 	switch runtime.GOOS {
@@ -135,15 +168,21 @@ Command graph displays relationship between mirrors, local repositories,
 snapshots and published repositories using graphviz package to render
 graph as an image.
 
+Formats json (nodes and edges with properties) and html (interactive
+page rendered in the browser) don't require graphviz. With -name, only
+lineage of the named object is displayed: objects it has been created from
+and objects created from it.
+
 Example:
 
   $ aptly graph
 `,
 	}
 
-	cmd.Flag.String("format", "png", "render graph to specified format (png, svg, pdf, etc.)")
+	cmd.Flag.String("format", "png", "render graph to specified format (png, svg, pdf, json, html, etc.)")
 	cmd.Flag.String("output", "", "specify output filename, default is to open result in viewer")
 	cmd.Flag.String("layout", "horizontal", "create a more 'vertical' or a more 'horizontal' graph layout")
+	cmd.Flag.String("name", "", "display only lineage of mirror, local repo, snapshot or published repository (prefix/distribution) with the name")
 
 	return cmd
 }
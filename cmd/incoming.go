@@ -0,0 +1,324 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/aptly-dev/aptly/aptly"
+	"github.com/aptly-dev/aptly/deb"
+	"github.com/aptly-dev/aptly/pgp"
+	"github.com/aptly-dev/aptly/query"
+	"github.com/aptly-dev/aptly/utils"
+	"github.com/smira/commander"
+)
+
+func makeCmdIncoming() *commander.Command {
+	return &commander.Command{
+		UsageLine: "incoming",
+		Short:     "process incoming queues of .changes uploads",
+		Subcommands: []*commander.Command{
+			makeCmdIncomingProcess(),
+			makeCmdIncomingServe(),
+		},
+	}
+}
+
+// incomingQueues returns configured incoming queues by names (all queues if names are empty)
+func incomingQueues(names []string) (map[string]utils.IncomingQueue, error) {
+	queues := context.Config().IncomingQueues
+
+	if len(names) == 0 {
+		if len(queues) == 0 {
+			return nil, fmt.Errorf("no incoming queues configured, see IncomingQueues in aptly configuration file")
+		}
+		return queues, nil
+	}
+
+	result := make(map[string]utils.IncomingQueue, len(names))
+	for _, name := range names {
+		queue, ok := queues[name]
+		if !ok {
+			return nil, fmt.Errorf("incoming queue %s is not configured", name)
+		}
+		result[name] = queue
+	}
+
+	return result, nil
+}
+
+// incomingQueueNames returns sorted list of queue names
+func incomingQueueNames(queues map[string]utils.IncomingQueue) []string {
+	names := make([]string, 0, len(queues))
+	for name := range queues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// incomingQuarantineDir returns quarantine directory for the queue: as incoming directory
+// is scanned recursively, default location is next to it, not inside
+func incomingQuarantineDir(queue utils.IncomingQueue) string {
+	if queue.QuarantineDir != "" {
+		return queue.QuarantineDir
+	}
+
+	return filepath.Clean(queue.Dir) + "-quarantine"
+}
+
+// incomingQuarantinePath returns path of the file in quarantine directory: path relative to
+// incoming directory is kept, so that uploads from different subdirectories don't clash
+func incomingQuarantinePath(queue utils.IncomingQueue, quarantineDir, file string) string {
+	rel, err := filepath.Rel(queue.Dir, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		rel = filepath.Base(file)
+	}
+
+	return filepath.Join(quarantineDir, rel)
+}
+
+// incomingQuarantine moves rejected files to quarantine directory and writes reason file next to them
+func incomingQuarantine(queue utils.IncomingQueue, changesPath string, files []string, reasons []string) error {
+	quarantineDir := incomingQuarantineDir(queue)
+
+	for _, file := range utils.StrSliceDeduplicate(files) {
+		_, err := os.Stat(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		target := incomingQuarantinePath(queue, quarantineDir, file)
+
+		err = os.MkdirAll(filepath.Dir(target), 0777)
+		if err == nil {
+			err = os.Rename(file, target)
+			if err != nil {
+				// rename fails across filesystems, fall back to copy & remove
+				err = utils.CopyFile(file, target)
+				if err == nil {
+					err = os.Remove(file)
+				}
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("unable to quarantine %s: %s", file, err)
+		}
+	}
+
+	reasonPath := incomingQuarantinePath(queue, quarantineDir, changesPath) + ".reason"
+
+	err := os.MkdirAll(filepath.Dir(reasonPath), 0777)
+	if err != nil {
+		return err
+	}
+
+	reason := strings.Join(reasons, "\n") + "\n"
+
+	return ioutil.WriteFile(reasonPath, []byte(reason), 0644)
+}
+
+// incomingProcessQueue imports all .changes files from the incoming queue, rejected uploads
+// are quarantined; names of local repos which received packages are returned
+func incomingProcessQueue(name string, queue utils.IncomingQueue, verifier pgp.Verifier) (affectedRepos []string, err error) {
+	repoTemplateString := queue.Repo
+	if repoTemplateString == "" {
+		repoTemplateString = "{{.Distribution}}"
+	}
+
+	repoTemplate, err := template.New("repo").Parse(repoTemplateString)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing repo template for incoming queue %s: %s", name, err)
+	}
+
+	var uploaders *deb.Uploaders
+	if queue.UploadersFile != "" {
+		uploaders, err = deb.NewUploadersFromFile(queue.UploadersFile)
+		if err != nil {
+			return nil, err
+		}
+
+		err = uploaders.Compile(query.Parse)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	changesFiles, _ := deb.CollectChangesFiles([]string{queue.Dir}, &aptly.RecordingResultReporter{})
+
+	for _, path := range changesFiles {
+		if context.Err() != nil {
+			// aborted, remaining uploads would be processed next time
+			break
+		}
+
+		// parse .changes file upfront to figure out referenced files and target repo,
+		// as .changes file is gone after successful import
+		var (
+			referencedFiles []string
+			repoName        string
+		)
+
+		changes, err2 := deb.NewChanges(path)
+		if err2 == nil {
+			err2 = changes.VerifyAndParse(true, true, verifier)
+			if err2 == nil {
+				for _, file := range changes.Files {
+					referencedFiles = append(referencedFiles, filepath.Join(changes.BasePath, filepath.Base(file.Filename)))
+				}
+
+				buf := &bytes.Buffer{}
+				if repoTemplate.Execute(buf, changes.Stanza) == nil {
+					repoName = buf.String()
+				}
+			}
+			changes.Cleanup()
+		}
+
+		reporter := &aptly.RecordingResultReporter{
+			Warnings:     []string{},
+			AddedLines:   []string{},
+			RemovedLines: []string{},
+		}
+
+		var processedFiles, failedFiles []string
+
+		processedFiles, failedFiles, err = deb.ImportChangesFiles(
+			[]string{path}, reporter, queue.AcceptUnsigned, queue.IgnoreSignatures, queue.ForceReplace, false, verifier,
+			repoTemplateString, nil, context.CollectionFactory().LocalRepoCollection(), context.CollectionFactory().PackageCollection(),
+			context.PackagePool(), context.CollectionFactory().ChecksumCollection, uploaders, query.Parse)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, line := range reporter.AddedLines {
+			context.Progress().ColoredPrintf("@g[+]@| %s", line)
+		}
+		for _, line := range reporter.RemovedLines {
+			context.Progress().ColoredPrintf("@r[-]@| %s", line)
+		}
+
+		if len(failedFiles) > 0 {
+			if utils.StrSliceHasItem(failedFiles, path) {
+				// whole upload has been rejected
+				failedFiles = append(failedFiles, referencedFiles...)
+			}
+
+			context.Progress().ColoredPrintf("@y[!]@| @!%s: upload rejected, moving to quarantine@|", filepath.Base(path))
+			for _, warning := range reporter.Warnings {
+				context.Progress().ColoredPrintf("  %s", warning)
+			}
+
+			err = incomingQuarantine(queue, path, failedFiles, reporter.Warnings)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if utils.StrSliceHasItem(processedFiles, path) && repoName != "" && len(reporter.AddedLines) > 0 {
+			affectedRepos = append(affectedRepos, repoName)
+		}
+	}
+
+	return utils.StrSliceDeduplicate(affectedRepos), nil
+}
+
+// incomingPublishUpdate re-publishes all published repositories which are based on affected local repos
+func incomingPublishUpdate(affectedRepos []string, signer pgp.Signer) error {
+	collectionFactory := context.CollectionFactory()
+	publishedCollection := collectionFactory.PublishedRepoCollection()
+
+	updated := map[string]bool{}
+
+	for _, repoName := range affectedRepos {
+		repo, err := collectionFactory.LocalRepoCollection().ByName(repoName)
+		if err != nil {
+			return fmt.Errorf("unable to update published repositories: %s", err)
+		}
+
+		for _, published := range publishedCollection.ByLocalRepo(repo) {
+			if updated[published.UUID] {
+				continue
+			}
+			updated[published.UUID] = true
+
+			err = publishedCollection.LoadComplete(published, collectionFactory)
+			if err != nil {
+				return fmt.Errorf("unable to update %s: %s", published, err)
+			}
+
+			components := published.Components()
+			for _, component := range components {
+				published.UpdateLocalRepo(component)
+			}
+
+			err = published.Publish(context.PackagePool(), context, collectionFactory, signer, context.Progress(), false)
+			if err != nil {
+				return fmt.Errorf("unable to publish %s: %s", published, err)
+			}
+
+			err = publishedCollection.Update(published)
+			if err != nil {
+				return fmt.Errorf("unable to save to DB: %s", err)
+			}
+
+			err = publishedCollection.CleanupPrefixComponentFiles(published.Prefix, components,
+				context.GetPublishedStorage(published.Storage), collectionFactory, context.Progress())
+			if err != nil {
+				return fmt.Errorf("unable to update %s: %s", published, err)
+			}
+
+			context.Progress().Printf("Published repository %s has been updated.\n", published)
+		}
+	}
+
+	return nil
+}
+
+// incomingProcessQueues processes every queue once, optionally updating published repositories
+func incomingProcessQueues(queues map[string]utils.IncomingQueue, signer pgp.Signer) error {
+	verifier, err := getVerifier(context.Flags())
+	if err != nil {
+		return fmt.Errorf("unable to initialize GPG verifier: %s", err)
+	}
+
+	if verifier == nil {
+		verifier = context.GetVerifier()
+	}
+
+	for _, name := range incomingQueueNames(queues) {
+		queue := queues[name]
+
+		affectedRepos, err := incomingProcessQueue(name, queue, verifier)
+		if err != nil {
+			return fmt.Errorf("unable to process incoming queue %s: %s", name, err)
+		}
+
+		if queue.PublishUpdate && len(affectedRepos) > 0 {
+			err = incomingPublishUpdate(affectedRepos, signer)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// incomingHasChanges checks whether any queue has .changes files waiting
+func incomingHasChanges(queues map[string]utils.IncomingQueue) bool {
+	for _, queue := range queues {
+		changesFiles, _ := deb.CollectChangesFiles([]string{queue.Dir}, &aptly.RecordingResultReporter{})
+		if len(changesFiles) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aptly-dev/aptly/pgp"
+	"github.com/smira/commander"
+	"github.com/smira/flag"
+)
+
+func aptlyIncomingProcess(cmd *commander.Command, args []string) error {
+	queues, err := incomingQueues(args)
+	if err != nil {
+		return fmt.Errorf("unable to process: %s", err)
+	}
+
+	var signer pgp.Signer
+	for _, queue := range queues {
+		if queue.PublishUpdate {
+			signer, err = getSigner(context.Flags())
+			if err != nil {
+				return fmt.Errorf("unable to initialize GPG signer: %s", err)
+			}
+			break
+		}
+	}
+
+	return incomingProcessQueues(queues, signer)
+}
+
+func addIncomingSigningFlags(cmd *commander.Command) {
+	cmd.Flag.String("gpg-key", "", "GPG key ID to use when signing the release (for queues with publishUpdate)")
+	cmd.Flag.Var(&keyRingsFlag{}, "keyring", "GPG keyring to use when verifying uploads and signing the release (instead of default)")
+	cmd.Flag.String("secret-keyring", "", "GPG secret keyring to use (instead of default)")
+	cmd.Flag.String("passphrase", "", "GPG passphrase for the key (warning: could be insecure)")
+	cmd.Flag.String("passphrase-file", "", "GPG passphrase-file for the key (warning: could be insecure)")
+	cmd.Flag.Bool("batch", false, "run GPG with detached tty")
+	cmd.Flag.Bool("skip-signing", false, "don't sign Release files with GPG")
+}
+
+func makeCmdIncomingProcess() *commander.Command {
+	cmd := &commander.Command{
+		Run:       aptlyIncomingProcess,
+		UsageLine: "process [<queue> ...]",
+		Short:     "process .changes files waiting in incoming queues",
+		Long: `
+Command process imports .changes files waiting in incoming queues (all
+configured queues by default) into local repositories, just like 'aptly repo include'
+does. Incoming queues are configured in IncomingQueues section of aptly
+configuration file, each queue has incoming directory, template to choose local
+repository (defaults to Distribution field of .changes file) and other options.
+
+Rejected uploads are moved into quarantine directory of the queue (keeping
+their path relative to the incoming directory) along with <name>.changes.reason
+file listing reasons for rejection. If publishUpdate is
+enabled for the queue, published repositories of local repositories which
+received new packages are updated.
+
+Example:
+
+  $ aptly incoming process main-queue
+`,
+		Flag: *flag.NewFlagSet("aptly-incoming-process", flag.ExitOnError),
+	}
+
+	addIncomingSigningFlags(cmd)
+
+	return cmd
+}
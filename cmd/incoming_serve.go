@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	gocontext "context"
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/aptly-dev/aptly/pgp"
+	"github.com/aptly-dev/aptly/utils"
+	"github.com/smira/commander"
+	"github.com/smira/flag"
+)
+
+func aptlyIncomingServe(cmd *commander.Command, args []string) error {
+	queues, err := incomingQueues(args)
+	if err != nil {
+		return fmt.Errorf("unable to serve: %s", err)
+	}
+
+	pollInterval := context.Flags().Lookup("poll-interval").Value.Get().(time.Duration)
+
+	var signer pgp.Signer
+	for _, queue := range queues {
+		if queue.PublishUpdate {
+			signer, err = getSigner(context.Flags())
+			if err != nil {
+				return fmt.Errorf("unable to initialize GPG signer: %s", err)
+			}
+			break
+		}
+	}
+
+	dirs := make([]string, 0, len(queues))
+	for _, name := range incomingQueueNames(queues) {
+		dirs = append(dirs, queues[name].Dir)
+	}
+
+	watcher := utils.NewDirWatcher(dirs)
+	defer watcher.Close()
+
+	// database is not required while waiting for uploads
+	err = context.CloseDatabase()
+	if err != nil {
+		return err
+	}
+
+	// unlike other commands, serve runs as a service, so it is stopped with SIGTERM as well
+	context.GoContextHandleSignals(syscall.SIGTERM)
+
+	context.Progress().Printf("Watching incoming queues %v (press Ctrl+C to quit)...\n", incomingQueueNames(queues))
+
+	for {
+		if incomingHasChanges(queues) {
+			err = context.ReOpenDatabase()
+			if err != nil {
+				return fmt.Errorf("unable to reopen the DB: %s", err)
+			}
+
+			err = incomingProcessQueues(queues, signer)
+			if err != nil {
+				context.Progress().ColoredPrintf("@rERROR@|: %s", err)
+			}
+
+			context.CollectionFactory().Flush()
+
+			err = context.CloseDatabase()
+			if err != nil {
+				return err
+			}
+		}
+
+		if context.Err() != nil {
+			break
+		}
+
+		err = watcher.Wait(context, pollInterval)
+		if err == gocontext.Canceled {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	context.Progress().Printf("Stopped watching incoming queues.\n")
+
+	return nil
+}
+
+func makeCmdIncomingServe() *commander.Command {
+	cmd := &commander.Command{
+		Run:       aptlyIncomingServe,
+		UsageLine: "serve [<queue> ...]",
+		Short:     "watch incoming queues and process uploads as they arrive",
+		Long: `
+Command serve runs continuously, watching directories of incoming queues
+(all configured queues by default) and processing .changes files as they
+arrive, the same way 'aptly incoming process' does. On Linux, inotify
+is used to get notified about new files (subdirectories of incoming directories
+are watched as well), on other platforms (or if inotify is not available)
+directories are polled with -poll-interval. Database is released between
+processing runs, so other aptly commands could run concurrently.
+
+On SIGINT (Ctrl+C) or SIGTERM, upload being processed is completed and
+command exits.
+
+Uploaders should put .changes file into incoming directory last (which is
+default behavior of dput and dupload).
+
+Example:
+
+  $ aptly incoming serve -poll-interval=30s
+`,
+		Flag: *flag.NewFlagSet("aptly-incoming-serve", flag.ExitOnError),
+	}
+
+	cmd.Flag.Duration("poll-interval", time.Minute, "interval to rescan incoming directories")
+	addIncomingSigningFlags(cmd)
+
+	return cmd
+}
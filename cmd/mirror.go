@@ -1,8 +1,13 @@
 package cmd
 
 import (
+	"fmt"
+	"path/filepath"
 	"strings"
 
+	"github.com/aptly-dev/aptly/aptly"
+	"github.com/aptly-dev/aptly/deb"
+	"github.com/aptly-dev/aptly/http"
 	"github.com/aptly-dev/aptly/pgp"
 	"github.com/smira/commander"
 	"github.com/smira/flag"
@@ -28,6 +33,148 @@ func getVerifier(flags *flag.FlagSet) (pgp.Verifier, error) {
 	return verifier, nil
 }
 
+// mirrorIndexCacheDir is directory to keep last downloaded package indexes of mirrors in
+func mirrorIndexCacheDir() string {
+	return filepath.Join(context.Config().RootDir, "indexes")
+}
+
+// mirrorDownloader returns downloader configured with transport options of the mirror
+func mirrorDownloader(repo *deb.RemoteRepo) (aptly.Downloader, error) {
+	var (
+		downloader aptly.Downloader
+		err        error
+	)
+
+	// only mirrors of local archives are allowed to access local filesystem
+	if repo.IsLocal() {
+		downloader, err = context.LocalDownloaderWithOptions(repo.DownloadOptions)
+	} else {
+		downloader, err = context.DownloaderWithOptions(repo.DownloadOptions)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize downloader: %s", err)
+	}
+
+	if len(repo.FallbackArchiveRoots) > 0 {
+		roots := append([]string{repo.ArchiveRoot}, repo.FallbackArchiveRoots...)
+		downloader = http.NewFailoverDownloader(downloader, roots, repo.RoundRobinArchiveRoots)
+	}
+
+	return downloader, nil
+}
+
+// addMirrorDownloadFlags adds flags for mirror transport options
+func addMirrorDownloadFlags(cmd *commander.Command) {
+	cmd.Flag.String("proxy", "", "HTTP proxy URL to use for the mirror")
+	cmd.Flag.String("ca-cert", "", "file with additional CA certificates (PEM) to verify mirror TLS certificate")
+	cmd.Flag.String("client-cert", "", "client TLS certificate (PEM) for the mirror")
+	cmd.Flag.String("client-key", "", "client TLS certificate key (PEM), if not bundled with certificate")
+	cmd.Flag.String("username", "", "username for HTTP basic authentication")
+	cmd.Flag.String("password", "", "password for HTTP basic authentication (visible in process list, prefer -password-file or -password-env)")
+	cmd.Flag.String("password-file", "", "file to read password for HTTP basic authentication from")
+	cmd.Flag.String("password-env", "", "environment variable to take password for HTTP basic authentication from")
+	cmd.Flag.Var(&headersFlag{}, "header", "additional HTTP header 'Name: value' (could be specified multiple times)")
+	cmd.Flag.Var(&fallbackURLsFlag{}, "fallback-url", "alternate archive url to download from if archive url fails (could be specified multiple times)")
+	cmd.Flag.Bool("round-robin", false, "spread downloads across archive url and fallback urls round-robin")
+}
+
+// applyMirrorDownloadFlags updates mirror transport options from flags set on command line
+func applyMirrorDownloadFlags(repo *deb.RemoteRepo, flags *flag.FlagSet) {
+	flags.Visit(func(flag *flag.Flag) {
+		switch flag.Name {
+		case "proxy":
+			repo.DownloadOptions.Proxy = flag.Value.String()
+		case "ca-cert":
+			repo.DownloadOptions.CACertFile = flag.Value.String()
+		case "client-cert":
+			repo.DownloadOptions.ClientCertFile = flag.Value.String()
+		case "client-key":
+			repo.DownloadOptions.ClientKeyFile = flag.Value.String()
+		case "username":
+			repo.DownloadOptions.Username = flag.Value.String()
+		case "password":
+			repo.DownloadOptions.Password = flag.Value.String()
+			repo.DownloadOptions.PasswordFile, repo.DownloadOptions.PasswordEnv = "", ""
+		case "password-file":
+			repo.DownloadOptions.PasswordFile = flag.Value.String()
+			repo.DownloadOptions.Password, repo.DownloadOptions.PasswordEnv = "", ""
+		case "password-env":
+			repo.DownloadOptions.PasswordEnv = flag.Value.String()
+			repo.DownloadOptions.Password, repo.DownloadOptions.PasswordFile = "", ""
+		case "header":
+			repo.DownloadOptions.Headers = flag.Value.Get().(map[string]string)
+		case "fallback-url":
+			repo.FallbackArchiveRoots = flag.Value.Get().([]string)
+		case "round-robin":
+			repo.RoundRobinArchiveRoots = flag.Value.Get().(bool)
+		}
+	})
+}
+
+type headersFlag struct {
+	headers map[string]string
+}
+
+func (h *headersFlag) Set(value string) error {
+	if value == "" {
+		// empty value resets headers
+		h.headers = map[string]string{}
+		return nil
+	}
+
+	if h.headers == nil {
+		h.headers = map[string]string{}
+	}
+
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return fmt.Errorf("header should be in format 'Name: value'")
+	}
+
+	h.headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	return nil
+}
+
+func (h *headersFlag) Get() interface{} {
+	return h.headers
+}
+
+func (h *headersFlag) String() string {
+	names := make([]string, 0, len(h.headers))
+	for name := range h.headers {
+		names = append(names, name)
+	}
+
+	return strings.Join(names, ",")
+}
+
+type fallbackURLsFlag struct {
+	urls []string
+}
+
+func (f *fallbackURLsFlag) Set(value string) error {
+	if value == "" {
+		// empty value resets the list
+		f.urls = []string{}
+		return nil
+	}
+
+	if !strings.HasSuffix(value, "/") {
+		value += "/"
+	}
+
+	f.urls = append(f.urls, value)
+	return nil
+}
+
+func (f *fallbackURLsFlag) Get() interface{} {
+	return f.urls
+}
+
+func (f *fallbackURLsFlag) String() string {
+	return strings.Join(f.urls, ",")
+}
+
 type keyRingsFlag struct {
 	keyRings []string
 }
@@ -58,6 +205,7 @@ func makeCmdMirror() *commander.Command {
 			makeCmdMirrorRename(),
 			makeCmdMirrorEdit(),
 			makeCmdMirrorSearch(),
+			makeCmdMirrorHistory(),
 		},
 	}
 }
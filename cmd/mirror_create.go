@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/aptly-dev/aptly/deb"
 	"github.com/aptly-dev/aptly/query"
@@ -46,6 +47,22 @@ func aptlyMirrorCreate(cmd *commander.Command, args []string) error {
 	repo.FilterWithDeps = context.Flags().Lookup("filter-with-deps").Value.Get().(bool)
 	repo.SkipComponentCheck = context.Flags().Lookup("force-components").Value.Get().(bool)
 	repo.SkipArchitectureCheck = context.Flags().Lookup("force-architectures").Value.Get().(bool)
+	repo.DownloadTranslations = context.Flags().Lookup("with-translations").Value.Get().(bool)
+	repo.DownloadExtras = context.Flags().Lookup("with-extras").Value.Get().(bool)
+	applyMirrorDownloadFlags(repo, context.Flags())
+
+	if date := context.Flags().Lookup("date").Value.String(); date != "" {
+		var snapshotDate time.Time
+		snapshotDate, err = deb.ParseSnapshotDate(date)
+		if err != nil {
+			return fmt.Errorf("unable to create mirror: %s", err)
+		}
+
+		err = repo.PinToDate(snapshotDate)
+		if err != nil {
+			return fmt.Errorf("unable to create mirror: %s", err)
+		}
+	}
 
 	if repo.Filter != "" {
 		_, err = query.Parse(repo.Filter)
@@ -59,7 +76,12 @@ func aptlyMirrorCreate(cmd *commander.Command, args []string) error {
 		return fmt.Errorf("unable to initialize GPG verifier: %s", err)
 	}
 
-	err = repo.Fetch(context.Downloader(), verifier)
+	downloader, err := mirrorDownloader(repo)
+	if err != nil {
+		return err
+	}
+
+	err = repo.Fetch(downloader, verifier)
 	if err != nil {
 		return fmt.Errorf("unable to fetch mirror: %s", err)
 	}
@@ -87,6 +109,33 @@ PPA urls could specified in short format:
 
   $ aptly mirror create <name> ppa:<user>/<project>
 
+Local Debian archive (e.g. unpacked vendor tarball or NFS mount) could be mirrored by specifying file:// URL
+or directory path as archive url (path should be absolute or start with ./, so that URL with missing
+scheme is not taken for a path):
+
+  $ aptly mirror create vendor-stable /srv/vendor/debian/ stable main
+
+Mirror could be configured with its own HTTP transport options (proxy, CA certificates, client certificate,
+basic authentication and extra headers), which are applied on top of global downloadOptions from
+configuration file. Password could be read from file (-password-file) or environment variable
+(-password-env), so that it doesn't show up in process list; only the file path or variable name is stored.
+
+Auxiliary metadata listed in Release file (AppStream dep11/Components-* and icons, command-not-found
+cnf/Commands-*, Contents-*) could be mirrored with -with-extras; it is republished when snapshot of the
+mirror is published (mirrored Contents only if contents generation is disabled with -skip-contents).
+
+Alternate archive urls (mirrors of the same archive) could be specified with -fallback-url: when download from
+archive url fails, same file is downloaded from fallback urls in order (or spread round-robin with -round-robin);
+failing urls are tried last for the rest of the update. Interrupted downloads are resumed when retried
+within the same update (see -max-tries of 'aptly mirror update').
+
+Mirror could be pinned to the state of archive at specified date with -date, if archive follows
+snapshot.debian.org layout (<url>/archive/<name>/[<timestamp>/]): archive url and fallback urls
+are rewritten to point to archive snapshot, so that subsequent updates are reproducible
+(use -unpin of 'aptly mirror edit' to follow the latest state of archive again):
+
+  $ aptly mirror create -date=2020-01-01 buster-main http://snapshot.debian.org/archive/debian/ buster main
+
 Example:
 
   $ aptly mirror create wheezy-main http://mirror.yandex.ru/debian/ wheezy main
@@ -98,11 +147,15 @@ Example:
 	cmd.Flag.Bool("with-installer", false, "download additional not packaged installer files")
 	cmd.Flag.Bool("with-sources", false, "download source packages in addition to binary packages")
 	cmd.Flag.Bool("with-udebs", false, "download .udeb packages (Debian installer support)")
+	cmd.Flag.Bool("with-translations", false, "download translated package descriptions (i18n/Translation-*)")
+	cmd.Flag.Bool("with-extras", false, "download auxiliary metadata listed in Release file (dep11 AppStream, cnf command-not-found, Contents)")
 	cmd.Flag.String("filter", "", "filter packages in mirror")
+	cmd.Flag.String("date", "", "pin mirror to archive snapshot at date (YYYYMMDDTHHMMSSZ or YYYY-MM-DD), snapshot.debian.org layout")
 	cmd.Flag.Bool("filter-with-deps", false, "when filtering, include dependencies of matching packages as well")
 	cmd.Flag.Bool("force-components", false, "(only with component list) skip check that requested components are listed in Release file")
 	cmd.Flag.Bool("force-architectures", false, "(only with architecture list) skip check that requested architectures are listed in Release file")
 	cmd.Flag.Var(&keyRingsFlag{}, "keyring", "gpg keyring to use when verifying Release file (could be specified multiple times)")
+	addMirrorDownloadFlags(cmd)
 
 	return cmd
 }
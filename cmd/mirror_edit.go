@@ -2,7 +2,10 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/aptly-dev/aptly/aptly"
+	"github.com/aptly-dev/aptly/deb"
 	"github.com/aptly-dev/aptly/pgp"
 	"github.com/aptly-dev/aptly/query"
 	"github.com/smira/commander"
@@ -27,6 +30,7 @@ func aptlyMirrorEdit(cmd *commander.Command, args []string) error {
 	}
 
 	fetchMirror := false
+	snapshotDate := ""
 	context.Flags().Visit(func(flag *flag.Flag) {
 		switch flag.Name {
 		case "filter":
@@ -39,11 +43,51 @@ func aptlyMirrorEdit(cmd *commander.Command, args []string) error {
 			repo.DownloadSources = flag.Value.Get().(bool)
 		case "with-udebs":
 			repo.DownloadUdebs = flag.Value.Get().(bool)
+		case "with-translations":
+			repo.DownloadTranslations = flag.Value.Get().(bool)
+		case "with-extras":
+			repo.DownloadExtras = flag.Value.Get().(bool)
 		case "archive-url":
-			repo.SetArchiveRoot(flag.Value.String())
+			err = repo.SetArchiveRoot(flag.Value.String())
+			fetchMirror = true
+		case "date":
+			snapshotDate = flag.Value.String()
 			fetchMirror = true
 		}
 	})
+	if err != nil {
+		return fmt.Errorf("unable to edit: %s", err)
+	}
+	applyMirrorDownloadFlags(repo, context.Flags())
+
+	unpin := context.Flags().Lookup("unpin").Value.Get().(bool)
+	if unpin && snapshotDate != "" {
+		return fmt.Errorf("unable to edit: -date and -unpin can't be used together")
+	}
+
+	if unpin {
+		if repo.IsPinned() {
+			repo.Unpin()
+			fetchMirror = true
+		}
+	} else if snapshotDate != "" {
+		var date time.Time
+		date, err = deb.ParseSnapshotDate(snapshotDate)
+		if err != nil {
+			return fmt.Errorf("unable to edit: %s", err)
+		}
+
+		err = repo.PinToDate(date)
+		if err != nil {
+			return fmt.Errorf("unable to edit: %s", err)
+		}
+	} else if repo.IsPinned() {
+		// keep new archive url and fallback urls pinned to the same date
+		err = repo.PinToDate(repo.SnapshotDate)
+		if err != nil {
+			return fmt.Errorf("unable to edit: %s", err)
+		}
+	}
 
 	if repo.IsFlat() && repo.DownloadUdebs {
 		return fmt.Errorf("unable to edit: flat mirrors don't support udebs")
@@ -68,7 +112,13 @@ func aptlyMirrorEdit(cmd *commander.Command, args []string) error {
 			return fmt.Errorf("unable to initialize GPG verifier: %s", err)
 		}
 
-		err = repo.Fetch(context.Downloader(), verifier)
+		var downloader aptly.Downloader
+		downloader, err = mirrorDownloader(repo)
+		if err != nil {
+			return err
+		}
+
+		err = repo.Fetch(downloader, verifier)
 		if err != nil {
 			return fmt.Errorf("unable to edit: %s", err)
 		}
@@ -90,7 +140,9 @@ func makeCmdMirrorEdit() *commander.Command {
 		Short:     "edit mirror settings",
 		Long: `
 Command edit allows one to change settings of mirror:
-filters, list of architectures.
+filters, list of architectures, HTTP transport options, date of archive snapshot
+mirror is pinned to (see 'aptly mirror create'). Pinned mirror could be switched back
+to the latest state of the archive with -unpin.
 
 Example:
 
@@ -100,13 +152,18 @@ Example:
 	}
 
 	cmd.Flag.String("archive-url", "", "archive url is the root of archive")
+	cmd.Flag.String("date", "", "pin mirror to archive snapshot at date (YYYYMMDDTHHMMSSZ or YYYY-MM-DD), snapshot.debian.org layout")
 	cmd.Flag.String("filter", "", "filter packages in mirror")
 	cmd.Flag.Bool("filter-with-deps", false, "when filtering, include dependencies of matching packages as well")
+	cmd.Flag.Bool("unpin", false, "unpin mirror from archive snapshot date, so that it follows the latest state of archive")
 	cmd.Flag.Bool("ignore-signatures", false, "disable verification of Release file signatures")
 	cmd.Flag.Bool("with-installer", false, "download additional not packaged installer files")
 	cmd.Flag.Bool("with-sources", false, "download source packages in addition to binary packages")
 	cmd.Flag.Bool("with-udebs", false, "download .udeb packages (Debian installer support)")
+	cmd.Flag.Bool("with-translations", false, "download translated package descriptions (i18n/Translation-*)")
+	cmd.Flag.Bool("with-extras", false, "download auxiliary metadata listed in Release file (dep11 AppStream, cnf command-not-found, Contents)")
 	cmd.Flag.Var(&keyRingsFlag{}, "keyring", "gpg keyring to use when verifying Release file (could be specified multiple times)")
+	addMirrorDownloadFlags(cmd)
 
 	return cmd
 }
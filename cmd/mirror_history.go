@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aptly-dev/aptly/deb"
+	"github.com/smira/commander"
+	"github.com/smira/flag"
+)
+
+// notifyMirrorUpdate POSTs mirror update report to webhook
+func notifyMirrorUpdate(url string, report *deb.MirrorUpdateReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 60 * time.Second}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s returned %s", url, resp.Status)
+	}
+
+	return nil
+}
+
+func aptlyMirrorHistory(cmd *commander.Command, args []string) error {
+	var err error
+	if len(args) != 1 {
+		cmd.Usage()
+		return commander.ErrCommandError
+	}
+
+	repo, err := context.CollectionFactory().RemoteRepoCollection().ByName(args[0])
+	if err != nil {
+		return fmt.Errorf("unable to show history: %s", err)
+	}
+
+	reports, err := context.CollectionFactory().RemoteRepoCollection().UpdateReports(repo)
+	if err != nil {
+		return fmt.Errorf("unable to show history: %s", err)
+	}
+
+	if len(reports) == 0 {
+		fmt.Printf("Mirror `%s` hasn't been updated yet.\n", repo.Name)
+		return err
+	}
+
+	withPackages := context.Flags().Lookup("with-packages").Value.Get().(bool)
+
+	fmt.Printf("Updates of mirror `%s`:\n", repo.Name)
+	for _, report := range reports {
+		// same output as packages below, so that ordering is preserved
+		context.Progress().Printf(" * %s\n", report)
+
+		if !withPackages {
+			continue
+		}
+
+		for _, p := range report.Added {
+			context.Progress().ColoredPrintf("   @g+@| %s", p)
+		}
+		for _, p := range report.Removed {
+			context.Progress().ColoredPrintf("   @r-@| %s", p)
+		}
+		for _, p := range report.Upgraded {
+			context.Progress().ColoredPrintf("   @y!@| %s_%s: %s -> %s", p.Name, p.Architecture, p.OldVersion, p.NewVersion)
+		}
+		for _, p := range report.Downgraded {
+			context.Progress().ColoredPrintf("   @y<@| %s_%s: %s -> %s", p.Name, p.Architecture, p.OldVersion, p.NewVersion)
+		}
+	}
+
+	return err
+}
+
+func makeCmdMirrorHistory() *commander.Command {
+	cmd := &commander.Command{
+		Run:       aptlyMirrorHistory,
+		UsageLine: "history <name>",
+		Short:     "show history of mirror updates",
+		Long: `
+Command history displays reports of mirror updates: number of packages added, removed,
+upgraded and downgraded, amount of data downloaded and duration of each update. With
+-with-packages list of changed packages is displayed as well. Reports of 100 latest updates
+are kept.
+
+If mirrorUpdateWebhook is set in configuration file, report is POSTed in JSON format
+to the webhook after each successful update.
+
+Example:
+
+    $ aptly mirror history -with-packages wheezy-main
+`,
+		Flag: *flag.NewFlagSet("aptly-mirror-history", flag.ExitOnError),
+	}
+
+	cmd.Flag.Bool("with-packages", false, "show list of added, removed, upgraded and downgraded packages")
+
+	return cmd
+}
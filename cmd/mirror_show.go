@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"net/url"
+	"sort"
 	"strings"
 
 	"github.com/aptly-dev/aptly/deb"
@@ -34,6 +36,9 @@ func aptlyMirrorShow(cmd *commander.Command, args []string) error {
 		fmt.Printf("Status: In Update (PID %d)\n", repo.WorkerPID)
 	}
 	fmt.Printf("Archive Root URL: %s\n", repo.ArchiveRoot)
+	if repo.IsPinned() {
+		fmt.Printf("Snapshot Date: %s\n", repo.SnapshotDate.Format(deb.SnapshotDateFormat))
+	}
 	fmt.Printf("Distribution: %s\n", repo.Distribution)
 	fmt.Printf("Components: %s\n", strings.Join(repo.Components, ", "))
 	fmt.Printf("Architectures: %s\n", strings.Join(repo.Architectures, ", "))
@@ -47,6 +52,55 @@ func aptlyMirrorShow(cmd *commander.Command, args []string) error {
 		downloadUdebs = Yes
 	}
 	fmt.Printf("Download .udebs: %s\n", downloadUdebs)
+	downloadTranslations := No
+	if repo.DownloadTranslations {
+		downloadTranslations = Yes
+	}
+	fmt.Printf("Download Translations: %s\n", downloadTranslations)
+	if repo.DownloadExtras {
+		fmt.Printf("Download Extras: %s (%d files)\n", Yes, len(repo.ExtraFiles))
+	}
+	if len(repo.FallbackArchiveRoots) > 0 {
+		fmt.Printf("Fallback Archive Root URLs: %s\n", strings.Join(repo.FallbackArchiveRoots, ", "))
+		roundRobin := No
+		if repo.RoundRobinArchiveRoots {
+			roundRobin = Yes
+		}
+		fmt.Printf("Round-Robin Downloads: %s\n", roundRobin)
+	}
+	if repo.DownloadOptions.Proxy != "" {
+		proxy := repo.DownloadOptions.Proxy
+		if proxyURL, err := url.Parse(proxy); err == nil && proxyURL.User != nil {
+			// credentials are not shown
+			proxyURL.User = url.User(proxyURL.User.Username())
+			proxy = proxyURL.String()
+		}
+		fmt.Printf("Proxy: %s\n", proxy)
+	}
+	if repo.DownloadOptions.CACertFile != "" {
+		fmt.Printf("CA Certificates: %s\n", repo.DownloadOptions.CACertFile)
+	}
+	if repo.DownloadOptions.ClientCertFile != "" {
+		fmt.Printf("Client Certificate: %s\n", repo.DownloadOptions.ClientCertFile)
+	}
+	if repo.DownloadOptions.Username != "" {
+		fmt.Printf("Basic Auth User: %s\n", repo.DownloadOptions.Username)
+	}
+	if repo.DownloadOptions.PasswordFile != "" {
+		fmt.Printf("Basic Auth Password File: %s\n", repo.DownloadOptions.PasswordFile)
+	}
+	if repo.DownloadOptions.PasswordEnv != "" {
+		fmt.Printf("Basic Auth Password Variable: %s\n", repo.DownloadOptions.PasswordEnv)
+	}
+	if len(repo.DownloadOptions.Headers) > 0 {
+		headers := make([]string, 0, len(repo.DownloadOptions.Headers))
+		for name := range repo.DownloadOptions.Headers {
+			headers = append(headers, name)
+		}
+		sort.Strings(headers)
+		// header values might contain tokens, so only names are shown
+		fmt.Printf("Extra Headers: %s\n", strings.Join(headers, ", "))
+	}
 	if repo.Filter != "" {
 		fmt.Printf("Filter: %s\n", repo.Filter)
 		filterWithDeps := No
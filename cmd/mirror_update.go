@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/aptly-dev/aptly/aptly"
 	"github.com/aptly-dev/aptly/deb"
+	"github.com/aptly-dev/aptly/http"
 	"github.com/aptly-dev/aptly/query"
 	"github.com/aptly-dev/aptly/utils"
 	"github.com/smira/commander"
@@ -47,17 +49,43 @@ func aptlyMirrorUpdate(cmd *commander.Command, args []string) error {
 		return fmt.Errorf("unable to initialize GPG verifier: %s", err)
 	}
 
-	err = repo.Fetch(context.Downloader(), verifier)
+	downloader, err := mirrorDownloader(repo)
+	if err != nil {
+		return err
+	}
+
+	limiter, err := context.DownloadLimiter()
+	if err != nil {
+		return err
+	}
+	// quota is per update
+	limiter.ResetUsage()
+
+	err = repo.Fetch(downloader, verifier)
 	if err != nil {
 		return fmt.Errorf("unable to update: %s", err)
 	}
 
+	if !context.Flags().Lookup("skip-pdiffs").Value.Get().(bool) {
+		repo.SetIndexCacheDir(mirrorIndexCacheDir())
+	}
+	repo.SetIndexConcurrency(context.Config().DownloadConcurrency)
+
 	context.Progress().Printf("Downloading & parsing package files...\n")
-	err = repo.DownloadPackageIndexes(context.Progress(), context.Downloader(), verifier, context.CollectionFactory(), ignoreMismatch)
+	err = repo.DownloadPackageIndexes(context.Progress(), downloader, verifier, context.CollectionFactory(), ignoreMismatch)
 	if err != nil {
 		return fmt.Errorf("unable to update: %s", err)
 	}
 
+	if repo.DownloadExtras {
+		context.Progress().Printf("Downloading auxiliary metadata files...\n")
+		err = repo.DownloadExtraFiles(context.Progress(), downloader, context.PackagePool(),
+			context.CollectionFactory().ChecksumCollection(nil), ignoreMismatch)
+		if err != nil {
+			return fmt.Errorf("unable to update: %s", err)
+		}
+	}
+
 	if repo.Filter != "" {
 		context.Progress().Printf("Applying filter...\n")
 		var filterQuery deb.PackageQuery
@@ -82,6 +110,8 @@ func aptlyMirrorUpdate(cmd *commander.Command, args []string) error {
 
 	skipExistingPackages := context.Flags().Lookup("skip-existing-packages").Value.Get().(bool)
 
+	repo.SetPoolPeers(context.Config().PoolPeers)
+
 	context.Progress().Printf("Building download queue...\n")
 	queue, downloadSize, err = repo.BuildDownloadQueue(context.PackagePool(), context.CollectionFactory().PackageCollection(),
 		context.CollectionFactory().ChecksumCollection(nil), skipExistingPackages)
@@ -121,13 +151,20 @@ func aptlyMirrorUpdate(cmd *commander.Command, args []string) error {
 	downloadQueue := make(chan int)
 
 	var (
-		errors  []string
-		errLock sync.Mutex
+		errors        []string
+		errLock       sync.Mutex
+		quotaExceeded bool
+		peerFiles     int64
 	)
 
 	pushError := func(err error) {
 		errLock.Lock()
-		errors = append(errors, err.Error())
+		if http.IsQuotaExceeded(err) {
+			// remaining downloads fail immediately, update is aborted after importing what's been downloaded
+			quotaExceeded = true
+		} else {
+			errors = append(errors, err.Error())
+		}
 		errLock.Unlock()
 	}
 
@@ -142,6 +179,12 @@ func aptlyMirrorUpdate(cmd *commander.Command, args []string) error {
 		close(downloadQueue)
 	}()
 
+	// pools of peers are fetched without credentials & headers of upstream archive
+	peerDownloader, err := context.PeerDownloader()
+	if err != nil {
+		return fmt.Errorf("unable to update: %s", err)
+	}
+
 	var wg sync.WaitGroup
 
 	for i := 0; i < context.Config().DownloadConcurrency; i++ {
@@ -159,6 +202,19 @@ func aptlyMirrorUpdate(cmd *commander.Command, args []string) error {
 
 					var e error
 
+					if repo.IsLocal() {
+						// local archive: verify file in place, it would be linked into the pool
+						task.TempDownPath = repo.PackageURL(task.File.DownloadURL()).Path
+						e = http.VerifyLocalFile(task.TempDownPath, &task.File.Checksums, ignoreMismatch, context.Progress())
+						if e != nil {
+							pushError(e)
+							continue
+						}
+
+						task.Done = true
+						continue
+					}
+
 					// provision download location
 					task.TempDownPath, e = context.PackagePool().(aptly.LocalPackagePool).GenerateTempPath(task.File.Filename)
 					if e != nil {
@@ -166,8 +222,25 @@ func aptlyMirrorUpdate(cmd *commander.Command, args []string) error {
 						continue
 					}
 
+					// try pools of peers first, files are verified by checksum just like upstream ones
+					for _, peerURL := range task.PeerURLs {
+						e = peerDownloader.DownloadWithChecksum(context, peerURL, task.TempDownPath, &task.File.Checksums, false)
+						if e == nil || http.IsQuotaExceeded(e) {
+							break
+						}
+					}
+					if len(task.PeerURLs) > 0 && e == nil {
+						atomic.AddInt64(&peerFiles, 1)
+						task.Done = true
+						continue
+					}
+					if e != nil && http.IsQuotaExceeded(e) {
+						pushError(e)
+						continue
+					}
+
 					// download file...
-					e = context.Downloader().DownloadWithChecksum(
+					e = downloader.DownloadWithChecksum(
 						context,
 						repo.PackageURL(task.File.DownloadURL()).String(),
 						task.TempDownPath,
@@ -210,7 +283,8 @@ func aptlyMirrorUpdate(cmd *commander.Command, args []string) error {
 		}
 
 		// and import it back to the pool
-		task.File.PoolPath, err = context.PackagePool().Import(task.TempDownPath, task.File.Filename, &task.File.Checksums, true, context.CollectionFactory().ChecksumCollection(nil))
+		// files from local archive are linked (or copied), not moved
+		task.File.PoolPath, err = context.PackagePool().Import(task.TempDownPath, task.File.Filename, &task.File.Checksums, !repo.IsLocal(), context.CollectionFactory().ChecksumCollection(nil))
 		if err != nil {
 			return fmt.Errorf("unable to import file: %s", err)
 		}
@@ -230,16 +304,66 @@ func aptlyMirrorUpdate(cmd *commander.Command, args []string) error {
 	default:
 	}
 
+	if quotaExceeded {
+		downloaded := 0
+		for idx := range queue {
+			if queue[idx].Done {
+				downloaded++
+			}
+		}
+		return fmt.Errorf("unable to update: download quota exceeded, %d of %d files downloaded, run update again to continue", downloaded, count)
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("unable to update: download errors:\n  %s", strings.Join(errors, "\n  "))
 	}
 
-	repo.FinalizeDownload(context.CollectionFactory(), context.Progress())
+	var (
+		downloadedFiles int
+		downloadedBytes int64
+	)
+	for idx := range queue {
+		if queue[idx].Done && !repo.IsLocal() {
+			downloadedFiles++
+			downloadedBytes += queue[idx].File.Checksums.Size
+		}
+	}
+	repo.SetDownloadStats(downloadedFiles, downloadedBytes)
+
+	err = repo.FinalizeDownload(context.CollectionFactory(), context.Progress())
+	if err != nil {
+		return fmt.Errorf("unable to update: %s", err)
+	}
+
 	err = context.CollectionFactory().RemoteRepoCollection().Update(repo)
 	if err != nil {
 		return fmt.Errorf("unable to update: %s", err)
 	}
 
+	err = context.CollectionFactory().RemoteRepoCollection().AddUpdateReport(repo.UpdateReport())
+	if err != nil {
+		return fmt.Errorf("unable to update: %s", err)
+	}
+
+	if context.Config().MirrorUpdateWebhook != "" {
+		if e := notifyMirrorUpdate(context.Config().MirrorUpdateWebhook, repo.UpdateReport()); e != nil {
+			context.Progress().ColoredPrintf("@y[!]@| @!unable to notify webhook: %s@|", e)
+		}
+	}
+
+	if failover, ok := downloader.(*http.FailoverDownloader); ok {
+		failures := failover.Failures()
+		for _, root := range append([]string{repo.ArchiveRoot}, repo.FallbackArchiveRoots...) {
+			if failures[root] > 0 {
+				context.Progress().Printf("Archive root %s: %d failed downloads\n", root, failures[root])
+			}
+		}
+	}
+
+	if peerFiles > 0 {
+		context.Progress().Printf("Fetched %d files from pool peers\n", peerFiles)
+	}
+
 	context.Progress().Printf("\nMirror `%s` has been successfully updated.\n", repo.Name)
 	return err
 }
@@ -254,6 +378,26 @@ Updates remote mirror (downloads package files and meta information). When mirro
 this command should be run for the first time to fetch mirror contents. This command can be
 run multiple times to get updated repository contents. If interrupted, command can be safely restarted.
 
+Mirrors of local archives (file:// URL or plain directory path) are verified the same way as remote ones,
+but package files are hardlinked into the package pool (when on the same filesystem) instead of being copied.
+
+If remote repository publishes pdiffs (Packages.diff/Index), aptly keeps copy of last downloaded package
+indexes and on subsequent updates downloads only patches, falling back to full download when patches
+can't be applied.
+
+Changes made by each update (added, removed and upgraded packages) are recorded, see 'aptly mirror history'.
+
+Package indexes of different components and architectures are downloaded and parsed in parallel
+(up to downloadConcurrency from configuration file).
+
+If poolPeers are set in configuration file, package files are looked up by checksum in the pools of
+other aptly instances (served by 'aptly api serve') before being downloaded from upstream.
+
+Download speed could be limited with -download-limit (or downloadSpeedLimit and downloadSchedule in configuration
+file). With -download-quota (or downloadQuota) update is aborted once specified amount of data has been downloaded:
+files downloaded completely are kept in the package pool, so next update downloads only the remaining
+files (partially downloaded files are discarded).
+
 Example:
 
   $ aptly mirror update wheezy-main
@@ -265,7 +409,9 @@ Example:
 	cmd.Flag.Bool("ignore-checksums", false, "ignore checksum mismatches while downloading package files and metadata")
 	cmd.Flag.Bool("ignore-signatures", false, "disable verification of Release file signatures")
 	cmd.Flag.Bool("skip-existing-packages", false, "do not check file existence for packages listed in the internal database of the mirror")
+	cmd.Flag.Bool("skip-pdiffs", false, "always download full package indexes instead of applying pdiff patches (Packages.diff/Index)")
 	cmd.Flag.Int64("download-limit", 0, "limit download speed (kbytes/sec)")
+	cmd.Flag.Int64("download-quota", 0, "abort update after downloading specified amount of data (MiB)")
 	cmd.Flag.Int("max-tries", 1, "max download tries till process fails with download error")
 	cmd.Flag.Var(&keyRingsFlag{}, "keyring", "gpg keyring to use when verifying Release file (could be specified multiple times)")
 
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aptly-dev/aptly/deb"
+	"github.com/aptly-dev/aptly/utils"
+	"github.com/smira/commander"
+)
+
+func makeCmdOverride() *commander.Command {
+	return &commander.Command{
+		UsageLine: "override",
+		Short:     "manage overrides of package fields in published repositories",
+		Subcommands: []*commander.Command{
+			makeCmdOverrideImport(),
+			makeCmdOverrideShow(),
+		},
+	}
+}
+
+// overridePublishedRepo looks up published repository by distribution and optional [<endpoint>:]<prefix>
+// and picks component (specified or the only one)
+func overridePublishedRepo(distribution, param, component string) (*deb.PublishedRepo, string, error) {
+	storage, prefix := deb.ParsePrefix(param)
+
+	published, err := context.CollectionFactory().PublishedRepoCollection().ByStoragePrefixDistribution(storage, prefix, distribution)
+	if err != nil {
+		return nil, "", err
+	}
+
+	components := published.Components()
+	if component == "" {
+		if len(components) != 1 {
+			return nil, "", fmt.Errorf("published repository has several components, please specify one with -component")
+		}
+		component = components[0]
+	} else if !utils.StrSliceHasItem(components, component) {
+		return nil, "", fmt.Errorf("component %s is not published in %s/%s", component, published.StoragePrefix(), published.Distribution)
+	}
+
+	return published, component, nil
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aptly-dev/aptly/deb"
+	"github.com/smira/commander"
+	"github.com/smira/flag"
+)
+
+func aptlyOverrideImport(cmd *commander.Command, args []string) error {
+	var err error
+	if len(args) < 2 || len(args) > 3 {
+		cmd.Usage()
+		return commander.ErrCommandError
+	}
+
+	distribution, overrideFile := args[0], args[1]
+	param := "."
+
+	if len(args) == 3 {
+		param = args[2]
+	}
+
+	overrideType := context.Flags().Lookup("type").Value.String()
+
+	published, component, err := overridePublishedRepo(distribution, param, context.Flags().Lookup("component").Value.String())
+	if err != nil {
+		return fmt.Errorf("unable to import overrides: %s", err)
+	}
+
+	f, err := os.Open(overrideFile)
+	if err != nil {
+		return fmt.Errorf("unable to import overrides: %s", err)
+	}
+	defer f.Close()
+
+	overrides, err := deb.ParseOverrides(f, overrideType)
+	if err != nil {
+		return fmt.Errorf("unable to import overrides from %s: %s", overrideFile, err)
+	}
+
+	err = published.SetComponentOverrides(component, overrideType, overrides)
+	if err != nil {
+		return fmt.Errorf("unable to import overrides: %s", err)
+	}
+
+	err = context.CollectionFactory().PublishedRepoCollection().Update(published)
+	if err != nil {
+		return fmt.Errorf("unable to save to DB: %s", err)
+	}
+
+	context.Progress().Printf("Imported %d %s overrides for component %s of %s/%s.\n", len(overrides), overrideType, component,
+		published.StoragePrefix(), published.Distribution)
+	context.Progress().Printf("Overrides would be applied next time repository is published (e.g. with 'aptly publish update').\n")
+
+	return err
+}
+
+func makeCmdOverrideImport() *commander.Command {
+	cmd := &commander.Command{
+		Run:       aptlyOverrideImport,
+		UsageLine: "import <distribution> <override-file> [[<endpoint>:]<prefix>]",
+		Short:     "import override file for published repository",
+		Long: `
+Command import loads Debian-style override file and attaches it to the component
+of published repository <distribution> and <prefix>. Overrides are applied
+to Packages and Sources indexes every time repository is published (with
+'aptly publish update' or 'aptly publish switch'). Importing replaces all the overrides
+of the same type for the component, so importing empty file drops them.
+
+Override file types (-type):
+
+  binary: lines 'package priority section [maintainer]', overrides Priority and Section of binary packages
+  source: lines 'package section', overrides Section of source packages
+  extra: lines 'package field value', sets any field (e.g. Task) of binary packages
+
+Example:
+
+    $ aptly override import -type=binary -component=main wheezy override.wheezy.main ppa
+`,
+		Flag: *flag.NewFlagSet("aptly-override-import", flag.ExitOnError),
+	}
+	cmd.Flag.String("type", deb.OverrideBinary, "type of override file: binary, source or extra")
+	cmd.Flag.String("component", "", "component to apply overrides to (required if repository has several components)")
+
+	return cmd
+}
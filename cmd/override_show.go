@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aptly-dev/aptly/deb"
+	"github.com/smira/commander"
+	"github.com/smira/flag"
+)
+
+func aptlyOverrideShow(cmd *commander.Command, args []string) error {
+	var err error
+	if len(args) < 1 || len(args) > 2 {
+		cmd.Usage()
+		return commander.ErrCommandError
+	}
+
+	distribution := args[0]
+	param := "."
+
+	if len(args) == 2 {
+		param = args[1]
+	}
+
+	published, component, err := overridePublishedRepo(distribution, param, context.Flags().Lookup("component").Value.String())
+	if err != nil {
+		return fmt.Errorf("unable to show: %s", err)
+	}
+
+	overrides := published.ComponentOverrides(component)
+	if overrides == nil {
+		overrides = deb.NewPackageOverrides()
+	}
+
+	lines, err := overrides.Lines(context.Flags().Lookup("type").Value.String())
+	if err != nil {
+		return fmt.Errorf("unable to show: %s", err)
+	}
+
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+
+	return err
+}
+
+func makeCmdOverrideShow() *commander.Command {
+	cmd := &commander.Command{
+		Run:       aptlyOverrideShow,
+		UsageLine: "show <distribution> [[<endpoint>:]<prefix>]",
+		Short:     "show overrides of published repository",
+		Long: `
+Command show prints overrides of specified type attached to the component of
+published repository in override file format.
+
+Example:
+
+    $ aptly override show -type=extra -component=main wheezy ppa
+`,
+		Flag: *flag.NewFlagSet("aptly-override-show", flag.ExitOnError),
+	}
+	cmd.Flag.String("type", deb.OverrideBinary, "type of overrides: binary, source or extra")
+	cmd.Flag.String("component", "", "component (required if repository has several components)")
+
+	return cmd
+}
@@ -9,6 +9,7 @@ func makeCmdPackage() *commander.Command {
 		UsageLine: "package",
 		Short:     "operations on packages",
 		Subcommands: []*commander.Command{
+			makeCmdPackageRdepends(),
 			makeCmdPackageSearch(),
 			makeCmdPackageShow(),
 		},
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aptly-dev/aptly/deb"
+	"github.com/aptly-dev/aptly/query"
+	"github.com/smira/commander"
+	"github.com/smira/flag"
+)
+
+// rdependsRefList returns list of packages to look for reverse dependencies in
+func rdependsRefList() (*deb.PackageRefList, error) {
+	snapshotName := context.Flags().Lookup("snapshot").Value.String()
+	repoName := context.Flags().Lookup("repo").Value.String()
+	mirrorName := context.Flags().Lookup("mirror").Value.String()
+
+	given := 0
+	for _, name := range []string{snapshotName, repoName, mirrorName} {
+		if name != "" {
+			given++
+		}
+	}
+	if given > 1 {
+		return nil, fmt.Errorf("only one of -snapshot, -repo and -mirror could be specified")
+	}
+
+	switch {
+	case snapshotName != "":
+		snapshot, err := context.CollectionFactory().SnapshotCollection().ByName(snapshotName)
+		if err != nil {
+			return nil, err
+		}
+
+		err = context.CollectionFactory().SnapshotCollection().LoadComplete(snapshot)
+		if err != nil {
+			return nil, err
+		}
+
+		return snapshot.RefList(), nil
+	case repoName != "":
+		repo, err := context.CollectionFactory().LocalRepoCollection().ByName(repoName)
+		if err != nil {
+			return nil, err
+		}
+
+		err = context.CollectionFactory().LocalRepoCollection().LoadComplete(repo)
+		if err != nil {
+			return nil, err
+		}
+
+		return repo.RefList(), nil
+	case mirrorName != "":
+		repo, err := context.CollectionFactory().RemoteRepoCollection().ByName(mirrorName)
+		if err != nil {
+			return nil, err
+		}
+
+		err = context.CollectionFactory().RemoteRepoCollection().LoadComplete(repo)
+		if err != nil {
+			return nil, err
+		}
+
+		if repo.RefList() == nil {
+			return nil, fmt.Errorf("mirror %s hasn't been downloaded yet", repo.Name)
+		}
+
+		return repo.RefList(), nil
+	}
+
+	return context.CollectionFactory().PackageCollection().AllPackageRefs(), nil
+}
+
+func aptlyPackageRdepends(cmd *commander.Command, args []string) error {
+	var err error
+	if len(args) != 1 {
+		cmd.Usage()
+		return commander.ErrCommandError
+	}
+
+	q, err := query.Parse(args[0])
+	if err != nil {
+		return fmt.Errorf("unable to search: %s", err)
+	}
+
+	reflist, err := rdependsRefList()
+	if err != nil {
+		return fmt.Errorf("unable to search: %s", err)
+	}
+
+	list, err := deb.NewPackageListFromRefList(reflist, context.CollectionFactory().PackageCollection(), context.Progress())
+	if err != nil {
+		return fmt.Errorf("unable to load packages: %s", err)
+	}
+
+	list.PrepareIndex()
+
+	result, err := list.Filter([]deb.PackageQuery{q}, false, nil, 0, nil)
+	if err != nil {
+		return fmt.Errorf("unable to search: %s", err)
+	}
+
+	if result.Len() == 0 {
+		return fmt.Errorf("no results")
+	}
+
+	index := deb.NewReverseDependencyIndex(list, context.DependencyOptions()|deb.DepFollowBuild)
+
+	result.PrepareIndex()
+
+	return result.ForEachIndexed(func(p *deb.Package) error {
+		fmt.Printf("Reverse dependencies of %s:\n", p)
+
+		rdeps := index.Lookup(p)
+		if len(rdeps) == 0 {
+			fmt.Printf("  none\n")
+		}
+
+		for _, rdep := range rdeps {
+			fmt.Printf("  %s %s: %s\n", rdep.Package, rdep.Field, rdep.Dependency)
+		}
+
+		return nil
+	})
+}
+
+func makeCmdPackageRdepends() *commander.Command {
+	cmd := &commander.Command{
+		Run:       aptlyPackageRdepends,
+		UsageLine: "rdepends [-snapshot=<name>|-repo=<name>|-mirror=<name>] <package-query>",
+		Short:     "show packages depending on packages matching query",
+		Long: `
+Command rdepends displays packages which depend on packages matching query
+(directly or via virtual packages they provide). Search is performed in the
+snapshot, local repo or mirror if specified, otherwise in the whole DB.
+
+Pre-Depends, Depends, Build-Depends and Build-Depends-Indep are always considered,
+Recommends and Suggests are considered if -dep-follow-recommends or -dep-follow-suggests
+is enabled.
+
+Example:
+
+    $ aptly package rdepends -snapshot=wheezy-main libssl1.0.0
+`,
+		Flag: *flag.NewFlagSet("aptly-package-rdepends", flag.ExitOnError),
+	}
+
+	cmd.Flag.String("snapshot", "", "look for reverse dependencies in snapshot")
+	cmd.Flag.String("repo", "", "look for reverse dependencies in local repo")
+	cmd.Flag.String("mirror", "", "look for reverse dependencies in mirror")
+
+	return cmd
+}
@@ -29,7 +29,27 @@ func aptlyPackageSearch(cmd *commander.Command, args []string) error {
 		q = &deb.MatchAllQuery{}
 	}
 
-	result := q.Query(context.CollectionFactory().PackageCollection())
+	var result *deb.PackageList
+
+	collection := context.CollectionFactory().PackageCollection()
+	if deb.HasReverseDependsQuery(q) {
+		// reverse dependencies are resolved against list of all the packages
+		var list *deb.PackageList
+		list, err = deb.NewPackageListFromRefList(collection.AllPackageRefs(), collection, nil)
+		if err != nil {
+			return fmt.Errorf("unable to load packages: %s", err)
+		}
+
+		list.PrepareIndex()
+
+		result, err = list.Filter([]deb.PackageQuery{q}, false, nil, 0, nil)
+		if err != nil {
+			return fmt.Errorf("unable to search: %s", err)
+		}
+	} else {
+		result = q.Query(collection)
+	}
+
 	if result.Len() == 0 {
 		return fmt.Errorf("no results")
 	}
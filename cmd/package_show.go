@@ -73,6 +73,7 @@ func aptlyPackageShow(cmd *commander.Command, args []string) error {
 
 	withFiles := context.Flags().Lookup("with-files").Value.Get().(bool)
 	withReferences := context.Flags().Lookup("with-references").Value.Get().(bool)
+	withProvenance := context.Flags().Lookup("provenance").Value.Get().(bool)
 
 	w := bufio.NewWriter(os.Stdout)
 
@@ -108,6 +109,23 @@ func aptlyPackageShow(cmd *commander.Command, args []string) error {
 			fmt.Printf("\n")
 		}
 
+		if withProvenance {
+			var provenance *deb.PackageProvenance
+
+			provenance, err = context.CollectionFactory().PackageCollection().Provenance(p.Key(""))
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Provenance:\n")
+			if provenance != nil {
+				fmt.Printf("  %s\n", provenance)
+			} else {
+				fmt.Printf("  unknown\n")
+			}
+			fmt.Printf("\n")
+		}
+
 		return nil
 	})
 
@@ -126,8 +144,9 @@ func makeCmdPackageShow() *commander.Command {
 		Long: `
 Command shows displays detailed meta-information about packages
 matching query. Information from Debian control file is displayed.
-Optionally information about package files and
-inclusion into mirrors/snapshots/local repos is shown.
+Optionally information about package files,
+inclusion into mirrors/snapshots/local repos and provenance (mirror or local
+repo which introduced the package, when and by whom) is shown.
 
 Example:
 
@@ -138,6 +157,7 @@ Example:
 
 	cmd.Flag.Bool("with-files", false, "display information about files from package pool")
 	cmd.Flag.Bool("with-references", false, "display information about mirrors, snapshots and local repos referencing this package")
+	cmd.Flag.Bool("provenance", false, "display information about mirror or local repo package has come from")
 
 	return cmd
 }
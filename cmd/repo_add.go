@@ -6,6 +6,7 @@ import (
 
 	"github.com/aptly-dev/aptly/aptly"
 	"github.com/aptly-dev/aptly/deb"
+	"github.com/aptly-dev/aptly/query"
 	"github.com/aptly-dev/aptly/utils"
 	"github.com/smira/commander"
 	"github.com/smira/flag"
@@ -32,6 +33,13 @@ func aptlyRepoAdd(cmd *commander.Command, args []string) error {
 		return fmt.Errorf("unable to add: %s", err)
 	}
 
+	if repo.Uploaders != nil {
+		err = repo.Uploaders.Compile(query.Parse)
+		if err != nil {
+			return fmt.Errorf("unable to add: %s", err)
+		}
+	}
+
 	context.Progress().Printf("Loading packages...\n")
 
 	list, err := deb.NewPackageListFromRefList(repo.RefList(), context.CollectionFactory().PackageCollection(), context.Progress())
@@ -49,7 +57,7 @@ func aptlyRepoAdd(cmd *commander.Command, args []string) error {
 
 	processedFiles, failedFiles2, err = deb.ImportPackageFiles(list, packageFiles, forceReplace, verifier, context.PackagePool(),
 		context.CollectionFactory().PackageCollection(), &aptly.ConsoleResultReporter{Progress: context.Progress()}, nil,
-		context.CollectionFactory().ChecksumCollection)
+		context.CollectionFactory().ChecksumCollection, repo.Uploaders, "", deb.NewLocalRepoProvenance(repo, ""))
 	failedFiles = append(failedFiles, failedFiles2...)
 	if err != nil {
 		return fmt.Errorf("unable to import package files: %s", err)
@@ -99,6 +107,10 @@ patterns. Every file discovered would be analyzed to extract metadata, package w
 to the database. Files would be imported to internal package pool. For source packages, all required files are
 added automatically as well. Extra files for source package should be in the same directory as *.dsc file.
 
+If local repository has uploaders restrictions configured (see 'aptly repo create -uploaders-file'), every
+package is checked against them: signed .dsc files and .deb files signed with dpkg-sig are matched against
+signature keys, while unsigned packages are accepted only if matching rule allows '*'.
+
 Example:
 
   $ aptly repo add testing myapp-0.1.2.deb incoming/
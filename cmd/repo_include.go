@@ -40,11 +40,9 @@ func aptlyRepoInclude(cmd *commander.Command, args []string) error {
 			return err
 		}
 
-		for i := range uploaders.Rules {
-			uploaders.Rules[i].CompiledCondition, err = query.Parse(uploaders.Rules[i].Condition)
-			if err != nil {
-				return fmt.Errorf("error parsing query %s: %s", uploaders.Rules[i].Condition, err)
-			}
+		err = uploaders.Compile(query.Parse)
+		if err != nil {
+			return err
 		}
 	}
 
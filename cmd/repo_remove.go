@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/aptly-dev/aptly/deb"
 	"github.com/aptly-dev/aptly/query"
@@ -11,7 +12,15 @@ import (
 
 func aptlyRepoRemove(cmd *commander.Command, args []string) error {
 	var err error
-	if len(args) < 2 {
+
+	keepVersions := context.Flags().Lookup("keep-versions").Value.Get().(int)
+	keepDays := context.Flags().Lookup("keep-days").Value.Get().(int)
+
+	if keepVersions < 0 || keepDays < 0 {
+		return fmt.Errorf("unable to remove: -keep-versions and -keep-days should not be negative")
+	}
+
+	if len(args) < 2 && !(len(args) == 1 && (keepVersions > 0 || keepDays > 0)) {
 		cmd.Usage()
 		return commander.ErrCommandError
 	}
@@ -43,6 +52,19 @@ func aptlyRepoRemove(cmd *commander.Command, args []string) error {
 		}
 	}
 
+	if len(queries) == 0 {
+		queries = []deb.PackageQuery{&deb.MatchAllQuery{}}
+	}
+
+	var retained *deb.PackageRefList
+	if keepVersions > 0 || keepDays > 0 {
+		retained, err = repo.RefList().RetainRefs(keepVersions, time.Duration(keepDays)*24*time.Hour,
+			context.CollectionFactory().PackageCollection())
+		if err != nil {
+			return fmt.Errorf("unable to remove: %s", err)
+		}
+	}
+
 	list.PrepareIndex()
 	toRemove, err := list.Filter(queries, false, nil, 0, nil)
 	if err != nil {
@@ -50,6 +72,9 @@ func aptlyRepoRemove(cmd *commander.Command, args []string) error {
 	}
 
 	toRemove.ForEach(func(p *deb.Package) error {
+		if retained != nil && retained.Has(p) {
+			return nil
+		}
 		list.Remove(p)
 		context.Progress().ColoredPrintf("@r[-]@| %s removed", p)
 		return nil
@@ -72,7 +97,7 @@ func aptlyRepoRemove(cmd *commander.Command, args []string) error {
 func makeCmdRepoRemove() *commander.Command {
 	cmd := &commander.Command{
 		Run:       aptlyRepoRemove,
-		UsageLine: "remove <name> <package-query> ...",
+		UsageLine: "remove <name> [<package-query> ...]",
 		Short:     "remove packages from local repository",
 		Long: `
 Commands removes packages matching <package-query> from local repository
@@ -83,11 +108,27 @@ snapshots, they can be removed completely (including files) by running
 Example:
 
   $ aptly repo remove testing 'myapp (=0.1.12)'
+
+With -keep-versions=N, N latest versions of each package (per architecture)
+are never removed, so old versions could be expired (<package-query> defaults
+to all the packages in this case):
+
+  $ aptly repo remove -keep-versions=3 testing 'myapp'
+
+With -keep-days=N, versions first seen by aptly within last N days are never
+removed, the latest version of each package is always kept. Age comes from package
+provenance: it is the date package was first imported into aptly (by any mirror,
+repo or upload), not the date it was added to this repo. Packages imported before
+provenance has been recorded have unknown age and are never removed by this rule. When both flags are given, version is kept if any of the rules keeps it:
+
+  $ aptly repo remove -keep-versions=3 -keep-days=90 testing
 `,
 		Flag: *flag.NewFlagSet("aptly-repo-add", flag.ExitOnError),
 	}
 
 	cmd.Flag.Bool("dry-run", false, "don't remove, just show what would be removed")
+	cmd.Flag.Int("keep-versions", 0, "don't remove N latest versions of each package")
+	cmd.Flag.Int("keep-days", 0, "don't remove versions first seen by aptly (in any repo or mirror) within last N days")
 
 	return cmd
 }
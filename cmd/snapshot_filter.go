@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/aptly-dev/aptly/deb"
 	"github.com/aptly-dev/aptly/query"
@@ -19,6 +20,12 @@ func aptlySnapshotFilter(cmd *commander.Command, args []string) error {
 	}
 
 	withDeps := context.Flags().Lookup("with-deps").Value.Get().(bool)
+	keepVersions := context.Flags().Lookup("keep-versions").Value.Get().(int)
+	keepDays := context.Flags().Lookup("keep-days").Value.Get().(int)
+
+	if keepVersions < 0 || keepDays < 0 {
+		return fmt.Errorf("unable to filter: -keep-versions and -keep-days should not be negative")
+	}
 
 	// Load <source> snapshot
 	source, err := context.CollectionFactory().SnapshotCollection().ByName(args[0])
@@ -71,9 +78,16 @@ func aptlySnapshotFilter(cmd *commander.Command, args []string) error {
 		return fmt.Errorf("unable to filter: %s", err)
 	}
 
+	refList, err := deb.NewPackageRefListFromPackageList(result).RetainRefs(keepVersions,
+		time.Duration(keepDays)*24*time.Hour, context.CollectionFactory().PackageCollection())
+	if err != nil {
+		return fmt.Errorf("unable to filter: %s", err)
+	}
+
 	// Create <destination> snapshot
-	destination := deb.NewSnapshotFromPackageList(args[1], []*deb.Snapshot{source}, result,
+	destination := deb.NewSnapshotFromRefList(args[1], []*deb.Snapshot{source}, refList,
 		fmt.Sprintf("Filtered '%s', query was: '%s'", source.Name, strings.Join(args[2:], " ")))
+	destination.ExtraFiles = source.ExtraFiles
 
 	err = context.CollectionFactory().SnapshotCollection().Add(destination)
 	if err != nil {
@@ -98,11 +112,26 @@ as 'package-name' or as package queries.
 Example:
 
     $ aptly snapshot filter wheezy-main wheezy-required 'Priority (required)'
+
+With -keep-versions=N, only N latest versions of each package (per
+architecture) are kept in <destination>:
+
+    $ aptly snapshot filter -keep-versions=3 builds builds-latest 'Name'
+
+With -keep-days=N, versions first seen by aptly more than N days ago are dropped (the
+latest version of each package is always kept, as well as packages with unknown
+age). Age comes from package provenance: it is the date package was first imported
+into aptly by any mirror, repo or upload, not the date it got into <source>. Combined with -keep-versions, version is kept if any of the rules keeps it.
+
+Auxiliary metadata files (dep11, cnf, Contents) of <source> are carried into
+<destination> unfiltered.
 `,
 		Flag: *flag.NewFlagSet("aptly-snapshot-filter", flag.ExitOnError),
 	}
 
 	cmd.Flag.Bool("with-deps", false, "include dependent packages as well")
+	cmd.Flag.Int("keep-versions", 0, "keep only N latest versions of each package (0 keeps all)")
+	cmd.Flag.Int("keep-days", 0, "drop versions first seen by aptly (in any repo or mirror) more than N days ago (0 keeps all)")
 
 	return cmd
 }
@@ -56,6 +56,12 @@ func aptlySnapshotMerge(cmd *commander.Command, args []string) error {
 	destination := deb.NewSnapshotFromRefList(args[0], sources, result,
 		fmt.Sprintf("Merged from sources: %s", strings.Join(sourceDescription, ", ")))
 
+	var replaced []string
+	destination.ExtraFiles, replaced = deb.MergeExtraFiles(sources)
+	for _, path := range replaced {
+		context.Progress().ColoredPrintf("@y[!]@| @!auxiliary metadata file %s is taken from the latest source only@|", path)
+	}
+
 	err = context.CollectionFactory().SnapshotCollection().Add(destination)
 	if err != nil {
 		return fmt.Errorf("unable to create snapshot: %s", err)
@@ -78,6 +84,11 @@ name-architecture pair are replaced during merge (package from latest snapshot
 on the list wins).  If run with only one source snapshot, merge copies <source> into
 <destination>.
 
+Auxiliary metadata files (dep11, cnf, Contents) of source snapshots are carried
+into <destination>; if several sources have the same file, the file of the latest
+source wins. These files are not regenerated, so they still describe the packages
+of their source snapshots.
+
 Example:
 
     $ aptly snapshot merge wheezy-w-backports wheezy-main wheezy-backports
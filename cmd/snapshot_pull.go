@@ -136,6 +136,7 @@ func aptlySnapshotPull(cmd *commander.Command, args []string) error {
 		// Create <destination> snapshot
 		destination := deb.NewSnapshotFromPackageList(args[2], []*deb.Snapshot{snapshot, source}, packageList,
 			fmt.Sprintf("Pulled into '%s' with '%s' as source, pull request was: '%s'", snapshot.Name, source.Name, strings.Join(args[3:], " ")))
+		destination.ExtraFiles = snapshot.ExtraFiles
 
 		err = context.CollectionFactory().SnapshotCollection().Add(destination)
 		if err != nil {
@@ -162,6 +163,9 @@ as 'package-name' or as package queries.
 Example:
 
     $ aptly snapshot pull wheezy-main wheezy-backports wheezy-new-xorg xorg-server-server
+
+Auxiliary metadata files (dep11, cnf, Contents) of <name> are carried into
+<destination>, files of <source> are not pulled.
 `,
 		Flag: *flag.NewFlagSet("aptly-snapshot-pull", flag.ExitOnError),
 	}
@@ -3,9 +3,11 @@ package cmd
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/aptly-dev/aptly/deb"
 	"github.com/smira/commander"
+	"github.com/smira/flag"
 )
 
 func aptlySnapshotVerify(cmd *commander.Command, args []string) error {
@@ -93,24 +95,52 @@ func aptlySnapshotVerify(cmd *commander.Command, args []string) error {
 		}
 	}
 
+	if context.Flags().Lookup("installability").Value.Get().(bool) {
+		context.Progress().Printf("Checking installability...\n")
+
+		checker := deb.NewInstallabilityChecker(sourcePackageList)
+		problems, err := checker.CheckAll(packageList, architecturesList, context.Progress())
+		if err != nil {
+			return fmt.Errorf("unable to check installability: %s", err)
+		}
+
+		if len(problems) == 0 {
+			context.Progress().Printf("All packages are installable.\n")
+		} else {
+			context.Progress().Printf("Uninstallable packages (%d):\n", len(problems))
+
+			for _, problem := range problems {
+				context.Progress().Printf("  %s\n", strings.Replace(problem.String(), "\n", "\n  ", -1))
+			}
+		}
+	}
+
 	return err
 }
 
 func makeCmdSnapshotVerify() *commander.Command {
 	cmd := &commander.Command{
 		Run:       aptlySnapshotVerify,
-		UsageLine: "verify <name> [<source> ...]",
+		UsageLine: "verify [-installability] <name> [<source> ...]",
 		Short:     "verify dependencies in snapshot",
 		Long: `
 Verify does dependency resolution in snapshot <name>, possibly using additional
 snapshots <source> as dependency sources. All unsatisfied dependencies are
 printed.
 
+With -installability flag, each package is also checked to be installable: that
+its dependencies could be satisfied by packages which don't conflict with or
+break each other. Every uninstallable package is printed with the chain of
+dependencies leading to the problem.
+
 Example:
 
     $ aptly snapshot verify wheezy-main wheezy-contrib wheezy-non-free
 `,
+		Flag: *flag.NewFlagSet("aptly-snapshot-verify", flag.ExitOnError),
 	}
 
+	cmd.Flag.Bool("installability", false, "check that packages are installable, taking Conflicts and Breaks into account")
+
 	return cmd
 }
@@ -40,6 +40,7 @@ type AptlyContext struct {
 
 	progress          aptly.Progress
 	downloader        aptly.Downloader
+	downloadLimiter   *http.Limiter
 	database          database.Storage
 	packagePool       aptly.PackagePool
 	publishedStorages map[string]aptly.PublishedStorage
@@ -206,6 +207,64 @@ func (context *AptlyContext) Downloader() aptly.Downloader {
 	defer context.Unlock()
 
 	if context.downloader == nil {
+		var err error
+
+		context.downloader, err = context.newDownloader(utils.DownloadOptions{}, false)
+		if err != nil {
+			Fatal(err)
+		}
+	}
+
+	return context.downloader
+}
+
+// DownloaderWithOptions returns downloader with transport options (e.g. of the mirror)
+// applied on top of global ones
+func (context *AptlyContext) DownloaderWithOptions(options utils.DownloadOptions) (aptly.Downloader, error) {
+	if options.IsEmpty() {
+		return context.Downloader(), nil
+	}
+
+	context.Lock()
+	defer context.Unlock()
+
+	return context.newDownloader(options, false)
+}
+
+// LocalDownloaderWithOptions returns downloader with transport options applied on top
+// of global ones, which can also read local files (file:// URLs) of local archive mirrors
+func (context *AptlyContext) LocalDownloaderWithOptions(options utils.DownloadOptions) (aptly.Downloader, error) {
+	context.Lock()
+	defer context.Unlock()
+
+	return context.newDownloader(options, true)
+}
+
+// PeerDownloader returns downloader for pools of peer aptly instances: transport options from
+// configuration file (credentials, headers) are meant for upstream archives, so they are not
+// applied; failed downloads are not retried, as file is downloaded from upstream anyway
+func (context *AptlyContext) PeerDownloader() (aptly.Downloader, error) {
+	context.Lock()
+	defer context.Unlock()
+
+	limiter, err := context._downloadLimiter()
+	if err != nil {
+		return nil, err
+	}
+
+	return http.NewDownloaderWithLimiter(limiter, 1, utils.DownloadOptions{}, context._progress())
+}
+
+// DownloadLimiter returns speed & quota limiter shared by all downloaders
+func (context *AptlyContext) DownloadLimiter() (*http.Limiter, error) {
+	context.Lock()
+	defer context.Unlock()
+
+	return context._downloadLimiter()
+}
+
+func (context *AptlyContext) _downloadLimiter() (*http.Limiter, error) {
+	if context.downloadLimiter == nil {
 		var downloadLimit int64
 		limitFlag := context.flags.Lookup("download-limit")
 		if limitFlag != nil {
@@ -214,18 +273,47 @@ func (context *AptlyContext) Downloader() aptly.Downloader {
 		if downloadLimit == 0 {
 			downloadLimit = context.config().DownloadLimit
 		}
-		maxTries := context.config().DownloadRetries + 1
-		maxTriesFlag := context.flags.Lookup("max-tries")
-		if maxTriesFlag != nil {
-			maxTriesFlagValue := maxTriesFlag.Value.Get().(int)
-			if maxTriesFlagValue > maxTries {
-				maxTries = maxTriesFlagValue
-			}
+
+		var downloadQuota int64
+		quotaFlag := context.flags.Lookup("download-quota")
+		if quotaFlag != nil {
+			downloadQuota = quotaFlag.Value.Get().(int64)
+		}
+		if downloadQuota == 0 {
+			downloadQuota = context.config().DownloadQuota
+		}
+
+		limiter, err := http.NewLimiter(downloadLimit*1024, context.config().DownloadSchedule, downloadQuota*1024*1024)
+		if err != nil {
+			return nil, fmt.Errorf("invalid downloadSchedule: %s", err)
 		}
-		context.downloader = http.NewDownloader(downloadLimit*1024, maxTries, context._progress())
+
+		context.downloadLimiter = limiter
 	}
 
-	return context.downloader
+	return context.downloadLimiter, nil
+}
+
+func (context *AptlyContext) newDownloader(options utils.DownloadOptions, local bool) (aptly.Downloader, error) {
+	limiter, err := context._downloadLimiter()
+	if err != nil {
+		return nil, err
+	}
+
+	maxTries := context.config().DownloadRetries + 1
+	maxTriesFlag := context.flags.Lookup("max-tries")
+	if maxTriesFlag != nil {
+		maxTriesFlagValue := maxTriesFlag.Value.Get().(int)
+		if maxTriesFlagValue > maxTries {
+			maxTries = maxTriesFlagValue
+		}
+	}
+
+	if local {
+		return http.NewLocalDownloaderWithLimiter(limiter, maxTries, context.config().DownloadOptions.Merge(options), context._progress())
+	}
+
+	return http.NewDownloaderWithLimiter(limiter, maxTries, context.config().DownloadOptions.Merge(options), context._progress())
 }
 
 // DBPath builds path to database
@@ -330,7 +418,9 @@ func (context *AptlyContext) PackagePool() aptly.PackagePool {
 	defer context.Unlock()
 
 	if context.packagePool == nil {
-		context.packagePool = files.NewPackagePool(context.config().RootDir, !context.config().SkipLegacyPool)
+		pool := files.NewPackagePool(context.config().RootDir, !context.config().SkipLegacyPool)
+		pool.SetGracePeriod(time.Duration(context.config().PoolGracePeriod) * time.Hour)
+		context.packagePool = pool
 	}
 
 	return context.packagePool
@@ -477,23 +567,28 @@ func (context *AptlyContext) GlobalFlags() *flag.FlagSet {
 	return context.globalFlags
 }
 
-// GoContextHandleSignals upgrades context to handle ^C by aborting context
-func (context *AptlyContext) GoContextHandleSignals() {
+// GoContextHandleSignals upgrades context to handle ^C by aborting context,
+// extraSignals (if any) abort context as well
+func (context *AptlyContext) GoContextHandleSignals(extraSignals ...os.Signal) {
 	context.Lock()
 	defer context.Unlock()
 
 	// Catch ^C
 	sigch := make(chan os.Signal)
-	signal.Notify(sigch, os.Interrupt)
+	signal.Notify(sigch, append([]os.Signal{os.Interrupt}, extraSignals...)...)
 
 	var cancel gocontext.CancelFunc
 
 	context.Context, cancel = gocontext.WithCancel(context.Context)
 
 	go func() {
-		<-sigch
+		sig := <-sigch
 		signal.Stop(sigch)
-		context.Progress().PrintfStdErr("Aborting... press ^C once again to abort immediately\n")
+		if sig == os.Interrupt {
+			context.Progress().PrintfStdErr("Aborting... press ^C once again to abort immediately\n")
+		} else {
+			context.Progress().PrintfStdErr("Aborting on %s...\n", sig)
+		}
 		cancel()
 	}()
 }
@@ -0,0 +1,105 @@
+package deb
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SnapshotDateFormat is format of timestamp in snapshot.debian.org-style archive URLs
+const SnapshotDateFormat = "20060102T150405Z"
+
+// snapshotArchiveRegexp matches archive root in snapshot.debian.org layout:
+// <base>/archive/<archive name>/[<timestamp>/]
+var snapshotArchiveRegexp = regexp.MustCompile(`^(.*/archive/[^/]+/)(\d{8}T\d{6}Z/)?$`)
+
+// ParseSnapshotDate parses date of archive snapshot, accepted formats are
+// 20060102T150405Z, RFC 3339 (2006-01-02T15:04:05Z07:00) and plain date (2006-01-02)
+func ParseSnapshotDate(value string) (time.Time, error) {
+	for _, layout := range []string{SnapshotDateFormat, time.RFC3339, "2006-01-02"} {
+		date, err := time.Parse(layout, value)
+		if err == nil {
+			return date.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unable to parse date %#v, expected format is YYYYMMDDTHHMMSSZ or YYYY-MM-DD", value)
+}
+
+// SnapshotArchiveRoot returns archive root pinned to the date, archive root should follow
+// snapshot.debian.org layout (with or without timestamp)
+func SnapshotArchiveRoot(archiveRoot string, date time.Time) (string, error) {
+	if !strings.HasSuffix(archiveRoot, "/") {
+		archiveRoot += "/"
+	}
+
+	matches := snapshotArchiveRegexp.FindStringSubmatch(archiveRoot)
+	if matches == nil {
+		return "", fmt.Errorf("archive url %s doesn't follow snapshot archive layout (<url>/archive/<name>/[<timestamp>/])", archiveRoot)
+	}
+
+	return matches[1] + date.UTC().Format(SnapshotDateFormat) + "/", nil
+}
+
+// PinToDate rewrites archive root (and fallback archive roots) to point to archive snapshot
+// at specified date
+func (repo *RemoteRepo) PinToDate(date time.Time) error {
+	archiveRoot, err := SnapshotArchiveRoot(repo.ArchiveRoot, date)
+	if err != nil {
+		return err
+	}
+
+	fallbackRoots := make([]string, len(repo.FallbackArchiveRoots))
+	for i, root := range repo.FallbackArchiveRoots {
+		fallbackRoots[i], err = SnapshotArchiveRoot(root, date)
+		if err != nil {
+			return fmt.Errorf("fallback %s", err)
+		}
+	}
+
+	err = repo.SetArchiveRoot(archiveRoot)
+	if err != nil {
+		return err
+	}
+
+	repo.SnapshotDate = date.UTC()
+	if len(fallbackRoots) > 0 {
+		repo.FallbackArchiveRoots = fallbackRoots
+	}
+
+	return nil
+}
+
+// Unpin rewrites archive root (and fallback archive roots) pinned to archive snapshot date
+// back to the latest state of the archive
+func (repo *RemoteRepo) Unpin() {
+	if !repo.IsPinned() {
+		return
+	}
+
+	unpinned := func(root string) string {
+		if !strings.HasSuffix(root, "/") {
+			root += "/"
+		}
+
+		matches := snapshotArchiveRegexp.FindStringSubmatch(root)
+		if matches == nil {
+			return root
+		}
+
+		return matches[1]
+	}
+
+	for i := range repo.FallbackArchiveRoots {
+		repo.FallbackArchiveRoots[i] = unpinned(repo.FallbackArchiveRoots[i])
+	}
+
+	repo.SnapshotDate = time.Time{}
+	repo.SetArchiveRoot(unpinned(repo.ArchiveRoot))
+}
+
+// IsPinned checks whether mirror is pinned to archive snapshot date
+func (repo *RemoteRepo) IsPinned() bool {
+	return !repo.SnapshotDate.IsZero()
+}
@@ -0,0 +1,92 @@
+package deb
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type ArchiveDateSuite struct{}
+
+var _ = Suite(&ArchiveDateSuite{})
+
+func (s *ArchiveDateSuite) TestParseSnapshotDate(c *C) {
+	expected := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	date, err := ParseSnapshotDate("20200102T030405Z")
+	c.Assert(err, IsNil)
+	c.Check(date.Equal(expected), Equals, true)
+
+	date, err = ParseSnapshotDate("2020-01-02T06:04:05+03:00")
+	c.Assert(err, IsNil)
+	c.Check(date.Equal(expected), Equals, true)
+	c.Check(date.Location(), Equals, time.UTC)
+
+	date, err = ParseSnapshotDate("2020-01-02")
+	c.Assert(err, IsNil)
+	c.Check(date.Equal(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)), Equals, true)
+
+	_, err = ParseSnapshotDate("yesterday")
+	c.Check(err, ErrorMatches, "unable to parse date.*")
+}
+
+func (s *ArchiveDateSuite) TestSnapshotArchiveRoot(c *C) {
+	date := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	root, err := SnapshotArchiveRoot("http://snapshot.debian.org/archive/debian/", date)
+	c.Assert(err, IsNil)
+	c.Check(root, Equals, "http://snapshot.debian.org/archive/debian/20200102T030405Z/")
+
+	root, err = SnapshotArchiveRoot("http://snapshot.debian.org/archive/debian-security/20190101T000000Z", date)
+	c.Assert(err, IsNil)
+	c.Check(root, Equals, "http://snapshot.debian.org/archive/debian-security/20200102T030405Z/")
+
+	_, err = SnapshotArchiveRoot("http://deb.debian.org/debian/", date)
+	c.Check(err, ErrorMatches, "archive url .* doesn't follow snapshot archive layout.*")
+}
+
+func (s *ArchiveDateSuite) TestPinToDate(c *C) {
+	repo, _ := NewRemoteRepo("buster", "http://snapshot.debian.org/archive/debian/", "buster", []string{"main"}, []string{}, false, false, false)
+	c.Check(repo.IsPinned(), Equals, false)
+
+	c.Assert(repo.PinToDate(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)), IsNil)
+	c.Check(repo.IsPinned(), Equals, true)
+	c.Check(repo.ArchiveRoot, Equals, "http://snapshot.debian.org/archive/debian/20200102T030405Z/")
+	c.Check(repo.IndexesRootURL().String(), Equals, "http://snapshot.debian.org/archive/debian/20200102T030405Z/dists/buster/")
+
+	// pinned date survives encoding
+	repo2 := &RemoteRepo{}
+	c.Assert(repo2.Decode(repo.Encode()), IsNil)
+	c.Check(repo2.SnapshotDate.Equal(repo.SnapshotDate), Equals, true)
+
+	repo, _ = NewRemoteRepo("local", "http://deb.debian.org/debian/", "buster", []string{"main"}, []string{}, false, false, false)
+	c.Check(repo.PinToDate(time.Now()), NotNil)
+	c.Check(repo.IsPinned(), Equals, false)
+	c.Check(repo.ArchiveRoot, Equals, "http://deb.debian.org/debian/")
+
+	// fallback archive roots are pinned as well
+	repo, _ = NewRemoteRepo("buster", "http://snapshot.debian.org/archive/debian/", "buster", []string{"main"}, []string{}, false, false, false)
+	repo.FallbackArchiveRoots = []string{"http://snapshot-mirror.example.com/archive/debian/"}
+	c.Assert(repo.PinToDate(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)), IsNil)
+	c.Check(repo.FallbackArchiveRoots, DeepEquals, []string{"http://snapshot-mirror.example.com/archive/debian/20200102T030405Z/"})
+
+	repo.FallbackArchiveRoots = []string{"http://deb.debian.org/debian/"}
+	c.Check(repo.PinToDate(time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)), ErrorMatches, "fallback archive url http://deb.debian.org/debian/ doesn't follow.*")
+	c.Check(repo.ArchiveRoot, Equals, "http://snapshot.debian.org/archive/debian/20200102T030405Z/")
+}
+
+func (s *ArchiveDateSuite) TestUnpin(c *C) {
+	repo, _ := NewRemoteRepo("buster", "http://snapshot.debian.org/archive/debian/", "buster", []string{"main"}, []string{}, false, false, false)
+	repo.FallbackArchiveRoots = []string{"http://snapshot-mirror.example.com/archive/debian/"}
+	c.Assert(repo.PinToDate(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)), IsNil)
+
+	repo.Unpin()
+	c.Check(repo.IsPinned(), Equals, false)
+	c.Check(repo.ArchiveRoot, Equals, "http://snapshot.debian.org/archive/debian/")
+	c.Check(repo.IndexesRootURL().String(), Equals, "http://snapshot.debian.org/archive/debian/dists/buster/")
+	c.Check(repo.FallbackArchiveRoots, DeepEquals, []string{"http://snapshot-mirror.example.com/archive/debian/"})
+
+	// not pinned mirror is left as is
+	repo.Unpin()
+	c.Check(repo.ArchiveRoot, Equals, "http://snapshot.debian.org/archive/debian/")
+}
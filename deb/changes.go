@@ -349,11 +349,9 @@ func ImportChangesFiles(changesFiles []string, reporter aptly.ResultReporter, ac
 		currentUploaders := uploaders
 		if repo.Uploaders != nil {
 			currentUploaders = repo.Uploaders
-			for i := range currentUploaders.Rules {
-				currentUploaders.Rules[i].CompiledCondition, err = parseQuery(currentUploaders.Rules[i].Condition)
-				if err != nil {
-					return nil, nil, fmt.Errorf("error parsing query %s: %s", currentUploaders.Rules[i].Condition, err)
-				}
+			err = currentUploaders.Compile(parseQuery)
+			if err != nil {
+				return nil, nil, err
 			}
 		}
 
@@ -384,7 +382,7 @@ func ImportChangesFiles(changesFiles []string, reporter aptly.ResultReporter, ac
 		var processedFiles2, failedFiles2 []string
 
 		processedFiles2, failedFiles2, err = ImportPackageFiles(list, packageFiles, forceReplace, verifier, pool,
-			packageCollection, reporter, restriction, checksumStorageProvider)
+			packageCollection, reporter, restriction, checksumStorageProvider, nil, "", NewChangesProvenance(repo, changes))
 
 		if err != nil {
 			return nil, nil, fmt.Errorf("unable to import package files: %s", err)
@@ -3,10 +3,14 @@ package deb
 import (
 	"archive/tar"
 	"bufio"
+	"bytes"
 	"compress/bzip2"
 	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"strings"
 
@@ -139,6 +143,129 @@ func GetControlFileFromDsc(dscFile string, verifier pgp.Verifier) (Stanza, error
 
 }
 
+// GetSignatureKeysFromDsc verifies signature of .dsc file and returns keys
+// which made good signatures (nil if .dsc file is not signed)
+func GetSignatureKeysFromDsc(dscFile string, verifier pgp.Verifier) ([]pgp.Key, error) {
+	file, err := os.Open(dscFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	isClearSigned, err := verifier.IsClearSigned(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isClearSigned {
+		return nil, nil
+	}
+
+	file.Seek(0, 0)
+
+	keyInfo, err := verifier.VerifyClearsigned(file, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return keyInfo.GoodKeys, nil
+}
+
+// debSignaturePrefix is the prefix of .deb archive members holding dpkg-sig signatures
+const debSignaturePrefix = "_gpg"
+
+// GetSignatureKeysFromDeb verifies dpkg-sig signatures embedded into .deb package and
+// returns list of keys which signed the package; unsigned package returns empty list
+//
+// Each signature is clearsigned list of checksums of the other archive members, so
+// signature is accepted only if it covers all the members and checksums match.
+func GetSignatureKeysFromDeb(packageFile string, verifier pgp.Verifier) ([]pgp.Key, error) {
+	file, err := os.Open(packageFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var signatures [][]byte
+	members := make(map[string]string)
+
+	library := ar.NewReader(file)
+	for {
+		header, err := library.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read .deb archive %s: %s", packageFile, err)
+		}
+
+		name := strings.TrimSuffix(header.Name, "/")
+
+		if strings.HasPrefix(name, debSignaturePrefix) {
+			signature, err := ioutil.ReadAll(library)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read .deb archive %s: %s", packageFile, err)
+			}
+			signatures = append(signatures, signature)
+			continue
+		}
+
+		md5hash, sha1hash := md5.New(), sha1.New()
+		size, err := io.Copy(io.MultiWriter(md5hash, sha1hash), library)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read .deb archive %s: %s", packageFile, err)
+		}
+
+		members[name] = fmt.Sprintf("%x %x %d", md5hash.Sum(nil), sha1hash.Sum(nil), size)
+	}
+
+	var keys []pgp.Key
+
+	for _, signature := range signatures {
+		keyInfo, err := verifier.VerifyClearsigned(bytes.NewReader(signature), false)
+		if err != nil {
+			return nil, err
+		}
+
+		text, err := verifier.ExtractClearsigned(bytes.NewReader(signature))
+		if err != nil {
+			return nil, err
+		}
+
+		stanza, err := NewControlFileReader(text, false, false).ReadStanza()
+		text.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		covered := 0
+		for _, line := range strings.Split(stanza["Files"], "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			if len(fields) != 4 {
+				return nil, fmt.Errorf("malformed signature in %s: %#v", packageFile, line)
+			}
+
+			if members[fields[3]] != strings.Join(fields[:3], " ") {
+				return nil, fmt.Errorf("signature in %s doesn't match contents of %s", packageFile, fields[3])
+			}
+			covered++
+		}
+
+		if covered != len(members) {
+			return nil, fmt.Errorf("signature in %s doesn't cover all the package contents", packageFile)
+		}
+
+		if keyInfo != nil {
+			keys = append(keys, keyInfo.GoodKeys...)
+		}
+	}
+
+	return keys, nil
+}
+
 // GetContentsFromDeb returns list of files installed by .deb package
 func GetContentsFromDeb(file io.Reader, packageFile string) ([]string, error) {
 	library := ar.NewReader(file)
@@ -1,11 +1,19 @@
 package deb
 
 import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/aptly-dev/aptly/pgp"
+	ar "github.com/mkrautz/goar"
 
 	. "gopkg.in/check.v1"
 )
@@ -68,6 +76,96 @@ func (s *DebSuite) TestGetControlFileFromDsc(c *C) {
 	c.Check(st["Source"], Equals, "pyspi")
 }
 
+// keyVerifier accepts any signature as made by the keys
+type keyVerifier struct {
+	NullVerifier
+	keys []pgp.Key
+}
+
+func (v *keyVerifier) VerifyClearsigned(clearsigned io.Reader, hint bool) (*pgp.KeyInfo, error) {
+	return &pgp.KeyInfo{GoodKeys: v.keys}, nil
+}
+
+// signDeb creates copy of .deb package with dpkg-sig style signature listing checksums of members,
+// signature text is not really signed as it is verified with keyVerifier
+func (s *DebSuite) signDeb(c *C, dir string, skip string, tamper bool) string {
+	source, err := os.Open(s.debFile)
+	c.Assert(err, IsNil)
+	defer source.Close()
+
+	type member struct {
+		header  *ar.Header
+		content []byte
+	}
+	members := []member{}
+
+	reader := ar.NewReader(source)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		c.Assert(err, IsNil)
+
+		content, err := ioutil.ReadAll(reader)
+		c.Assert(err, IsNil)
+		members = append(members, member{header, content})
+	}
+
+	signature := "Version: 4\nRole: builder\nFiles:\n"
+	for _, m := range members {
+		name := strings.TrimSuffix(m.header.Name, "/")
+		if name == skip {
+			continue
+		}
+		size := len(m.content)
+		if tamper {
+			size++
+		}
+		signature += fmt.Sprintf(" %x %x %d %s\n", md5.Sum(m.content), sha1.Sum(m.content), size, name)
+	}
+	members = append(members, member{&ar.Header{Name: "_gpgbuilder"}, []byte(signature)})
+
+	// ar archive: global header, then 60-byte header and content padded to even size for each member
+	archive := bytes.NewBufferString("!<arch>\n")
+	for _, m := range members {
+		fmt.Fprintf(archive, "%-16s%-12d%-6d%-6d%-8o%-10d`\n", m.header.Name, 0, 0, 0, 0644, len(m.content))
+		archive.Write(m.content)
+		if len(m.content)%2 != 0 {
+			archive.WriteString("\n")
+		}
+	}
+
+	path := filepath.Join(dir, "signed.deb")
+	c.Assert(ioutil.WriteFile(path, archive.Bytes(), 0644), IsNil)
+
+	return path
+}
+
+func (s *DebSuite) TestGetSignatureKeysFromDeb(c *C) {
+	verifier := &keyVerifier{keys: []pgp.Key{"21DBB89C16DB3E6D"}}
+
+	_, err := GetSignatureKeysFromDeb("/no/such/file", verifier)
+	c.Check(err, ErrorMatches, ".*no such file or directory")
+
+	// unsigned package
+	keys, err := GetSignatureKeysFromDeb(s.debFile, verifier)
+	c.Check(err, IsNil)
+	c.Check(keys, HasLen, 0)
+
+	dir := c.MkDir()
+
+	keys, err = GetSignatureKeysFromDeb(s.signDeb(c, dir, "", false), verifier)
+	c.Check(err, IsNil)
+	c.Check(keys, DeepEquals, []pgp.Key{"21DBB89C16DB3E6D"})
+
+	_, err = GetSignatureKeysFromDeb(s.signDeb(c, dir, "", true), verifier)
+	c.Check(err, ErrorMatches, "signature in .* doesn't match contents of debian-binary")
+
+	_, err = GetSignatureKeysFromDeb(s.signDeb(c, dir, "debian-binary", false), verifier)
+	c.Check(err, ErrorMatches, "signature in .* doesn't cover all the package contents")
+}
+
 func (s *DebSuite) TestGetContentsFromDeb(c *C) {
 	f, err := os.Open(s.debFile)
 	c.Assert(err, IsNil)
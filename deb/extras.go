@@ -0,0 +1,282 @@
+package deb
+
+import (
+	gocontext "context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aptly-dev/aptly/aptly"
+	"github.com/aptly-dev/aptly/http"
+	"github.com/aptly-dev/aptly/utils"
+)
+
+// ExtraFile is auxiliary metadata file listed in Release file (AppStream dep11 metadata & icons,
+// command-not-found indexes, Contents), which is mirrored & published along with package indexes
+type ExtraFile struct {
+	// Component file belongs to
+	Component string
+	// Path relative to component directory, e.g. dep11/Components-amd64.yml.gz
+	Path string
+	// Checksums of the file
+	Checksums utils.ChecksumInfo
+	// Location of the file in package pool
+	PoolPath string
+}
+
+// extraFilePatterns match paths of auxiliary metadata files (relative to component directory,
+// without compression extension), first group (if any) is architecture
+var extraFilePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^dep11/Components-([^./]+)\.yml$`),
+	regexp.MustCompile(`^dep11/icons-[^/]+\.tar$`),
+	regexp.MustCompile(`^cnf/Commands-([^./]+)$`),
+	regexp.MustCompile(`^Contents-(?:udeb-)?([^./]+)$`),
+}
+
+// extraFileCompressions are compression variants of auxiliary files in order of preference
+var extraFileCompressions = []string{".gz", ".xz", ".bz2", ""}
+
+// extraFileArchitecture checks whether path (relative to component directory) is auxiliary metadata
+// file and returns its architecture ("" for architecture-independent files)
+func extraFileArchitecture(path string) (string, bool) {
+	for _, ext := range extraFileCompressions {
+		if ext != "" && !strings.HasSuffix(path, ext) {
+			continue
+		}
+
+		base := strings.TrimSuffix(path, ext)
+		for _, pattern := range extraFilePatterns {
+			matches := pattern.FindStringSubmatch(base)
+			if matches == nil {
+				continue
+			}
+
+			if len(matches) > 1 {
+				return matches[1], true
+			}
+			return "", true
+		}
+	}
+
+	return "", false
+}
+
+// IsContents checks whether extra file is Contents index
+func (file *ExtraFile) IsContents() bool {
+	return strings.HasPrefix(file.Path, "Contents-")
+}
+
+// ExtraFileCandidates returns auxiliary metadata files listed in Release file for mirror
+// components and architectures: map of file path (without compression extension) to the list
+// of compression variants in order of preference
+func (repo *RemoteRepo) ExtraFileCandidates() map[string][]string {
+	result := map[string][]string{}
+
+	if repo.IsFlat() {
+		return result
+	}
+
+	for path := range repo.ReleaseFiles {
+		for _, component := range repo.Components {
+			if !strings.HasPrefix(path, component+"/") {
+				continue
+			}
+
+			arch, ok := extraFileArchitecture(strings.TrimPrefix(path, component+"/"))
+			if !ok {
+				continue
+			}
+
+			if arch != "" && arch != ArchitectureAll && len(repo.Architectures) > 0 && !utils.StrSliceHasItem(repo.Architectures, arch) {
+				continue
+			}
+
+			base := path
+			for _, ext := range extraFileCompressions {
+				if ext != "" && strings.HasSuffix(path, ext) {
+					base = strings.TrimSuffix(path, ext)
+					break
+				}
+			}
+
+			result[base] = append(result[base], path)
+		}
+	}
+
+	for base, variants := range result {
+		rank := func(path string) int {
+			for i, ext := range extraFileCompressions {
+				if path == base+ext {
+					return i
+				}
+			}
+			return len(extraFileCompressions)
+		}
+
+		sort.Slice(variants, func(i, j int) bool {
+			return rank(variants[i]) < rank(variants[j])
+		})
+	}
+
+	return result
+}
+
+// DownloadExtraFiles downloads auxiliary metadata files listed in Release file into package pool,
+// list of files replaces ExtraFiles on FinalizeDownload
+func (repo *RemoteRepo) DownloadExtraFiles(progress aptly.Progress, d aptly.Downloader, packagePool aptly.PackagePool,
+	checksumStorage aptly.ChecksumStorage, ignoreMismatch bool) error {
+	candidates := repo.ExtraFileCandidates()
+
+	bases := make([]string, 0, len(candidates))
+	for base := range candidates {
+		bases = append(bases, base)
+	}
+	sort.Strings(bases)
+
+	tempDir, err := ioutil.TempDir(os.TempDir(), "aptly")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	repo.extraFiles = []ExtraFile{}
+
+	for _, base := range bases {
+		for _, path := range candidates[base] {
+			file, err := repo.downloadExtraFile(d, packagePool, checksumStorage, ignoreMismatch, tempDir, path)
+			if err != nil {
+				if herr, ok := err.(*http.Error); ok && (herr.Code == 404 || herr.Code == 403) {
+					// Release file lists checksums of uncompressed files even if they are missing
+					continue
+				}
+				return fmt.Errorf("unable to download %s: %s", path, err)
+			}
+
+			repo.extraFiles = append(repo.extraFiles, *file)
+			break
+		}
+	}
+
+	if progress != nil {
+		progress.Printf("Mirrored %d auxiliary metadata files\n", len(repo.extraFiles))
+	}
+
+	return nil
+}
+
+func (repo *RemoteRepo) downloadExtraFile(d aptly.Downloader, packagePool aptly.PackagePool, checksumStorage aptly.ChecksumStorage,
+	ignoreMismatch bool, tempDir, path string) (*ExtraFile, error) {
+	component := ""
+	for _, c := range repo.Components {
+		if strings.HasPrefix(path, c+"/") && len(c) > len(component) {
+			component = c
+		}
+	}
+
+	file := &ExtraFile{
+		Component: component,
+		Path:      strings.TrimPrefix(path, component+"/"),
+		Checksums: repo.ReleaseFiles[path],
+	}
+
+	basename := filepath.Base(path)
+
+	poolPath, exists, err := packagePool.Verify("", basename, &file.Checksums, checksumStorage)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		tempPath := filepath.Join(tempDir, strings.Replace(path, "/", "_", -1))
+
+		err = d.DownloadWithChecksum(gocontext.TODO(), repo.IndexesRootURL().ResolveReference(&url.URL{Path: path}).String(),
+			tempPath, &file.Checksums, ignoreMismatch)
+		if err != nil {
+			return nil, err
+		}
+
+		poolPath, err = packagePool.Import(tempPath, basename, &file.Checksums, true, checksumStorage)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	file.PoolPath = poolPath
+
+	return file, nil
+}
+
+// MergeExtraFiles combines auxiliary metadata files of snapshots which are merged into new snapshot,
+// file of later snapshot replaces file with the same component & path; paths of replaced files are
+// returned, as metadata of replaced file doesn't describe packages of later snapshot
+func MergeExtraFiles(sources []*Snapshot) (result []ExtraFile, replaced []string) {
+	index := map[string]int{}
+
+	for _, source := range sources {
+		for _, file := range source.ExtraFiles {
+			key := file.Component + "/" + file.Path
+			if i, exists := index[key]; exists {
+				if result[i].PoolPath != file.PoolPath {
+					replaced = append(replaced, key)
+				}
+				result[i] = file
+				continue
+			}
+
+			index[key] = len(result)
+			result = append(result, file)
+		}
+	}
+
+	return
+}
+
+// extraFiles returns auxiliary metadata files to publish in component: files of snapshot created
+// from single-component mirror, or files of the matching mirror component
+func (p *PublishedRepo) extraFiles(component string) []ExtraFile {
+	snapshot := p.sourceItems[component].snapshot
+	if snapshot == nil || len(snapshot.ExtraFiles) == 0 {
+		return nil
+	}
+
+	components := map[string]bool{}
+	for _, file := range snapshot.ExtraFiles {
+		components[file.Component] = true
+	}
+
+	if len(components) == 1 {
+		return snapshot.ExtraFiles
+	}
+
+	result := []ExtraFile{}
+	for _, file := range snapshot.ExtraFiles {
+		if file.Component == component {
+			result = append(result, file)
+		}
+	}
+
+	return result
+}
+
+// copyExtraFile copies auxiliary file from package pool into index file
+func copyExtraFile(packagePool aptly.PackagePool, file *ExtraFile, index *indexFile) error {
+	src, err := packagePool.Open(file.PoolPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	w, err := index.BufWriter()
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, src)
+	return err
+}
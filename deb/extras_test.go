@@ -0,0 +1,135 @@
+package deb
+
+import (
+	"io/ioutil"
+	"sort"
+
+	"github.com/aptly-dev/aptly/files"
+	"github.com/aptly-dev/aptly/http"
+	"github.com/aptly-dev/aptly/utils"
+
+	. "gopkg.in/check.v1"
+)
+
+type ExtrasSuite struct{}
+
+var _ = Suite(&ExtrasSuite{})
+
+func (s *ExtrasSuite) TestExtraFileArchitecture(c *C) {
+	for path, expected := range map[string]string{
+		"dep11/Components-amd64.yml.gz": "amd64",
+		"dep11/Components-arm64.yml":    "arm64",
+		"dep11/icons-64x64@2.tar.gz":    "",
+		"cnf/Commands-i386.xz":          "i386",
+		"Contents-amd64.gz":             "amd64",
+		"Contents-udeb-armhf.gz":        "armhf",
+	} {
+		arch, ok := extraFileArchitecture(path)
+		c.Check(ok, Equals, true, Commentf("path: %s", path))
+		c.Check(arch, Equals, expected, Commentf("path: %s", path))
+	}
+
+	for _, path := range []string{"binary-amd64/Packages.gz", "i18n/Translation-en", "dep11/README", "source/Sources.xz"} {
+		_, ok := extraFileArchitecture(path)
+		c.Check(ok, Equals, false, Commentf("path: %s", path))
+	}
+}
+
+func (s *ExtrasSuite) TestDownloadExtraFiles(c *C) {
+	checksum := func(data string) utils.ChecksumInfo {
+		w := utils.NewChecksumWriter()
+		w.Write([]byte(data))
+		return w.Sum()
+	}
+
+	repo, _ := NewRemoteRepo("yandex", "http://mirror.yandex.ru/debian/", "squeeze", []string{"main"}, []string{"amd64"}, false, false, false)
+	repo.ReleaseFiles = map[string]utils.ChecksumInfo{
+		"main/binary-amd64/Packages":          checksum("packages"),
+		"main/dep11/Components-amd64.yml":     checksum("components"),
+		"main/dep11/Components-amd64.yml.xz":  checksum("components.xz"),
+		"main/dep11/Components-amd64.yml.gz":  checksum("components.gz"),
+		"main/dep11/Components-i386.yml.gz":   checksum("components-i386.gz"),
+		"main/dep11/icons-64x64.tar":          checksum("icons"),
+		"main/cnf/Commands-amd64.xz":          checksum("commands.xz"),
+		"contrib/dep11/Components-amd64.yml":  checksum("contrib"),
+		"main/Contents-amd64.gz":              checksum("contents.gz"),
+		"main/debian-installer/Contents-i386": checksum("unrelated"),
+	}
+
+	candidates := repo.ExtraFileCandidates()
+	keys := make([]string, 0, len(candidates))
+	for key := range candidates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	c.Check(keys, DeepEquals, []string{"main/Contents-amd64", "main/cnf/Commands-amd64", "main/dep11/Components-amd64.yml", "main/dep11/icons-64x64.tar"})
+	c.Check(candidates["main/dep11/Components-amd64.yml"], DeepEquals,
+		[]string{"main/dep11/Components-amd64.yml.gz", "main/dep11/Components-amd64.yml.xz", "main/dep11/Components-amd64.yml"})
+
+	packagePool := files.NewPackagePool(c.MkDir(), false)
+	cs := files.NewMockChecksumStorage()
+
+	d := http.NewFakeDownloader()
+	d.ExpectResponse("http://mirror.yandex.ru/debian/dists/squeeze/main/Contents-amd64.gz", "contents.gz")
+	d.ExpectResponse("http://mirror.yandex.ru/debian/dists/squeeze/main/cnf/Commands-amd64.xz", "commands.xz")
+	d.ExpectError("http://mirror.yandex.ru/debian/dists/squeeze/main/dep11/Components-amd64.yml.gz", &http.Error{Code: 404})
+	d.ExpectResponse("http://mirror.yandex.ru/debian/dists/squeeze/main/dep11/Components-amd64.yml.xz", "components.xz")
+	d.ExpectResponse("http://mirror.yandex.ru/debian/dists/squeeze/main/dep11/icons-64x64.tar", "icons")
+
+	err := repo.DownloadExtraFiles(nil, d, packagePool, cs, false)
+	c.Assert(err, IsNil)
+	c.Check(d.Empty(), Equals, true)
+	c.Assert(repo.extraFiles, HasLen, 4)
+
+	file := repo.extraFiles[2]
+	c.Check(file.Component, Equals, "main")
+	c.Check(file.Path, Equals, "dep11/Components-amd64.yml.xz")
+
+	r, err := packagePool.Open(file.PoolPath)
+	c.Assert(err, IsNil)
+	contents, _ := ioutil.ReadAll(r)
+	r.Close()
+	c.Check(string(contents), Equals, "components.xz")
+
+	// files already in the pool are not downloaded again
+	d = http.NewFakeDownloader()
+	d.ExpectError("http://mirror.yandex.ru/debian/dists/squeeze/main/dep11/Components-amd64.yml.gz", &http.Error{Code: 404})
+
+	err = repo.DownloadExtraFiles(nil, d, packagePool, cs, false)
+	c.Assert(err, IsNil)
+	c.Check(d.Empty(), Equals, true)
+	c.Check(repo.extraFiles, HasLen, 4)
+
+	// other errors are fatal
+	d = http.NewFakeDownloader()
+	d.ExpectError("http://mirror.yandex.ru/debian/dists/squeeze/main/dep11/Components-amd64.yml.gz", &http.Error{Code: 500})
+
+	err = repo.DownloadExtraFiles(nil, d, packagePool, cs, false)
+	c.Check(err, ErrorMatches, "unable to download main/dep11/Components-amd64.yml.gz: .*")
+}
+
+func (s *ExtrasSuite) TestMergeExtraFiles(c *C) {
+	s1 := &Snapshot{ExtraFiles: []ExtraFile{
+		{Component: "main", Path: "Contents-amd64.gz", PoolPath: "aa/Contents-amd64.gz"},
+		{Component: "main", Path: "cnf/Commands-amd64.xz", PoolPath: "bb/Commands-amd64.xz"},
+	}}
+	s2 := &Snapshot{ExtraFiles: []ExtraFile{
+		{Component: "contrib", Path: "Contents-amd64.gz", PoolPath: "cc/Contents-amd64.gz"},
+		{Component: "main", Path: "Contents-amd64.gz", PoolPath: "dd/Contents-amd64.gz"},
+	}}
+	s3 := &Snapshot{ExtraFiles: []ExtraFile{
+		{Component: "main", Path: "cnf/Commands-amd64.xz", PoolPath: "bb/Commands-amd64.xz"},
+	}}
+
+	files, replaced := MergeExtraFiles([]*Snapshot{s1, s2, s3})
+	c.Check(files, DeepEquals, []ExtraFile{
+		{Component: "main", Path: "Contents-amd64.gz", PoolPath: "dd/Contents-amd64.gz"},
+		{Component: "main", Path: "cnf/Commands-amd64.xz", PoolPath: "bb/Commands-amd64.xz"},
+		{Component: "contrib", Path: "Contents-amd64.gz", PoolPath: "cc/Contents-amd64.gz"},
+	})
+	c.Check(replaced, DeepEquals, []string{"main/Contents-amd64.gz"})
+
+	files, replaced = MergeExtraFiles([]*Snapshot{{}, {}})
+	c.Check(files, HasLen, 0)
+	c.Check(replaced, HasLen, 0)
+}
@@ -131,7 +131,7 @@ func isMultilineField(field string, isRelease bool) bool {
 	case "SHA512":
 		return isRelease
 	}
-	return false
+	return isTranslationField(field)
 }
 
 // Write single field from Stanza to writer.
@@ -145,7 +145,7 @@ func writeField(w *bufio.Writer, field, value string, isRelease bool) (err error
 			value = value + "\n"
 		}
 
-		if field != "Description" && field != "" {
+		if field != "Description" && !isTranslationField(field) && field != "" {
 			value = "\n" + value
 		}
 
@@ -222,6 +222,11 @@ func canonicalCase(field string) string {
 		return "ButAutomaticUpgrades"
 	}
 
+	if strings.HasPrefix(upper, "DESCRIPTION-") && upper != "DESCRIPTION-MD5" {
+		// language code is kept as is: Description-pt_BR
+		return "Description-" + field[len("Description-"):]
+	}
+
 	startOfWord := true
 
 	return strings.Map(func(r rune) rune {
@@ -2,33 +2,50 @@ package deb
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/awalterschulze/gographviz"
 )
 
-// BuildGraph generates graph contents from aptly object database
-func BuildGraph(collectionFactory *CollectionFactory, layout string) (gographviz.Interface, error) {
-	var err error
+// Kinds of graph nodes
+const (
+	GraphNodeMirror    = "mirror"
+	GraphNodeLocalRepo = "repo"
+	GraphNodeSnapshot  = "snapshot"
+	GraphNodePublished = "publish"
+)
 
-	graph := gographviz.NewEscape()
-	graph.SetDir(true)
-	graph.SetName("aptly")
+// GraphNode is mirror, local repo, snapshot or published repository in the graph
+type GraphNode struct {
+	// UUID of the object
+	UUID string
+	// Kind of the object (GraphNodeMirror, GraphNodeLocalRepo, ...)
+	Kind string
+	// Name of the object, for published repositories it's prefix/distribution
+	Name string
+	// Object properties (url, distribution, packages, ...)
+	Meta map[string]string
+}
 
-	var labelStart string
-	var labelEnd string
+// GraphEdge links object to another object created from it
+type GraphEdge struct {
+	// UUIDs of source and derived objects
+	From string
+	To   string
+}
 
-	switch layout {
-	case "vertical":
-		graph.AddAttr("aptly", "rankdir", "LR")
-		labelStart = ""
-		labelEnd = ""
-	case "horizontal":
-		fallthrough
-	default:
-		labelStart = "{"
-		labelEnd = "}"
-	}
+// Graph is relationship of mirrors, local repos, snapshots and published repositories
+type Graph struct {
+	Nodes []*GraphNode
+	Edges []*GraphEdge
+}
+
+// BuildGraphData collects graph nodes and edges from aptly object database
+func BuildGraphData(collectionFactory *CollectionFactory) (*Graph, error) {
+	var err error
+
+	graph := &Graph{Nodes: []*GraphNode{}, Edges: []*GraphEdge{}}
 
 	existingNodes := map[string]bool{}
 
@@ -38,13 +55,17 @@ func BuildGraph(collectionFactory *CollectionFactory, layout string) (gographviz
 			return e
 		}
 
-		graph.AddNode("aptly", repo.UUID, map[string]string{
-			"shape":     "Mrecord",
-			"style":     "filled",
-			"fillcolor": "darkgoldenrod1",
-			"label": fmt.Sprintf("%sMirror %s|url: %s|dist: %s|comp: %s|arch: %s|pkgs: %d%s", labelStart, repo.Name, repo.ArchiveRoot,
-				repo.Distribution, strings.Join(repo.Components, ", "),
-				strings.Join(repo.Architectures, ", "), repo.NumPackages(), labelEnd),
+		graph.Nodes = append(graph.Nodes, &GraphNode{
+			UUID: repo.UUID,
+			Kind: GraphNodeMirror,
+			Name: repo.Name,
+			Meta: map[string]string{
+				"url":           repo.ArchiveRoot,
+				"distribution":  repo.Distribution,
+				"components":    strings.Join(repo.Components, ", "),
+				"architectures": strings.Join(repo.Architectures, ", "),
+				"packages":      strconv.Itoa(repo.NumPackages()),
+			},
 		})
 		existingNodes[repo.UUID] = true
 		return nil
@@ -60,12 +81,14 @@ func BuildGraph(collectionFactory *CollectionFactory, layout string) (gographviz
 			return e
 		}
 
-		graph.AddNode("aptly", repo.UUID, map[string]string{
-			"shape":     "Mrecord",
-			"style":     "filled",
-			"fillcolor": "mediumseagreen",
-			"label": fmt.Sprintf("%sRepo %s|comment: %s|pkgs: %d%s", labelStart,
-				repo.Name, repo.Comment, repo.NumPackages(), labelEnd),
+		graph.Nodes = append(graph.Nodes, &GraphNode{
+			UUID: repo.UUID,
+			Kind: GraphNodeLocalRepo,
+			Name: repo.Name,
+			Meta: map[string]string{
+				"comment":  repo.Comment,
+				"packages": strconv.Itoa(repo.NumPackages()),
+			},
 		})
 		existingNodes[repo.UUID] = true
 		return nil
@@ -91,19 +114,22 @@ func BuildGraph(collectionFactory *CollectionFactory, layout string) (gographviz
 			description = "Snapshot from repo"
 		}
 
-		graph.AddNode("aptly", snapshot.UUID, map[string]string{
-			"shape":     "Mrecord",
-			"style":     "filled",
-			"fillcolor": "cadetblue1",
-			"label": fmt.Sprintf("%sSnapshot %s|%s|pkgs: %d%s", labelStart,
-				snapshot.Name, description, snapshot.NumPackages(), labelEnd),
+		graph.Nodes = append(graph.Nodes, &GraphNode{
+			UUID: snapshot.UUID,
+			Kind: GraphNodeSnapshot,
+			Name: snapshot.Name,
+			Meta: map[string]string{
+				"description": description,
+				"created":     snapshot.CreatedAt.Format("2006-01-02 15:04:05"),
+				"packages":    strconv.Itoa(snapshot.NumPackages()),
+			},
 		})
 
 		if snapshot.SourceKind == SourceRemoteRepo || snapshot.SourceKind == SourceLocalRepo || snapshot.SourceKind == SourceSnapshot {
 			for _, uuid := range snapshot.SourceIDs {
 				_, exists := existingNodes[uuid]
 				if exists {
-					graph.AddEdge(uuid, snapshot.UUID, true, nil)
+					graph.Edges = append(graph.Edges, &GraphEdge{From: uuid, To: snapshot.UUID})
 				}
 			}
 		}
@@ -115,19 +141,24 @@ func BuildGraph(collectionFactory *CollectionFactory, layout string) (gographviz
 	}
 
 	collectionFactory.PublishedRepoCollection().ForEach(func(repo *PublishedRepo) error {
-		graph.AddNode("aptly", repo.UUID, map[string]string{
-			"shape":     "Mrecord",
-			"style":     "filled",
-			"fillcolor": "darkolivegreen1",
-			"label": fmt.Sprintf("%sPublished %s/%s|comp: %s|arch: %s%s", labelStart,
-				repo.Prefix, repo.Distribution, strings.Join(repo.Components(), " "),
-				strings.Join(repo.Architectures, ", "), labelEnd),
+		graph.Nodes = append(graph.Nodes, &GraphNode{
+			UUID: repo.UUID,
+			Kind: GraphNodePublished,
+			Name: fmt.Sprintf("%s/%s", repo.StoragePrefix(), repo.Distribution),
+			Meta: map[string]string{
+				"storage":       repo.Storage,
+				"prefix":        repo.Prefix,
+				"distribution":  repo.Distribution,
+				"components":    strings.Join(repo.Components(), " "),
+				"architectures": strings.Join(repo.Architectures, ", "),
+			},
 		})
 
-		for _, uuid := range repo.Sources {
+		for _, component := range repo.Components() {
+			uuid := repo.Sources[component]
 			_, exists := existingNodes[uuid]
 			if exists {
-				graph.AddEdge(uuid, repo.UUID, true, nil)
+				graph.Edges = append(graph.Edges, &GraphEdge{From: uuid, To: repo.UUID})
 			}
 		}
 
@@ -136,3 +167,120 @@ func BuildGraph(collectionFactory *CollectionFactory, layout string) (gographviz
 
 	return graph, nil
 }
+
+// Filter returns subgraph with lineage of objects named name: objects they have been
+// created from and objects created from them
+func (graph *Graph) Filter(name string) *Graph {
+	sources := map[string][]string{}
+	derived := map[string][]string{}
+
+	for _, edge := range graph.Edges {
+		sources[edge.To] = append(sources[edge.To], edge.From)
+		derived[edge.From] = append(derived[edge.From], edge.To)
+	}
+
+	keep := map[string]bool{}
+
+	var walk func(uuid string, links map[string][]string, visited map[string]bool)
+	walk = func(uuid string, links map[string][]string, visited map[string]bool) {
+		if visited[uuid] {
+			return
+		}
+		visited[uuid] = true
+		keep[uuid] = true
+
+		for _, next := range links[uuid] {
+			walk(next, links, visited)
+		}
+	}
+
+	upstream, downstream := map[string]bool{}, map[string]bool{}
+	for _, node := range graph.Nodes {
+		if node.Name == name {
+			walk(node.UUID, sources, upstream)
+			walk(node.UUID, derived, downstream)
+		}
+	}
+
+	result := &Graph{Nodes: []*GraphNode{}, Edges: []*GraphEdge{}}
+
+	for _, node := range graph.Nodes {
+		if keep[node.UUID] {
+			result.Nodes = append(result.Nodes, node)
+		}
+	}
+
+	for _, edge := range graph.Edges {
+		if keep[edge.From] && keep[edge.To] {
+			result.Edges = append(result.Edges, edge)
+		}
+	}
+
+	return result
+}
+
+// Dot converts graph to graphviz representation
+func (graph *Graph) Dot(layout string) gographviz.Interface {
+	dot := gographviz.NewEscape()
+	dot.SetDir(true)
+	dot.SetName("aptly")
+
+	var labelStart string
+	var labelEnd string
+
+	switch layout {
+	case "vertical":
+		dot.AddAttr("aptly", "rankdir", "LR")
+		labelStart = ""
+		labelEnd = ""
+	case "horizontal":
+		fallthrough
+	default:
+		labelStart = "{"
+		labelEnd = "}"
+	}
+
+	for _, node := range graph.Nodes {
+		var fillColor, label string
+
+		switch node.Kind {
+		case GraphNodeMirror:
+			fillColor = "darkgoldenrod1"
+			label = fmt.Sprintf("Mirror %s|url: %s|dist: %s|comp: %s|arch: %s|pkgs: %s", node.Name, node.Meta["url"],
+				node.Meta["distribution"], node.Meta["components"], node.Meta["architectures"], node.Meta["packages"])
+		case GraphNodeLocalRepo:
+			fillColor = "mediumseagreen"
+			label = fmt.Sprintf("Repo %s|comment: %s|pkgs: %s", node.Name, node.Meta["comment"], node.Meta["packages"])
+		case GraphNodeSnapshot:
+			fillColor = "cadetblue1"
+			label = fmt.Sprintf("Snapshot %s|%s|pkgs: %s", node.Name, node.Meta["description"], node.Meta["packages"])
+		case GraphNodePublished:
+			fillColor = "darkolivegreen1"
+			label = fmt.Sprintf("Published %s/%s|comp: %s|arch: %s", node.Meta["prefix"], node.Meta["distribution"],
+				node.Meta["components"], node.Meta["architectures"])
+		}
+
+		dot.AddNode("aptly", node.UUID, map[string]string{
+			"shape":     "Mrecord",
+			"style":     "filled",
+			"fillcolor": fillColor,
+			"label":     labelStart + label + labelEnd,
+		})
+	}
+
+	for _, edge := range graph.Edges {
+		dot.AddEdge(edge.From, edge.To, true, nil)
+	}
+
+	return dot
+}
+
+// BuildGraph generates graph contents from aptly object database
+func BuildGraph(collectionFactory *CollectionFactory, layout string) (gographviz.Interface, error) {
+	graph, err := BuildGraphData(collectionFactory)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.Dot(layout), nil
+}
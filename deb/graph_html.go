@@ -0,0 +1,162 @@
+package deb
+
+import (
+	"bytes"
+	"html/template"
+)
+
+// graphHTMLTemplate is a self-contained page rendering graph as SVG in the browser
+var graphHTMLTemplate = template.Must(template.New("graph").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>aptly graph</title>
+<style>
+body { margin: 0; font: 12px sans-serif; display: flex; height: 100vh; }
+#canvas { flex: 1; overflow: auto; }
+#side { width: 280px; border-left: 1px solid #ccc; padding: 8px; overflow: auto; }
+#side input { width: 100%; box-sizing: border-box; margin-bottom: 8px; }
+#details td { vertical-align: top; padding: 1px 4px; word-break: break-all; }
+g.node { cursor: pointer; }
+g.node rect { stroke: #333; rx: 6; }
+g.dim { opacity: 0.2; }
+path.edge { fill: none; stroke: #555; marker-end: url(#arrow); }
+path.dim { opacity: 0.1; }
+</style>
+</head>
+<body>
+<div id="canvas"><svg id="graph" xmlns="http://www.w3.org/2000/svg"></svg></div>
+<div id="side">
+<input id="search" type="search" placeholder="Filter by name">
+<table id="details"></table>
+</div>
+<script>
+var graph = {{.}};
+var colors = {mirror: "#ffb90f", repo: "#3cb371", snapshot: "#98f5ff", publish: "#caff70"};
+var titles = {mirror: "Mirror", repo: "Repo", snapshot: "Snapshot", publish: "Published"};
+var svgNS = "http://www.w3.org/2000/svg";
+var svg = document.getElementById("graph");
+
+var nodes = {}, sources = {}, derived = {};
+graph.Nodes.forEach(function(n) { nodes[n.UUID] = n; sources[n.UUID] = []; derived[n.UUID] = []; });
+graph.Edges.forEach(function(e) { sources[e.To].push(e.From); derived[e.From].push(e.To); });
+
+// rank is the length of the longest chain of sources
+var rank = {};
+function getRank(uuid) {
+	if (rank[uuid] === undefined) {
+		rank[uuid] = 0;
+		sources[uuid].forEach(function(s) { rank[uuid] = Math.max(rank[uuid], getRank(s) + 1); });
+	}
+	return rank[uuid];
+}
+
+var width = 240, gapX = 80, gapY = 20, lineHeight = 15, columns = [];
+graph.Nodes.forEach(function(n) {
+	var r = getRank(n.UUID);
+	columns[r] = columns[r] || [];
+	columns[r].push(n);
+});
+
+function el(name, attrs, parent) {
+	var e = document.createElementNS(svgNS, name);
+	for (var k in attrs) { e.setAttribute(k, attrs[k]); }
+	parent.appendChild(e);
+	return e;
+}
+
+var defs = el("defs", {}, svg);
+var marker = el("marker", {id: "arrow", viewBox: "0 0 10 10", refX: 10, refY: 5, markerWidth: 8, markerHeight: 8, orient: "auto"}, defs);
+el("path", {d: "M 0 0 L 10 5 L 0 10 z", fill: "#555"}, marker);
+
+var edgeLayer = el("g", {}, svg), nodeLayer = el("g", {}, svg);
+var totalWidth = 0, totalHeight = 0;
+
+columns.forEach(function(column, r) {
+	var y = gapY;
+	column.forEach(function(n) {
+		var lines = [titles[n.Kind] + " " + n.Name];
+		["url", "distribution", "components", "architectures", "comment", "description", "packages"].forEach(function(k) {
+			if (n.Meta[k]) { lines.push(k + ": " + n.Meta[k]); }
+		});
+
+		n.x = gapX / 2 + r * (width + gapX);
+		n.y = y;
+		n.height = lines.length * lineHeight + 10;
+
+		var g = el("g", {"class": "node"}, nodeLayer);
+		el("rect", {x: n.x, y: n.y, width: width, height: n.height, fill: colors[n.Kind]}, g);
+		lines.forEach(function(line, i) {
+			var t = el("text", {x: n.x + 6, y: n.y + 17 + i * lineHeight, "font-weight": i === 0 ? "bold" : "normal"}, g);
+			t.textContent = line.length > 38 ? line.substring(0, 37) + "…" : line;
+		});
+		g.addEventListener("click", function() { select(n); });
+		n.element = g;
+
+		y += n.height + gapY;
+	});
+	totalWidth = Math.max(totalWidth, gapX / 2 + (r + 1) * (width + gapX));
+	totalHeight = Math.max(totalHeight, y);
+});
+
+svg.setAttribute("width", totalWidth);
+svg.setAttribute("height", totalHeight);
+
+graph.Edges.forEach(function(e) {
+	var from = nodes[e.From], to = nodes[e.To];
+	var x1 = from.x + width, y1 = from.y + from.height / 2, x2 = to.x, y2 = to.y + to.height / 2;
+	e.element = el("path", {"class": "edge", d: "M " + x1 + " " + y1 + " C " + (x1 + gapX / 2) + " " + y1 + ", " +
+		(x2 - gapX / 2) + " " + y2 + ", " + x2 + " " + y2}, edgeLayer);
+});
+
+function collect(uuid, links, result) {
+	if (result[uuid]) { return; }
+	result[uuid] = true;
+	links[uuid].forEach(function(next) { collect(next, links, result); });
+}
+
+function highlight(selected, all) {
+	var keep = {};
+	selected.forEach(function(n) {
+		var up = {}, down = {};
+		collect(n.UUID, sources, up);
+		collect(n.UUID, derived, down);
+		Object.keys(up).concat(Object.keys(down)).forEach(function(uuid) { keep[uuid] = true; });
+	});
+	graph.Nodes.forEach(function(n) { n.element.setAttribute("class", all || keep[n.UUID] ? "node" : "node dim"); });
+	graph.Edges.forEach(function(e) { e.element.setAttribute("class", all || (keep[e.From] && keep[e.To]) ? "edge" : "edge dim"); });
+}
+
+function select(n) {
+	highlight([n], false);
+	var details = document.getElementById("details");
+	details.innerHTML = "";
+	var rows = [["kind", titles[n.Kind]], ["name", n.Name], ["uuid", n.UUID]];
+	Object.keys(n.Meta).sort().forEach(function(k) { rows.push([k, n.Meta[k]]); });
+	rows.forEach(function(row) {
+		var tr = details.insertRow();
+		tr.insertCell().textContent = row[0];
+		tr.insertCell().textContent = row[1];
+	});
+}
+
+document.getElementById("search").addEventListener("input", function() {
+	var query = this.value.toLowerCase();
+	highlight(graph.Nodes.filter(function(n) { return n.Name.toLowerCase().indexOf(query) !== -1; }), query === "");
+});
+</script>
+</body>
+</html>
+`))
+
+// HTML renders graph as self-contained HTML page with interactive SVG drawing
+func (graph *Graph) HTML() ([]byte, error) {
+	var buf bytes.Buffer
+
+	err := graphHTMLTemplate.Execute(&buf, graph)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
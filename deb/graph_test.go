@@ -0,0 +1,100 @@
+package deb
+
+import (
+	"strings"
+
+	"github.com/aptly-dev/aptly/database"
+	"github.com/aptly-dev/aptly/database/goleveldb"
+
+	. "gopkg.in/check.v1"
+)
+
+type GraphSuite struct {
+	db                database.Storage
+	collectionFactory *CollectionFactory
+	repo              *LocalRepo
+	snapshot1         *Snapshot
+	snapshot2         *Snapshot
+	snapshot3         *Snapshot
+}
+
+var _ = Suite(&GraphSuite{})
+
+func (s *GraphSuite) SetUpTest(c *C) {
+	s.db, _ = goleveldb.NewOpenDB(c.MkDir())
+	s.collectionFactory = NewCollectionFactory(s.db)
+
+	s.repo = NewLocalRepo("repo", "my repo")
+	s.repo.UpdateRefList(NewPackageRefList())
+	c.Assert(s.collectionFactory.LocalRepoCollection().Add(s.repo), IsNil)
+
+	s.snapshot1 = NewSnapshotFromRefList("snap1", nil, NewPackageRefList(), "")
+	s.snapshot1.SourceKind = SourceLocalRepo
+	s.snapshot1.SourceIDs = []string{s.repo.UUID}
+	c.Assert(s.collectionFactory.SnapshotCollection().Add(s.snapshot1), IsNil)
+
+	s.snapshot2 = NewSnapshotFromRefList("snap2", []*Snapshot{s.snapshot1}, NewPackageRefList(), "merged")
+	c.Assert(s.collectionFactory.SnapshotCollection().Add(s.snapshot2), IsNil)
+
+	s.snapshot3 = NewSnapshotFromRefList("snap3", nil, NewPackageRefList(), "unrelated")
+	c.Assert(s.collectionFactory.SnapshotCollection().Add(s.snapshot3), IsNil)
+}
+
+func (s *GraphSuite) TearDownTest(c *C) {
+	s.db.Close()
+}
+
+func (s *GraphSuite) TestBuildGraphData(c *C) {
+	graph, err := BuildGraphData(s.collectionFactory)
+	c.Assert(err, IsNil)
+
+	c.Assert(graph.Nodes, HasLen, 4)
+	c.Check(graph.Nodes[0].Kind, Equals, GraphNodeLocalRepo)
+	c.Check(graph.Nodes[0].Name, Equals, "repo")
+	c.Check(graph.Nodes[0].Meta["comment"], Equals, "my repo")
+
+	// snapshots are iterated in UUID order
+	edges := map[GraphEdge]bool{}
+	for _, edge := range graph.Edges {
+		edges[*edge] = true
+	}
+	c.Check(edges, DeepEquals, map[GraphEdge]bool{
+		{From: s.repo.UUID, To: s.snapshot1.UUID}:      true,
+		{From: s.snapshot1.UUID, To: s.snapshot2.UUID}: true,
+	})
+
+	dot := graph.Dot("horizontal").String()
+	c.Check(strings.HasPrefix(dot, "digraph aptly"), Equals, true)
+	c.Check(dot, Matches, "(?s).*Repo repo\\|comment: my repo\\|pkgs: 0.*")
+
+	html, err := graph.HTML()
+	c.Assert(err, IsNil)
+	c.Check(string(html), Matches, "(?s)<!DOCTYPE html>.*\"Name\":\"snap2\".*")
+}
+
+func (s *GraphSuite) TestFilter(c *C) {
+	graph, err := BuildGraphData(s.collectionFactory)
+	c.Assert(err, IsNil)
+
+	names := func(g *Graph) []string {
+		result := []string{}
+		for _, node := range g.Nodes {
+			result = append(result, node.Name)
+		}
+		return result
+	}
+
+	filtered := graph.Filter("snap2")
+	c.Check(names(filtered), HasLen, 3)
+	c.Check(filtered.Edges, HasLen, 2)
+
+	filtered = graph.Filter("snap1")
+	c.Check(names(filtered), HasLen, 3)
+	c.Check(names(filtered)[0], Equals, "repo")
+
+	filtered = graph.Filter("snap3")
+	c.Check(names(filtered), DeepEquals, []string{"snap3"})
+	c.Check(filtered.Edges, HasLen, 0)
+
+	c.Check(graph.Filter("none").Nodes, HasLen, 0)
+}
@@ -64,9 +64,15 @@ func CollectPackageFiles(locations []string, reporter aptly.ResultReporter) (pac
 }
 
 // ImportPackageFiles imports files into local repository
+//
+// If uploaders is not nil, every package is checked against uploaders rules: signature keys
+// of .dsc files and user (authenticated API user, if not empty) are used as uploader identities.
+//
+// If provenance is not nil, it's recorded for packages seen for the first time.
 func ImportPackageFiles(list *PackageList, packageFiles []string, forceReplace bool, verifier pgp.Verifier,
 	pool aptly.PackagePool, collection *PackageCollection, reporter aptly.ResultReporter, restriction PackageQuery,
-	checksumStorageProvider aptly.ChecksumStorageProvider) (processedFiles []string, failedFiles []string, err error) {
+	checksumStorageProvider aptly.ChecksumStorageProvider, uploaders *Uploaders, user string,
+	provenance *PackageProvenance) (processedFiles []string, failedFiles []string, err error) {
 	if forceReplace {
 		list.PrepareIndex()
 	}
@@ -130,6 +136,27 @@ func ImportPackageFiles(list *PackageList, packageFiles []string, forceReplace b
 			continue
 		}
 
+		if uploaders != nil {
+			var keys []pgp.Key
+
+			if isSourcePackage {
+				keys, err = GetSignatureKeysFromDsc(file, verifier)
+			} else {
+				keys, err = GetSignatureKeysFromDeb(file, verifier)
+			}
+			if err != nil {
+				reporter.Warning("Unable to verify signature on %s: %s", file, err)
+				failedFiles = append(failedFiles, file)
+				continue
+			}
+
+			if err = uploaders.IsAllowedPackage(p, keys, user); err != nil {
+				reporter.Warning("%s has been rejected due to uploaders config, keys %#v: %s", p, keys, err)
+				failedFiles = append(failedFiles, file)
+				continue
+			}
+		}
+
 		var files PackageFiles
 
 		if isSourcePackage {
@@ -211,6 +238,10 @@ func ImportPackageFiles(list *PackageList, packageFiles []string, forceReplace b
 		if forceReplace {
 			conflictingPackages := list.Search(Dependency{Pkg: p.Name, Version: p.Version, Relation: VersionEqual, Architecture: p.Architecture}, true)
 			for _, cp := range conflictingPackages {
+				if cp.Name != p.Name {
+					// matched via versioned Provides
+					continue
+				}
 				reporter.Removed("%s removed due to conflict with package being added", cp)
 				list.Remove(cp)
 			}
@@ -223,6 +254,11 @@ func ImportPackageFiles(list *PackageList, packageFiles []string, forceReplace b
 			continue
 		}
 
+		err = collection.UpdateProvenanceInTransaction(p, provenance, transaction)
+		if err != nil {
+			return nil, nil, err
+		}
+
 		reporter.Added("%s added", p)
 		processedFiles = append(processedFiles, candidateProcessedFiles...)
 	}
@@ -378,6 +378,47 @@ func (files *indexFiles) LegacyContentsIndex(arch string, udeb bool) *indexFile
 	return file
 }
 
+func (files *indexFiles) TranslationIndex(component, lang string) *indexFile {
+	key := fmt.Sprintf("ti-%s-%s", component, lang)
+	file, ok := files.indexes[key]
+	if !ok {
+		file = &indexFile{
+			parent:        files,
+			discardable:   true,
+			compressable:  true,
+			onlyGzip:      false,
+			detachedSign:  false,
+			clearSign:     false,
+			acquireByHash: files.acquireByHash,
+			relativePath:  filepath.Join(component, "i18n", fmt.Sprintf("Translation-%s", lang)),
+		}
+
+		files.indexes[key] = file
+	}
+
+	return file
+}
+
+func (files *indexFiles) ExtraIndex(component, path string) *indexFile {
+	key := fmt.Sprintf("ei-%s-%s", component, path)
+	file, ok := files.indexes[key]
+	if !ok {
+		file = &indexFile{
+			parent:        files,
+			discardable:   false,
+			compressable:  false,
+			detachedSign:  false,
+			clearSign:     false,
+			acquireByHash: files.acquireByHash,
+			relativePath:  filepath.Join(component, path),
+		}
+
+		files.indexes[key] = file
+	}
+
+	return file
+}
+
 func (files *indexFiles) ReleaseFile() *indexFile {
 	return &indexFile{
 		parent:       files,
@@ -0,0 +1,388 @@
+package deb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aptly-dev/aptly/aptly"
+)
+
+// installabilityMaxSteps limits amount of work done for single package, as in the worst case
+// installability problem is NP-complete
+const installabilityMaxSteps = 100000
+
+// InstallabilityProblem describes why package can't be installed
+type InstallabilityProblem struct {
+	Package      *Package
+	Architecture string
+	// Chain of dependencies leading from the package to the problem
+	Path []string
+	// Why the last dependency in the chain can't be satisfied
+	Reason string
+}
+
+// String returns human-readable explanation of the problem
+func (problem *InstallabilityProblem) String() string {
+	lines := []string{fmt.Sprintf("%s [%s]:", problem.Package, problem.Architecture)}
+	for _, step := range problem.Path {
+		lines = append(lines, "  "+step)
+	}
+	lines = append(lines, "  "+problem.Reason)
+
+	return strings.Join(lines, "\n")
+}
+
+// InstallabilityChecker verifies that packages could be installed: that there's a set of
+// packages satisfying all the hard dependencies (Pre-Depends, Depends) of the package and
+// having no Conflicts or Breaks between them
+type InstallabilityChecker struct {
+	sources     *PackageList
+	conflicts   map[*Package][]packageConflict
+	installable map[string]map[*Package]bool
+}
+
+// packageConflict is parsed Conflicts or Breaks entry
+type packageConflict struct {
+	owner *Package
+	field string
+	entry string
+	dep   Dependency
+}
+
+// installabilityRequirement is a dependency which should be satisfied
+type installabilityRequirement struct {
+	path     []string
+	variants []Dependency
+}
+
+// installabilityState is the set of packages being installed
+type installabilityState struct {
+	arch        string
+	installed   map[string]*Package
+	provided    map[string][]*Package
+	conflicting map[string][]packageConflict
+	steps       int
+
+	// failure with most packages installed, as the most relevant one
+	failure          *InstallabilityProblem
+	failureInstalled int
+}
+
+// NewInstallabilityChecker creates checker which resolves dependencies using packages from sources
+func NewInstallabilityChecker(sources *PackageList) *InstallabilityChecker {
+	sources.PrepareIndex()
+
+	return &InstallabilityChecker{
+		sources:     sources,
+		conflicts:   make(map[*Package][]packageConflict),
+		installable: make(map[string]map[*Package]bool),
+	}
+}
+
+// CheckAll checks installability of every binary package in the list for each of the architectures
+func (checker *InstallabilityChecker) CheckAll(list *PackageList, architectures []string, progress aptly.Progress) ([]*InstallabilityProblem, error) {
+	list.PrepareIndex()
+	problems := []*InstallabilityProblem{}
+
+	if progress != nil {
+		progress.InitBar(int64(list.Len())*int64(len(architectures)), false)
+		defer progress.ShutdownBar()
+	}
+
+	for _, arch := range architectures {
+		for _, p := range list.packagesIndex {
+			if progress != nil {
+				progress.AddBar(1)
+			}
+
+			if arch == ArchitectureSource || p.IsSource || !p.MatchesArchitecture(arch) {
+				continue
+			}
+
+			problem, err := checker.Check(p, arch)
+			if err != nil {
+				return nil, err
+			}
+
+			if problem != nil {
+				problems = append(problems, problem)
+			}
+		}
+	}
+
+	return problems, nil
+}
+
+// Check verifies that package could be installed on architecture arch, returning nil if it could
+func (checker *InstallabilityChecker) Check(p *Package, arch string) (*InstallabilityProblem, error) {
+	if checker.installable[arch][p] {
+		return nil, nil
+	}
+
+	state := &installabilityState{
+		arch:        arch,
+		installed:   make(map[string]*Package),
+		provided:    make(map[string][]*Package),
+		conflicting: make(map[string][]packageConflict),
+	}
+
+	if err := checker.install(state, p); err != nil {
+		return nil, err
+	}
+
+	agenda, err := checker.requirements(p, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := checker.solve(state, agenda)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		if state.failure == nil || state.steps > installabilityMaxSteps {
+			return &InstallabilityProblem{
+				Package:      p,
+				Architecture: arch,
+				Reason:       "unable to find installation set: search limit exceeded",
+			}, nil
+		}
+
+		state.failure.Package = p
+		return state.failure, nil
+	}
+
+	// every package from the installation set is installable as well
+	if checker.installable[arch] == nil {
+		checker.installable[arch] = make(map[*Package]bool)
+	}
+	for _, installed := range state.installed {
+		checker.installable[arch][installed] = true
+	}
+
+	return nil, nil
+}
+
+// solve looks for packages satisfying all the requirements in the agenda, backtracking
+// to other candidates when dependency can't be satisfied
+func (checker *InstallabilityChecker) solve(state *installabilityState, agenda []*installabilityRequirement) (bool, error) {
+	for len(agenda) > 0 && state.satisfied(agenda[0]) {
+		agenda = agenda[1:]
+	}
+
+	if len(agenda) == 0 {
+		return true, nil
+	}
+
+	state.steps++
+	if state.steps > installabilityMaxSteps {
+		return false, nil
+	}
+
+	requirement, rest := agenda[0], agenda[1:]
+
+	candidates := checker.candidates(requirement, state.arch)
+	if len(candidates) == 0 {
+		state.fail(requirement, "no package satisfies the dependency")
+		return false, nil
+	}
+
+	for _, candidate := range candidates {
+		reason, err := checker.conflict(state, candidate)
+		if err != nil {
+			return false, err
+		}
+
+		if reason != "" {
+			state.fail(requirement, reason)
+			continue
+		}
+
+		if err = checker.install(state, candidate); err != nil {
+			return false, err
+		}
+
+		requirements, err := checker.requirements(candidate, requirement.path)
+		if err != nil {
+			return false, err
+		}
+
+		ok, err := checker.solve(state, append(requirements, rest...))
+		if err != nil || ok {
+			return ok, err
+		}
+
+		state.uninstall(candidate, checker.conflicts[candidate])
+
+		if state.steps > installabilityMaxSteps {
+			break
+		}
+	}
+
+	return false, nil
+}
+
+// requirements returns hard dependencies of the package as requirements
+func (checker *InstallabilityChecker) requirements(p *Package, path []string) ([]*installabilityRequirement, error) {
+	result := []*installabilityRequirement{}
+
+	for _, field := range p.dependencyFields(0) {
+		for _, dependency := range field.dependencies {
+			variants, err := ParseDependencyVariants(dependency)
+			if err != nil {
+				return nil, fmt.Errorf("unable to process package %s: %s", p, err)
+			}
+
+			requirementPath := make([]string, len(path), len(path)+1)
+			copy(requirementPath, path)
+
+			result = append(result, &installabilityRequirement{
+				path:     append(requirementPath, fmt.Sprintf("%s %s: %s", p, field.name, dependency)),
+				variants: variants,
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// candidates returns packages which could satisfy requirement in order of preference
+func (checker *InstallabilityChecker) candidates(requirement *installabilityRequirement, arch string) []*Package {
+	result := []*Package{}
+	seen := make(map[*Package]bool)
+
+	for _, dep := range requirement.variants {
+		if dep.Architecture == "" {
+			dep.Architecture = arch
+		}
+
+		for _, p := range checker.sources.Search(dep, true) {
+			if !seen[p] {
+				seen[p] = true
+				result = append(result, p)
+			}
+		}
+	}
+
+	return result
+}
+
+// packageConflicts returns parsed Conflicts and Breaks of the package
+func (checker *InstallabilityChecker) packageConflicts(p *Package) ([]packageConflict, error) {
+	conflicts, ok := checker.conflicts[p]
+	if ok {
+		return conflicts, nil
+	}
+
+	for _, field := range []string{"Conflicts", "Breaks"} {
+		value := strings.TrimSpace(p.Extra()[field])
+		if value == "" {
+			continue
+		}
+
+		for _, entry := range strings.Split(value, ",") {
+			entry = strings.TrimSpace(entry)
+			dep, err := ParseDependency(entry)
+			if err != nil {
+				return nil, fmt.Errorf("unable to process package %s: %s", p, err)
+			}
+
+			conflicts = append(conflicts, packageConflict{owner: p, field: field, entry: entry, dep: dep})
+		}
+	}
+
+	checker.conflicts[p] = conflicts
+
+	return conflicts, nil
+}
+
+// conflict returns reason why package can't be added to the set of installed packages
+func (checker *InstallabilityChecker) conflict(state *installabilityState, p *Package) (string, error) {
+	if installed := state.installed[p.Name]; installed != nil && installed != p {
+		return fmt.Sprintf("%s can't be installed together with %s", p, installed), nil
+	}
+
+	conflicts, err := checker.packageConflicts(p)
+	if err != nil {
+		return "", err
+	}
+
+	for _, conflict := range conflicts {
+		for _, installed := range state.provided[conflict.dep.Pkg] {
+			if installed != p && installed.MatchesDependency(conflict.dep) {
+				return fmt.Sprintf("%s %s: %s (%s)", p, conflict.field, conflict.entry, installed), nil
+			}
+		}
+	}
+
+	for _, name := range append([]string{p.Name}, p.ProvidedNames()...) {
+		for _, conflict := range state.conflicting[name] {
+			if conflict.owner != p && p.MatchesDependency(conflict.dep) {
+				return fmt.Sprintf("%s %s: %s (%s)", conflict.owner, conflict.field, conflict.entry, p), nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// install adds package to the set of installed packages
+func (checker *InstallabilityChecker) install(state *installabilityState, p *Package) error {
+	conflicts, err := checker.packageConflicts(p)
+	if err != nil {
+		return err
+	}
+
+	state.installed[p.Name] = p
+
+	for _, name := range append([]string{p.Name}, p.ProvidedNames()...) {
+		state.provided[name] = append(state.provided[name], p)
+	}
+
+	for _, conflict := range conflicts {
+		state.conflicting[conflict.dep.Pkg] = append(state.conflicting[conflict.dep.Pkg], conflict)
+	}
+
+	return nil
+}
+
+// uninstall removes package installed last from the set of installed packages
+func (state *installabilityState) uninstall(p *Package, conflicts []packageConflict) {
+	delete(state.installed, p.Name)
+
+	for _, name := range append([]string{p.Name}, p.ProvidedNames()...) {
+		state.provided[name] = state.provided[name][:len(state.provided[name])-1]
+	}
+
+	for _, conflict := range conflicts {
+		state.conflicting[conflict.dep.Pkg] = state.conflicting[conflict.dep.Pkg][:len(state.conflicting[conflict.dep.Pkg])-1]
+	}
+}
+
+// satisfied checks whether requirement is satisfied by already installed packages
+func (state *installabilityState) satisfied(requirement *installabilityRequirement) bool {
+	for _, dep := range requirement.variants {
+		for _, p := range state.provided[dep.Pkg] {
+			if p.MatchesDependency(dep) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// fail records failure to satisfy requirement
+func (state *installabilityState) fail(requirement *installabilityRequirement, reason string) {
+	if state.failure != nil && len(state.installed) <= state.failureInstalled {
+		return
+	}
+
+	state.failure = &InstallabilityProblem{
+		Architecture: state.arch,
+		Path:         requirement.path,
+		Reason:       reason,
+	}
+	state.failureInstalled = len(state.installed)
+}
@@ -0,0 +1,101 @@
+package deb
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type InstallabilitySuite struct {
+	list *PackageList
+}
+
+var _ = Suite(&InstallabilitySuite{})
+
+func (s *InstallabilitySuite) SetUpTest(c *C) {
+	s.list = NewPackageList()
+
+	for _, p := range []*Package{
+		{Name: "dpkg", Version: "1.7", Architecture: "i386", Provides: []string{"package-installer"}},
+		{Name: "dpkg", Version: "1.7", Architecture: "amd64", Provides: []string{"package-installer"}},
+		{Name: "lib", Version: "1.0", Architecture: "i386", deps: &PackageDependencies{PreDepends: []string{"dpkg (>= 1.6)"}}},
+		{Name: "lib", Version: "2.0", Architecture: "i386", deps: &PackageDependencies{PreDepends: []string{"dpkg (>= 1.6)"}}},
+		{Name: "data", Version: "1.1", Architecture: "all", deps: &PackageDependencies{Depends: []string{"package-installer"}}},
+		{Name: "app", Version: "1.1", Architecture: "i386", deps: &PackageDependencies{Depends: []string{"lib (>> 0.9)", "data"}}},
+		{Name: "app", Version: "1.1", Architecture: "amd64", deps: &PackageDependencies{Depends: []string{"lib (>> 0.9)", "data"}}},
+		{Name: "legacy", Version: "0.1", Architecture: "i386", deps: &PackageDependencies{Depends: []string{"lib (<< 2.0)", "app"}}},
+		{Name: "mta-a", Version: "1.0", Architecture: "i386", Provides: []string{"mail-transport-agent"},
+			extra: &Stanza{"Conflicts": "mail-transport-agent"}},
+		{Name: "mta-b", Version: "1.0", Architecture: "i386", Provides: []string{"mail-transport-agent"},
+			extra: &Stanza{"Conflicts": "mail-transport-agent"}},
+		{Name: "mailer", Version: "1.0", Architecture: "i386", deps: &PackageDependencies{Depends: []string{"mta-a | mta-b", "mta-b"}}},
+		{Name: "broken", Version: "1.0", Architecture: "i386", deps: &PackageDependencies{Depends: []string{"mta-a", "mta-b"}}},
+		{Name: "oldapp", Version: "1.0", Architecture: "i386", extra: &Stanza{"Breaks": "data (>= 1.0)"}},
+		{Name: "suite", Version: "1.0", Architecture: "i386", deps: &PackageDependencies{Depends: []string{"oldapp", "app"}}},
+		{Name: "app", Version: "1.1", Architecture: "source", IsSource: true, deps: &PackageDependencies{BuildDepends: []string{"missing"}}},
+	} {
+		if p.deps == nil {
+			p.deps = &PackageDependencies{}
+		}
+		if p.extra == nil {
+			p.extra = &Stanza{}
+		}
+		s.list.Add(p)
+	}
+}
+
+func (s *InstallabilitySuite) check(c *C, key, arch string) *InstallabilityProblem {
+	checker := NewInstallabilityChecker(s.list)
+	p := s.list.packages[key]
+	c.Assert(p, NotNil)
+
+	problem, err := checker.Check(p, arch)
+	c.Assert(err, IsNil)
+
+	return problem
+}
+
+func (s *InstallabilitySuite) TestInstallable(c *C) {
+	c.Check(s.check(c, "Pi386 app 1.1", "i386"), IsNil)
+	c.Check(s.check(c, "Pall data 1.1", "i386"), IsNil)
+	c.Check(s.check(c, "Pi386 mailer 1.0", "i386"), IsNil)
+}
+
+func (s *InstallabilitySuite) TestBacktracking(c *C) {
+	// lib 2.0 is preferred, but legacy requires lib << 2.0
+	c.Check(s.check(c, "Pi386 legacy 0.1", "i386"), IsNil)
+}
+
+func (s *InstallabilitySuite) TestMissing(c *C) {
+	problem := s.check(c, "Pamd64 app 1.1", "amd64")
+	c.Assert(problem, NotNil)
+	c.Check(problem.Path, DeepEquals, []string{"app_1.1_amd64 Depends: lib (>> 0.9)"})
+	c.Check(problem.Reason, Equals, "no package satisfies the dependency")
+	c.Check(problem.String(), Equals, "app_1.1_amd64 [amd64]:\n  app_1.1_amd64 Depends: lib (>> 0.9)\n  no package satisfies the dependency")
+}
+
+func (s *InstallabilitySuite) TestConflicts(c *C) {
+	problem := s.check(c, "Pi386 broken 1.0", "i386")
+	c.Assert(problem, NotNil)
+	c.Check(problem.Path, DeepEquals, []string{"broken_1.0_i386 Depends: mta-b"})
+	c.Check(problem.Reason, Equals, "mta-b_1.0_i386 Conflicts: mail-transport-agent (mta-a_1.0_i386)")
+}
+
+func (s *InstallabilitySuite) TestBreaks(c *C) {
+	problem := s.check(c, "Pi386 suite 1.0", "i386")
+	c.Assert(problem, NotNil)
+	c.Check(problem.Path, DeepEquals, []string{"suite_1.0_i386 Depends: app", "app_1.1_i386 Depends: data"})
+	c.Check(problem.Reason, Equals, "oldapp_1.0_i386 Breaks: data (>= 1.0) (data_1.1_all)")
+}
+
+func (s *InstallabilitySuite) TestCheckAll(c *C) {
+	checker := NewInstallabilityChecker(s.list)
+
+	problems, err := checker.CheckAll(s.list, []string{"amd64", "i386", "source"}, nil)
+	c.Assert(err, IsNil)
+
+	broken := []string{}
+	for _, problem := range problems {
+		broken = append(broken, problem.Package.String()+" "+problem.Architecture)
+	}
+
+	c.Check(broken, DeepEquals, []string{"app_1.1_amd64 amd64", "broken_1.0_i386 i386", "suite_1.0_i386 i386"})
+}
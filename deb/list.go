@@ -145,7 +145,7 @@ func (l *PackageList) Add(p *Package) error {
 	l.packages[key] = p
 
 	if l.indexed {
-		for _, provides := range p.Provides {
+		for _, provides := range p.ProvidedNames() {
 			l.providesIndex[provides] = append(l.providesIndex[provides], p)
 		}
 
@@ -215,7 +215,7 @@ func (l *PackageList) Append(pl *PackageList) error {
 func (l *PackageList) Remove(p *Package) {
 	delete(l.packages, l.keyFunc(p))
 	if l.indexed {
-		for _, provides := range p.Provides {
+		for _, provides := range p.ProvidedNames() {
 			for i, pkg := range l.providesIndex[provides] {
 				if pkg.Equals(p) {
 					// remove l.ProvidesIndex[provides][i] w/o preserving order
@@ -404,7 +404,7 @@ func (l *PackageList) PrepareIndex() {
 		l.packagesIndex[i] = p
 		i++
 
-		for _, provides := range p.Provides {
+		for _, provides := range p.ProvidedNames() {
 			l.providesIndex[provides] = append(l.providesIndex[provides], p)
 		}
 	}
@@ -464,14 +464,21 @@ func (l *PackageList) Search(dep Dependency, allMatches bool) (searchResults []*
 		i++
 	}
 
-	if dep.Relation == VersionDontCare {
-		for _, p := range l.providesIndex[dep.Pkg] {
-			if dep.Architecture == "" || p.MatchesArchitecture(dep.Architecture) {
-				searchResults = append(searchResults, p)
+	if len(searchResults) > 0 && !allMatches {
+		return
+	}
 
-				if !allMatches {
-					break
-				}
+	for _, p := range l.providesIndex[dep.Pkg] {
+		if p.Name == dep.Pkg {
+			// already matched above
+			continue
+		}
+
+		if p.MatchesDependency(dep) {
+			searchResults = append(searchResults, p)
+
+			if !allMatches {
+				break
 			}
 		}
 	}
@@ -493,6 +500,7 @@ func (l *PackageList) FilterWithProgress(queries []PackageQuery, withDependencie
 	result := NewPackageList()
 
 	for _, query := range queries {
+		prepareReverseDependsQueries(query, l)
 		result.Append(query.Query(l))
 	}
 
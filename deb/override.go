@@ -0,0 +1,205 @@
+package deb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Override file types (as in Debian archive tools)
+const (
+	// OverrideBinary is "package priority section [maintainer]" override file
+	OverrideBinary = "binary"
+	// OverrideSource is "package section" override file
+	OverrideSource = "source"
+	// OverrideExtra is "package field value" override file
+	OverrideExtra = "extra"
+)
+
+// PackageOverrides is a set of overrides for control fields of published packages,
+// overrides are applied to Packages/Sources stanzas at publishing time
+type PackageOverrides struct {
+	// Binary overrides: package name -> field -> value
+	Binary map[string]map[string]string
+	// Source overrides: source package name -> field -> value
+	Source map[string]map[string]string
+	// Extra overrides for binary packages: package name -> field -> value
+	Extra map[string]map[string]string
+}
+
+// NewPackageOverrides creates empty PackageOverrides
+func NewPackageOverrides() *PackageOverrides {
+	return &PackageOverrides{
+		Binary: map[string]map[string]string{},
+		Source: map[string]map[string]string{},
+		Extra:  map[string]map[string]string{},
+	}
+}
+
+// ParseOverrides reads override file of specified type
+//
+// Empty lines and comments (starting with '#') are ignored
+func ParseOverrides(r io.Reader, overrideType string) (map[string]map[string]string, error) {
+	result := map[string]map[string]string{}
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i != -1 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var pkg, field string
+		values := map[string]string{}
+
+		switch overrideType {
+		case OverrideBinary:
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("line %d: expected 'package priority section', got: %s", lineNo, line)
+			}
+			pkg = fields[0]
+			values["Priority"] = fields[1]
+			values["Section"] = fields[2]
+		case OverrideSource:
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("line %d: expected 'package section', got: %s", lineNo, line)
+			}
+			pkg = fields[0]
+			values["Section"] = fields[1]
+		case OverrideExtra:
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("line %d: expected 'package field value', got: %s", lineNo, line)
+			}
+			pkg, field = fields[0], canonicalCase(fields[1])
+			values[field] = strings.Join(fields[2:], " ")
+		default:
+			return nil, fmt.Errorf("unknown override type: %s", overrideType)
+		}
+
+		if result[pkg] == nil {
+			result[pkg] = map[string]string{}
+		}
+
+		for k, v := range values {
+			if overrideType == OverrideExtra && result[pkg][k] != "" {
+				// repeated fields (e.g. Task) are accumulated
+				v = result[pkg][k] + ", " + v
+			}
+			result[pkg][k] = v
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Set replaces overrides of specified type
+func (o *PackageOverrides) Set(overrideType string, overrides map[string]map[string]string) error {
+	switch overrideType {
+	case OverrideBinary:
+		o.Binary = overrides
+	case OverrideSource:
+		o.Source = overrides
+	case OverrideExtra:
+		o.Extra = overrides
+	default:
+		return fmt.Errorf("unknown override type: %s", overrideType)
+	}
+
+	return nil
+}
+
+// Get returns overrides of specified type
+func (o *PackageOverrides) Get(overrideType string) (map[string]map[string]string, error) {
+	switch overrideType {
+	case OverrideBinary:
+		return o.Binary, nil
+	case OverrideSource:
+		return o.Source, nil
+	case OverrideExtra:
+		return o.Extra, nil
+	}
+
+	return nil, fmt.Errorf("unknown override type: %s", overrideType)
+}
+
+// Len returns total number of overridden packages
+func (o *PackageOverrides) Len() int {
+	if o == nil {
+		return 0
+	}
+
+	return len(o.Binary) + len(o.Source) + len(o.Extra)
+}
+
+// Apply overrides fields in package stanza (as generated for publishing)
+func (o *PackageOverrides) Apply(p *Package, stanza Stanza) {
+	if o == nil {
+		return
+	}
+
+	var sets []map[string]map[string]string
+	if p.IsSource {
+		sets = []map[string]map[string]string{o.Source}
+	} else {
+		sets = []map[string]map[string]string{o.Binary, o.Extra}
+	}
+
+	for _, set := range sets {
+		for field, value := range set[p.Name] {
+			stanza[field] = value
+		}
+	}
+}
+
+// Lines returns overrides of specified type formatted as override file lines
+func (o *PackageOverrides) Lines(overrideType string) ([]string, error) {
+	overrides, err := o.Get(overrideType)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := []string{}
+	for _, name := range names {
+		fields := overrides[name]
+
+		switch overrideType {
+		case OverrideBinary:
+			result = append(result, fmt.Sprintf("%s %s %s", name, fields["Priority"], fields["Section"]))
+		case OverrideSource:
+			result = append(result, fmt.Sprintf("%s %s", name, fields["Section"]))
+		case OverrideExtra:
+			keys := make([]string, 0, len(fields))
+			for key := range fields {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				result = append(result, fmt.Sprintf("%s %s %s", name, key, fields[key]))
+			}
+		}
+	}
+
+	return result, nil
+}
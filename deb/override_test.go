@@ -0,0 +1,94 @@
+package deb
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type PackageOverridesSuite struct{}
+
+var _ = Suite(&PackageOverridesSuite{})
+
+func (s *PackageOverridesSuite) TestParseOverrides(c *C) {
+	binary, err := ParseOverrides(strings.NewReader(`
+# comment
+aptly          optional   utils
+libc6   required libs   Debian Maintainers <debian@example.com>
+
+`), OverrideBinary)
+	c.Assert(err, IsNil)
+	c.Check(binary, DeepEquals, map[string]map[string]string{
+		"aptly": {"Priority": "optional", "Section": "utils"},
+		"libc6": {"Priority": "required", "Section": "libs"},
+	})
+
+	source, err := ParseOverrides(strings.NewReader("aptly devel\nglibc libs # comment\n"), OverrideSource)
+	c.Assert(err, IsNil)
+	c.Check(source, DeepEquals, map[string]map[string]string{
+		"aptly": {"Section": "devel"},
+		"glibc": {"Section": "libs"},
+	})
+
+	extra, err := ParseOverrides(strings.NewReader("aptly Task server\naptly task desktop\nlibc6 Build-Essential yes\n"), OverrideExtra)
+	c.Assert(err, IsNil)
+	c.Check(extra, DeepEquals, map[string]map[string]string{
+		"aptly": {"Task": "server, desktop"},
+		"libc6": {"Build-Essential": "yes"},
+	})
+
+	_, err = ParseOverrides(strings.NewReader("aptly optional\n"), OverrideBinary)
+	c.Check(err, ErrorMatches, "line 1: expected 'package priority section'.*")
+
+	_, err = ParseOverrides(strings.NewReader("aptly\n"), OverrideSource)
+	c.Check(err, ErrorMatches, "line 1: expected 'package section'.*")
+
+	_, err = ParseOverrides(strings.NewReader("aptly optional\n"), "other")
+	c.Check(err, ErrorMatches, "unknown override type: other")
+}
+
+func (s *PackageOverridesSuite) TestApply(c *C) {
+	o := NewPackageOverrides()
+	o.Set(OverrideBinary, map[string]map[string]string{"aptly": {"Priority": "optional", "Section": "utils"}})
+	o.Set(OverrideSource, map[string]map[string]string{"aptly": {"Section": "devel"}})
+	o.Set(OverrideExtra, map[string]map[string]string{"aptly": {"Task": "server"}})
+
+	c.Check(o.Len(), Equals, 3)
+
+	binary := &Package{Name: "aptly"}
+	stanza := Stanza{"Package": "aptly", "Section": "misc"}
+	o.Apply(binary, stanza)
+	c.Check(stanza, DeepEquals, Stanza{"Package": "aptly", "Section": "utils", "Priority": "optional", "Task": "server"})
+
+	source := &Package{Name: "aptly", IsSource: true}
+	stanza = Stanza{"Package": "aptly", "Section": "misc", "Priority": "extra"}
+	o.Apply(source, stanza)
+	c.Check(stanza, DeepEquals, Stanza{"Package": "aptly", "Section": "devel", "Priority": "extra"})
+
+	other := &Package{Name: "other"}
+	stanza = Stanza{"Package": "other", "Section": "misc"}
+	o.Apply(other, stanza)
+	c.Check(stanza, DeepEquals, Stanza{"Package": "other", "Section": "misc"})
+
+	var nilOverrides *PackageOverrides
+	nilOverrides.Apply(other, stanza)
+	c.Check(nilOverrides.Len(), Equals, 0)
+}
+
+func (s *PackageOverridesSuite) TestLines(c *C) {
+	o := NewPackageOverrides()
+	o.Set(OverrideBinary, map[string]map[string]string{"libc6": {"Priority": "required", "Section": "libs"}, "aptly": {"Priority": "optional", "Section": "utils"}})
+	o.Set(OverrideExtra, map[string]map[string]string{"aptly": {"Task": "server", "Build-Essential": "yes"}})
+
+	lines, err := o.Lines(OverrideBinary)
+	c.Assert(err, IsNil)
+	c.Check(lines, DeepEquals, []string{"aptly optional utils", "libc6 required libs"})
+
+	lines, err = o.Lines(OverrideSource)
+	c.Assert(err, IsNil)
+	c.Check(lines, DeepEquals, []string{})
+
+	lines, err = o.Lines(OverrideExtra)
+	c.Assert(err, IsNil)
+	c.Check(lines, DeepEquals, []string{"aptly Build-Essential yes", "aptly Task server"})
+}
@@ -37,6 +37,7 @@ type Package struct {
 	V06Plus bool
 	// Offload fields
 	deps     *PackageDependencies
+	provided []Dependency
 	extra    *Stanza
 	files    *PackageFiles
 	contents []string
@@ -115,6 +116,7 @@ func NewPackageFromControlFile(input Stanza) *Package {
 	result.deps = depends
 
 	result.Provides = parseDependencies(input, "Provides")
+	result.provided = parseProvidesList(result.Provides)
 
 	result.extra = &input
 
@@ -316,24 +318,89 @@ func (p *Package) MatchesArchitecture(arch string) bool {
 	return p.Architecture == arch
 }
 
+// ProvidedPackages returns virtual packages provided by the package, unversioned provides
+// have VersionDontCare relation, versioned provides (`foo (= 1.2)`) have VersionEqual relation
+func (p *Package) ProvidedPackages() []Dependency {
+	if p.provided == nil {
+		p.provided = parseProvidesList(p.Provides)
+	}
+
+	return p.provided
+}
+
+// parseProvidesList parses all entries of Provides field
+func parseProvidesList(provides []string) []Dependency {
+	if len(provides) == 0 {
+		return nil
+	}
+
+	result := make([]Dependency, 0, len(provides))
+	for _, entry := range provides {
+		result = append(result, parseProvides(entry))
+	}
+
+	return result
+}
+
+// parseProvides parses single entry of Provides field
+func parseProvides(provides string) Dependency {
+	dep, err := ParseDependency(provides)
+	if err != nil {
+		// keep broken entry as unversioned provides, that's how it used to work
+		dep = Dependency{Pkg: provides, Relation: VersionDontCare}
+	}
+
+	return dep
+}
+
+// ProvidedNames returns names of virtual packages provided by the package
+func (p *Package) ProvidedNames() []string {
+	provided := p.ProvidedPackages()
+	if len(provided) == 0 {
+		return nil
+	}
+
+	result := make([]string, 0, len(provided))
+	for _, dep := range provided {
+		result = append(result, dep.Pkg)
+	}
+
+	return result
+}
+
 // MatchesDependency checks whether package matches specified dependency
+//
+// Versioned dependency could be satisfied by versioned provides (Debian Policy 7.5),
+// unversioned provides satisfy only unversioned dependencies
 func (p *Package) MatchesDependency(dep Dependency) bool {
 	if dep.Architecture != "" && !p.MatchesArchitecture(dep.Architecture) {
 		return false
 	}
 
-	if dep.Relation == VersionDontCare {
-		if utils.StrSliceHasItem(p.Provides, dep.Pkg) {
+	if dep.Pkg == p.Name && (dep.Relation == VersionDontCare || matchesVersion(p.Version, dep)) {
+		return true
+	}
+
+	for _, provided := range p.ProvidedPackages() {
+		if provided.Pkg != dep.Pkg {
+			continue
+		}
+
+		if dep.Relation == VersionDontCare {
 			return true
 		}
-		return dep.Pkg == p.Name
-	}
 
-	if dep.Pkg != p.Name {
-		return false
+		if provided.Relation == VersionEqual && matchesVersion(provided.Version, dep) {
+			return true
+		}
 	}
 
-	r := CompareVersions(p.Version, dep.Version)
+	return false
+}
+
+// matchesVersion checks whether version satisfies version relation of the dependency
+func matchesVersion(version string, dep Dependency) bool {
+	r := CompareVersions(version, dep.Version)
 
 	switch dep.Relation {
 	case VersionEqual:
@@ -347,10 +414,10 @@ func (p *Package) MatchesDependency(dep Dependency) bool {
 	case VersionGreaterOrEqual:
 		return r >= 0
 	case VersionPatternMatch:
-		matched, err := filepath.Match(dep.Version, p.Version)
+		matched, err := filepath.Match(dep.Version, version)
 		return err == nil && matched
 	case VersionRegexp:
-		return dep.Regexp.FindStringIndex(p.Version) != nil
+		return dep.Regexp.FindStringIndex(version) != nil
 	}
 
 	panic("unknown relation")
@@ -663,6 +730,8 @@ type PackageDownloadTask struct {
 	Additional   []PackageDownloadTask
 	TempDownPath string
 	Done         bool
+	// URLs of the file in the pools of peer aptly instances, tried before upstream
+	PeerURLs []string
 }
 
 // DownloadList returns list of missing package files for download in format
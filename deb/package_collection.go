@@ -103,6 +103,7 @@ func (collection *PackageCollection) ByKey(key []byte) (*Package, error) {
 		}
 	}
 
+	p.provided = parseProvidesList(p.Provides)
 	p.collection = collection
 
 	return p, nil
@@ -290,7 +291,7 @@ func (collection *PackageCollection) AllPackageRefs() *PackageRefList {
 
 // DeleteByKey deletes package in DB by key
 func (collection *PackageCollection) DeleteByKey(key []byte, dbw database.Writer) error {
-	for _, key := range [][]byte{key, append([]byte("xF"), key...), append([]byte("xD"), key...), append([]byte("xE"), key...)} {
+	for _, key := range [][]byte{key, append([]byte("xF"), key...), append([]byte("xD"), key...), append([]byte("xE"), key...), provenanceKey(key), translationKey(key)} {
 		err := dbw.Delete(key)
 		if err != nil {
 			return err
@@ -333,7 +333,9 @@ func (s *PackageSuite) TestMatchesDependency(c *C) {
 
 	// Provides
 	c.Check(p.MatchesDependency(Dependency{Pkg: "game", Relation: VersionDontCare}), Equals, false)
-	p.Provides = []string{"fun", "game"}
+	stanza := packageStanza.Copy()
+	stanza["Provides"] = "fun, game"
+	p = NewPackageFromControlFile(stanza)
 	c.Check(p.MatchesDependency(Dependency{Pkg: "game", Relation: VersionDontCare}), Equals, true)
 	c.Check(p.MatchesDependency(Dependency{Pkg: "game", Architecture: "amd64", Relation: VersionDontCare}), Equals, false)
 }
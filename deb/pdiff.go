@@ -0,0 +1,349 @@
+package deb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aptly-dev/aptly/utils"
+)
+
+// PDiffEntry is single file listed in pdiff Index (Packages.diff/Index)
+type PDiffEntry struct {
+	Name     string
+	Checksum utils.ChecksumInfo
+}
+
+// PDiffIndex is parsed pdiff Index file (Packages.diff/Index)
+//
+// History lists checksums of index states patches are applied to, Patches lists
+// checksums of uncompressed patches and Download checksums of compressed patches
+type PDiffIndex struct {
+	Current  utils.ChecksumInfo
+	History  []PDiffEntry
+	Patches  []PDiffEntry
+	Download []PDiffEntry
+	// Merged is true if every patch brings index directly to current state
+	Merged bool
+}
+
+// ParsePDiffIndex parses pdiff Index file, SHA256 checksums are preferred over SHA1
+func ParsePDiffIndex(r io.Reader) (*PDiffIndex, error) {
+	fields := map[string][]string{}
+
+	scanner := bufio.NewScanner(r)
+	lastField := ""
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if line[0] == ' ' || line[0] == '\t' {
+			if lastField == "" {
+				return nil, ErrMalformedStanza
+			}
+			fields[lastField] = append(fields[lastField], strings.TrimSpace(line))
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return nil, ErrMalformedStanza
+		}
+
+		lastField = strings.ToUpper(strings.TrimSpace(parts[0]))
+		if value := strings.TrimSpace(parts[1]); value != "" {
+			fields[lastField] = append(fields[lastField], value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	hash := "SHA256"
+	if len(fields["SHA256-CURRENT"]) == 0 {
+		hash = "SHA1"
+	}
+
+	current := fields[hash+"-CURRENT"]
+	if len(current) != 1 {
+		return nil, fmt.Errorf("pdiff index: missing %s-Current", hash)
+	}
+
+	index := &PDiffIndex{
+		Merged: strings.EqualFold(strings.Join(fields["X-PATCH-PRECEDENCE"], ""), "merged"),
+	}
+
+	entry, err := parsePDiffEntry(hash, current[0], false)
+	if err != nil {
+		return nil, err
+	}
+	index.Current = entry.Checksum
+
+	for _, list := range []struct {
+		field  string
+		result *[]PDiffEntry
+	}{
+		{hash + "-HISTORY", &index.History},
+		{hash + "-PATCHES", &index.Patches},
+		{hash + "-DOWNLOAD", &index.Download},
+	} {
+		for _, line := range fields[list.field] {
+			entry, err = parsePDiffEntry(hash, line, true)
+			if err != nil {
+				return nil, err
+			}
+			*list.result = append(*list.result, entry)
+		}
+	}
+
+	return index, nil
+}
+
+func parsePDiffEntry(hash, line string, withName bool) (entry PDiffEntry, err error) {
+	parts := strings.Fields(line)
+	if withName && len(parts) != 3 || !withName && len(parts) != 2 {
+		err = fmt.Errorf("pdiff index: malformed line: %s", line)
+		return
+	}
+
+	entry.Checksum.Size, err = strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		err = fmt.Errorf("pdiff index: malformed size in line: %s", line)
+		return
+	}
+
+	if hash == "SHA256" {
+		entry.Checksum.SHA256 = parts[0]
+	} else {
+		entry.Checksum.SHA1 = parts[0]
+	}
+
+	if withName {
+		entry.Name = parts[2]
+	}
+
+	return
+}
+
+func findPDiffEntry(entries []PDiffEntry, name string) *PDiffEntry {
+	for i := range entries {
+		if entries[i].Name == name {
+			return &entries[i]
+		}
+	}
+
+	return nil
+}
+
+// PDiffChecksumMatches checks actual checksum against checksum from pdiff Index
+func PDiffChecksumMatches(expected, actual utils.ChecksumInfo) bool {
+	if expected.Size != actual.Size {
+		return false
+	}
+
+	if expected.SHA256 != "" {
+		return expected.SHA256 == actual.SHA256
+	}
+
+	return expected.SHA1 == actual.SHA1
+}
+
+// PatchesFor returns list of patches to apply to bring index with specified checksum
+// to the current state, nil is returned if index is already current
+func (index *PDiffIndex) PatchesFor(checksum utils.ChecksumInfo) ([]PDiffEntry, error) {
+	if PDiffChecksumMatches(index.Current, checksum) {
+		return nil, nil
+	}
+
+	start := -1
+	for i := range index.History {
+		if PDiffChecksumMatches(index.History[i].Checksum, checksum) {
+			start = i
+		}
+	}
+
+	if start == -1 {
+		return nil, fmt.Errorf("local index is not listed in pdiff history")
+	}
+
+	history := index.History[start:]
+	if index.Merged {
+		history = history[:1]
+	}
+
+	result := make([]PDiffEntry, 0, len(history))
+	for _, state := range history {
+		patch := findPDiffEntry(index.Patches, state.Name)
+		if patch == nil {
+			return nil, fmt.Errorf("patch %s is missing from pdiff index", state.Name)
+		}
+
+		result = append(result, *patch)
+	}
+
+	return result, nil
+}
+
+// DownloadChecksum returns checksum of compressed patch (if listed)
+func (index *PDiffIndex) DownloadChecksum(patch PDiffEntry) *utils.ChecksumInfo {
+	entry := findPDiffEntry(index.Download, patch.Name+".gz")
+	if entry == nil {
+		return nil
+	}
+
+	return &entry.Checksum
+}
+
+// edCommand is single command of 'diff --ed' patch
+type edCommand struct {
+	op       byte
+	from, to int
+	text     []string
+}
+
+// ApplyEdPatch applies patch in 'diff --ed' format (as used by pdiffs) to src,
+// writing result to dst
+//
+// Only the patch is kept in memory, src is streamed line by line. Commands
+// in the patch should come in descending order (as generated by diff --ed).
+func ApplyEdPatch(dst io.Writer, src io.Reader, patch io.Reader) error {
+	commands, err := parseEdPatch(patch)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(nil, 16*1024*1024)
+
+	w := bufio.NewWriter(dst)
+	current := 0
+
+	// copyUpTo copies source lines until line number n is reached
+	copyUpTo := func(n int, skip bool) bool {
+		for current < n {
+			if !scanner.Scan() {
+				return false
+			}
+			current++
+			if !skip {
+				w.WriteString(scanner.Text())
+				w.WriteByte('\n')
+			}
+		}
+		return true
+	}
+
+	// commands are listed bottom-up, so apply them in reverse
+	for i := len(commands) - 1; i >= 0; i-- {
+		command := commands[i]
+
+		switch command.op {
+		case 'a':
+			if command.from < current {
+				return fmt.Errorf("ed patch: commands are not in descending order")
+			}
+			if !copyUpTo(command.from, false) {
+				return fmt.Errorf("ed patch: line %d out of range", command.from)
+			}
+		case 'c', 'd':
+			if command.from < 1 || command.to < command.from {
+				return fmt.Errorf("ed patch: range %d,%d out of range", command.from, command.to)
+			}
+			if command.from-1 < current {
+				return fmt.Errorf("ed patch: commands are not in descending order")
+			}
+			if !copyUpTo(command.from-1, false) || !copyUpTo(command.to, true) {
+				return fmt.Errorf("ed patch: range %d,%d out of range", command.from, command.to)
+			}
+		}
+
+		for _, line := range command.text {
+			w.WriteString(line)
+			w.WriteByte('\n')
+		}
+	}
+
+	for scanner.Scan() {
+		w.WriteString(scanner.Text())
+		w.WriteByte('\n')
+	}
+
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+// parseEdPatch parses commands of 'diff --ed' patch
+func parseEdPatch(patch io.Reader) ([]edCommand, error) {
+	var commands []edCommand
+
+	scanner := bufio.NewScanner(patch)
+	scanner.Buffer(nil, 16*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		command := edCommand{op: line[len(line)-1]}
+
+		var err error
+		command.from, command.to, err = parseEdRange(line[:len(line)-1])
+		if err != nil {
+			return nil, err
+		}
+
+		switch command.op {
+		case 'a', 'c':
+			terminated := false
+			for scanner.Scan() {
+				if scanner.Text() == "." {
+					terminated = true
+					break
+				}
+				command.text = append(command.text, scanner.Text())
+			}
+			if !terminated {
+				return nil, fmt.Errorf("ed patch: unterminated text for command %s", line)
+			}
+		case 'd':
+		default:
+			return nil, fmt.Errorf("ed patch: unsupported command %s", line)
+		}
+
+		commands = append(commands, command)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return commands, nil
+}
+
+func parseEdRange(spec string) (from, to int, err error) {
+	parts := strings.SplitN(spec, ",", 2)
+
+	from, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("ed patch: malformed command: %s", spec)
+	}
+
+	to = from
+	if len(parts) == 2 {
+		to, err = strconv.Atoi(parts[1])
+		if err != nil {
+			return 0, 0, fmt.Errorf("ed patch: malformed command: %s", spec)
+		}
+	}
+
+	return
+}
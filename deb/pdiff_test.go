@@ -0,0 +1,110 @@
+package deb
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/aptly-dev/aptly/utils"
+
+	. "gopkg.in/check.v1"
+)
+
+type PDiffSuite struct{}
+
+var _ = Suite(&PDiffSuite{})
+
+const examplePDiffIndex = `SHA256-Current: 0c2a 300
+SHA256-History:
+ aaaa 100 2024-01-01-0000.00
+ bbbb 200 2024-01-02-0000.00
+SHA256-Patches:
+ 1111 10 2024-01-01-0000.00
+ 2222 20 2024-01-02-0000.00
+SHA256-Download:
+ 3333 5 2024-01-01-0000.00.gz
+ 4444 6 2024-01-02-0000.00.gz
+`
+
+func (s *PDiffSuite) TestParsePDiffIndex(c *C) {
+	index, err := ParsePDiffIndex(strings.NewReader(examplePDiffIndex))
+	c.Assert(err, IsNil)
+	c.Check(index.Current, DeepEquals, utils.ChecksumInfo{SHA256: "0c2a", Size: 300})
+	c.Check(index.History, HasLen, 2)
+	c.Check(index.Patches[1], DeepEquals, PDiffEntry{Name: "2024-01-02-0000.00", Checksum: utils.ChecksumInfo{SHA256: "2222", Size: 20}})
+	c.Check(index.Merged, Equals, false)
+	c.Check(index.DownloadChecksum(index.Patches[0]), DeepEquals, &utils.ChecksumInfo{SHA256: "3333", Size: 5})
+
+	index, err = ParsePDiffIndex(strings.NewReader("SHA1-Current: abcd 10\nX-Patch-Precedence: merged\nSHA1-History:\n ef01 5 T-1\n"))
+	c.Assert(err, IsNil)
+	c.Check(index.Current, DeepEquals, utils.ChecksumInfo{SHA1: "abcd", Size: 10})
+	c.Check(index.Merged, Equals, true)
+	c.Check(index.DownloadChecksum(PDiffEntry{Name: "T-1"}), IsNil)
+
+	_, err = ParsePDiffIndex(strings.NewReader("SHA256-History:\n aaaa 100 x\n"))
+	c.Check(err, ErrorMatches, "pdiff index: missing SHA1-Current")
+
+	_, err = ParsePDiffIndex(strings.NewReader("SHA256-Current: 0c2a 300\nSHA256-History:\n aaaa x\n"))
+	c.Check(err, ErrorMatches, "pdiff index: malformed line: aaaa x")
+}
+
+func (s *PDiffSuite) TestPatchesFor(c *C) {
+	index, _ := ParsePDiffIndex(strings.NewReader(examplePDiffIndex))
+
+	patches, err := index.PatchesFor(utils.ChecksumInfo{SHA256: "0c2a", Size: 300})
+	c.Assert(err, IsNil)
+	c.Check(patches, HasLen, 0)
+
+	patches, err = index.PatchesFor(utils.ChecksumInfo{SHA256: "aaaa", Size: 100})
+	c.Assert(err, IsNil)
+	c.Check(patches, DeepEquals, index.Patches)
+
+	patches, err = index.PatchesFor(utils.ChecksumInfo{SHA256: "bbbb", Size: 200})
+	c.Assert(err, IsNil)
+	c.Check(patches, DeepEquals, index.Patches[1:])
+
+	_, err = index.PatchesFor(utils.ChecksumInfo{SHA256: "bbbb", Size: 201})
+	c.Check(err, ErrorMatches, "local index is not listed in pdiff history")
+
+	index.Merged = true
+	patches, err = index.PatchesFor(utils.ChecksumInfo{SHA256: "aaaa", Size: 100})
+	c.Assert(err, IsNil)
+	c.Check(patches, DeepEquals, index.Patches[:1])
+
+	index.Patches = index.Patches[1:]
+	_, err = index.PatchesFor(utils.ChecksumInfo{SHA256: "aaaa", Size: 100})
+	c.Check(err, ErrorMatches, "patch 2024-01-01-0000.00 is missing from pdiff index")
+}
+
+func (s *PDiffSuite) TestApplyEdPatch(c *C) {
+	apply := func(src, patch string) (string, error) {
+		var buf bytes.Buffer
+		err := ApplyEdPatch(&buf, strings.NewReader(src), strings.NewReader(patch))
+		return buf.String(), err
+	}
+
+	result, err := apply("a\nb\nc\nd\ne\n", "5a\nf\ng\n.\n3,4c\nC\n.\n1d\n0a\nstart\n.\n")
+	c.Assert(err, IsNil)
+	c.Check(result, Equals, "start\nb\nC\ne\nf\ng\n")
+
+	result, err = apply("a\nb\n", "")
+	c.Assert(err, IsNil)
+	c.Check(result, Equals, "a\nb\n")
+
+	_, err = apply("a\n", "2,3d\n")
+	c.Check(err, ErrorMatches, "ed patch: range 2,3 out of range")
+
+	_, err = apply("a\n", "3a\nb\n.\n")
+	c.Check(err, ErrorMatches, "ed patch: line 3 out of range")
+
+	_, err = apply("a\nb\nc\n", "1d\n3d\n")
+	c.Check(err, ErrorMatches, "ed patch: commands are not in descending order")
+
+	_, err = apply("a\n", "1a\nb\n")
+	c.Check(err, ErrorMatches, "ed patch: unterminated text for command 1a")
+
+	_, err = apply("a\n", "s/.//\n")
+	c.Check(err, ErrorMatches, "ed patch: malformed command: s/./")
+
+	_, err = apply("a\n", "1x\n")
+	c.Check(err, ErrorMatches, "ed patch: unsupported command 1x")
+}
@@ -0,0 +1,144 @@
+package deb
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aptly-dev/aptly/aptly"
+)
+
+// PoolMigrateReport is a result of package pool migration from legacy layout
+type PoolMigrateReport struct {
+	// Number of package files found at legacy (MD5-based) paths
+	Files int
+	// Number of package files moved to SHA256-based paths
+	Migrated int
+	// Number of legacy files removed from the pool
+	Removed int
+	// Files which failed to migrate
+	Failed []string
+}
+
+// MigrateLegacyPoolFiles moves package files stored at legacy (MD5-based) pool paths to
+// SHA256-based paths, updating package file records and checksums
+//
+// Every package is updated in its own transaction, so migration could be interrupted and
+// resumed later. Legacy files are removed once all the packages are updated, leftovers of
+// interrupted migration are not referenced anymore and would be removed by db cleanup.
+//
+// With dryRun, files at legacy paths are only counted.
+func MigrateLegacyPoolFiles(refs *PackageRefList, packageCollection *PackageCollection, packagePool aptly.PackagePool,
+	dryRun bool, progress aptly.Progress) (*PoolMigrateReport, error) {
+	localPool, ok := packagePool.(aptly.LocalPackagePool)
+	if !ok {
+		return nil, fmt.Errorf("package pool doesn't support migration")
+	}
+
+	report := &PoolMigrateReport{Failed: []string{}}
+
+	// legacy path -> whether legacy file is still in use
+	legacyPaths := map[string]bool{}
+
+	if progress != nil {
+		progress.InitBar(int64(refs.Len()), false)
+	}
+
+	err := refs.ForEach(func(key []byte) error {
+		if progress != nil {
+			progress.AddBar(1)
+		}
+
+		p, err := packageCollection.ByKey(key)
+		if err != nil {
+			return fmt.Errorf("unable to load package %s: %s", key, err)
+		}
+
+		transaction, err := packageCollection.db.OpenTransaction()
+		if err != nil {
+			return err
+		}
+		defer transaction.Discard()
+
+		checksumStorage := NewChecksumCollection(transaction)
+
+		files := p.Files()
+		updated := false
+
+		for i := range files {
+			legacyPath, e := packagePool.LegacyPath(files[i].Filename, &files[i].Checksums)
+			if files[i].PoolPath != "" && (e != nil || files[i].PoolPath != legacyPath) {
+				// file is already at SHA256-based path
+				continue
+			}
+
+			report.Files++
+
+			var poolPath string
+			if e == nil && !dryRun {
+				// checksums in package metadata are part of package key, so they are kept as is
+				checksums := files[i].Checksums
+				poolPath, e = localPool.MigrateLegacyFile(legacyPath, files[i].Filename, &checksums, checksumStorage)
+			}
+
+			if e != nil {
+				report.Failed = append(report.Failed, fmt.Sprintf("%s: %s: %s", p, files[i].Filename, e))
+				if legacyPath != "" {
+					legacyPaths[legacyPath] = true
+				}
+				continue
+			}
+
+			if dryRun {
+				continue
+			}
+
+			if _, inUse := legacyPaths[legacyPath]; !inUse {
+				legacyPaths[legacyPath] = false
+			}
+
+			files[i].PoolPath = poolPath
+			report.Migrated++
+			updated = true
+		}
+
+		if !updated {
+			return nil
+		}
+
+		p.UpdateFiles(files)
+
+		err = packageCollection.UpdateInTransaction(p, transaction)
+		if err != nil {
+			return fmt.Errorf("unable to save package %s: %s", p, err)
+		}
+
+		return transaction.Commit()
+	})
+
+	if progress != nil {
+		progress.ShutdownBar()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for legacyPath, inUse := range legacyPaths {
+		if inUse {
+			continue
+		}
+
+		_, err = packagePool.Remove(legacyPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// migration has been resumed, file was removed already
+				continue
+			}
+			return nil, fmt.Errorf("unable to remove %s: %s", legacyPath, err)
+		}
+
+		report.Removed++
+	}
+
+	return report, nil
+}
@@ -0,0 +1,118 @@
+package deb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/aptly-dev/aptly/database"
+	"github.com/aptly-dev/aptly/database/goleveldb"
+	"github.com/aptly-dev/aptly/files"
+	"github.com/aptly-dev/aptly/utils"
+
+	. "gopkg.in/check.v1"
+)
+
+type PoolMigrateSuite struct {
+	db                database.Storage
+	collectionFactory *CollectionFactory
+	packagePool       *files.PackagePool
+	p                 *Package
+	refs              *PackageRefList
+	legacyPath        string
+}
+
+var _ = Suite(&PoolMigrateSuite{})
+
+func (s *PoolMigrateSuite) SetUpTest(c *C) {
+	s.db, _ = goleveldb.NewOpenDB(c.MkDir())
+	s.collectionFactory = NewCollectionFactory(s.db)
+	s.packagePool = files.NewPackagePool(c.MkDir(), true)
+
+	s.p = NewPackageFromControlFile(packageStanza.Copy())
+
+	tmpFile := filepath.Join(c.MkDir(), s.p.Files()[0].Filename)
+	c.Assert(ioutil.WriteFile(tmpFile, []byte("package contents"), 0644), IsNil)
+
+	checksums, err := utils.ChecksumsForFile(tmpFile)
+	c.Assert(err, IsNil)
+
+	// place file at legacy path, as pre-1.1 aptly did, without SHA256 in package metadata
+	files := s.p.Files()
+	files[0].Checksums = utils.ChecksumInfo{Size: checksums.Size, MD5: checksums.MD5}
+	s.legacyPath, err = s.packagePool.LegacyPath(files[0].Filename, &files[0].Checksums)
+	c.Assert(err, IsNil)
+	c.Assert(os.MkdirAll(filepath.Dir(s.packagePool.FullPath(s.legacyPath)), 0777), IsNil)
+	c.Assert(utils.CopyFile(tmpFile, s.packagePool.FullPath(s.legacyPath)), IsNil)
+	s.p.UpdateFiles(files)
+
+	c.Assert(s.collectionFactory.PackageCollection().Update(s.p), IsNil)
+
+	list := NewPackageList()
+	list.Add(s.p)
+	s.refs = NewPackageRefListFromPackageList(list)
+}
+
+func (s *PoolMigrateSuite) TearDownTest(c *C) {
+	s.db.Close()
+}
+
+func (s *PoolMigrateSuite) TestDryRun(c *C) {
+	report, err := MigrateLegacyPoolFiles(s.refs, s.collectionFactory.PackageCollection(), s.packagePool, true, nil)
+	c.Assert(err, IsNil)
+	c.Check(report.Files, Equals, 1)
+	c.Check(report.Migrated, Equals, 0)
+	c.Check(report.Removed, Equals, 0)
+
+	_, err = s.packagePool.Stat(s.legacyPath)
+	c.Check(err, IsNil)
+}
+
+func (s *PoolMigrateSuite) TestMigrate(c *C) {
+	report, err := MigrateLegacyPoolFiles(s.refs, s.collectionFactory.PackageCollection(), s.packagePool, false, nil)
+	c.Assert(err, IsNil)
+	c.Check(report.Files, Equals, 1)
+	c.Check(report.Migrated, Equals, 1)
+	c.Check(report.Removed, Equals, 1)
+	c.Check(report.Failed, HasLen, 0)
+
+	_, err = s.packagePool.Stat(s.legacyPath)
+	c.Check(os.IsNotExist(err), Equals, true)
+
+	p, err := s.collectionFactory.PackageCollection().ByKey(s.p.Key(""))
+	c.Assert(err, IsNil)
+
+	// package key is not changed
+	file := p.Files()[0]
+	c.Check(file.Checksums, DeepEquals, s.p.Files()[0].Checksums)
+
+	stored, err := s.collectionFactory.ChecksumCollection(nil).Get(file.PoolPath)
+	c.Assert(err, IsNil)
+	c.Assert(stored, NotNil)
+	c.Check(stored.MD5, Equals, file.Checksums.MD5)
+	c.Check(file.PoolPath, Equals, stored.SHA256[0:2]+"/"+stored.SHA256[2:4]+"/"+stored.SHA256[4:32]+"_"+file.Filename)
+
+	verifyReport, err := VerifyPoolFiles(s.refs, s.collectionFactory.PackageCollection(), s.packagePool,
+		s.collectionFactory.ChecksumCollection(nil), 1, nil)
+	c.Assert(err, IsNil)
+	c.Check(verifyReport.Problems, HasLen, 0)
+
+	// nothing left to migrate
+	report2, err := MigrateLegacyPoolFiles(s.refs, s.collectionFactory.PackageCollection(), s.packagePool, false, nil)
+	c.Assert(err, IsNil)
+	c.Check(report2.Files, Equals, 0)
+}
+
+func (s *PoolMigrateSuite) TestMigrateMissing(c *C) {
+	c.Assert(os.Remove(s.packagePool.FullPath(s.legacyPath)), IsNil)
+
+	report, err := MigrateLegacyPoolFiles(s.refs, s.collectionFactory.PackageCollection(), s.packagePool, false, nil)
+	c.Assert(err, IsNil)
+	c.Check(report.Files, Equals, 1)
+	c.Check(report.Migrated, Equals, 0)
+	c.Check(report.Failed, HasLen, 1)
+
+	p, err := s.collectionFactory.PackageCollection().ByKey(s.p.Key(""))
+	c.Assert(err, IsNil)
+	c.Check(p.Files()[0].PoolPath, Equals, "")
+}
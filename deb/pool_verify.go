@@ -0,0 +1,349 @@
+package deb
+
+import (
+	gocontext "context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/aptly-dev/aptly/aptly"
+	"github.com/aptly-dev/aptly/utils"
+)
+
+// Kinds of pool file problems
+const (
+	PoolFileMissing = "missing"
+	PoolFileCorrupt = "corrupt"
+)
+
+// PoolFileProblem is a package file which is missing from the package pool or doesn't match checksums
+type PoolFileProblem struct {
+	// Package key and human-readable name
+	PackageKey string
+	Package    string
+	// File as recorded in package metadata
+	File PackageFile
+	// Path to the file in the pool
+	PoolPath string
+	// Kind of problem (PoolFileMissing or PoolFileCorrupt) and details
+	Problem string
+	Details string
+	// File has been re-downloaded (see RepairPoolFile)
+	Repaired bool
+}
+
+// String returns description of the problem
+func (problem *PoolFileProblem) String() string {
+	return fmt.Sprintf("%s: %s (%s) %s: %s", problem.Package, problem.File.Filename, problem.PoolPath, problem.Problem, problem.Details)
+}
+
+// PoolVerifyReport is a result of package pool verification
+type PoolVerifyReport struct {
+	// Number of package files verified
+	Files int
+	// Missing and corrupt files
+	Problems []*PoolFileProblem
+}
+
+type poolVerifyTask struct {
+	key  []byte
+	name string
+	file PackageFile
+}
+
+// checksumsMismatch compares actual checksums with expected ones (only the hashes which are set)
+func checksumsMismatch(expected, actual *utils.ChecksumInfo) string {
+	if expected.Size != actual.Size {
+		return fmt.Sprintf("size %d != %d", actual.Size, expected.Size)
+	} else if expected.MD5 != "" && expected.MD5 != actual.MD5 {
+		return fmt.Sprintf("md5 %s != %s", actual.MD5, expected.MD5)
+	} else if expected.SHA1 != "" && expected.SHA1 != actual.SHA1 {
+		return fmt.Sprintf("sha1 %s != %s", actual.SHA1, expected.SHA1)
+	} else if expected.SHA256 != "" && expected.SHA256 != actual.SHA256 {
+		return fmt.Sprintf("sha256 %s != %s", actual.SHA256, expected.SHA256)
+	} else if expected.SHA512 != "" && expected.SHA512 != actual.SHA512 {
+		return fmt.Sprintf("sha512 %s != %s", actual.SHA512, expected.SHA512)
+	}
+
+	return ""
+}
+
+// verifyPoolFile checks single file in the pool, returning nil if file is fine
+func verifyPoolFile(packagePool aptly.PackagePool, checksumStorage aptly.ChecksumStorage, task *poolVerifyTask) (*PoolFileProblem, error) {
+	poolPath, err := task.file.GetPoolPath(packagePool)
+	if err != nil {
+		return nil, err
+	}
+
+	problem := &PoolFileProblem{
+		PackageKey: string(task.key),
+		Package:    task.name,
+		File:       task.file,
+		PoolPath:   poolPath,
+	}
+
+	f, err := packagePool.Open(poolPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			problem.Problem = PoolFileMissing
+			problem.Details = "file doesn't exist"
+			return problem, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	checksummer := utils.NewChecksumWriter()
+	_, err = io.Copy(checksummer, f)
+	if err != nil {
+		return nil, err
+	}
+
+	actual := checksummer.Sum()
+
+	if mismatch := checksumsMismatch(&task.file.Checksums, &actual); mismatch != "" {
+		problem.Problem = PoolFileCorrupt
+		problem.Details = mismatch
+		return problem, nil
+	}
+
+	stored, err := checksumStorage.Get(poolPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if stored != nil {
+		if mismatch := checksumsMismatch(stored, &actual); mismatch != "" {
+			problem.Problem = PoolFileCorrupt
+			problem.Details = "stored checksums mismatch: " + mismatch
+			return problem, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// VerifyPoolFiles verifies that files of all the packages in the list exist in the package pool
+// and match checksums (both recorded in package metadata and in checksum storage)
+//
+// Files are verified in parallel by concurrency workers
+func VerifyPoolFiles(refs *PackageRefList, packageCollection *PackageCollection, packagePool aptly.PackagePool,
+	checksumStorage aptly.ChecksumStorage, concurrency int, progress aptly.Progress) (*PoolVerifyReport, error) {
+	tasks := []poolVerifyTask{}
+
+	err := refs.ForEach(func(key []byte) error {
+		p, err := packageCollection.ByKey(key)
+		if err != nil {
+			return fmt.Errorf("unable to load package %s: %s", key, err)
+		}
+
+		for _, f := range p.Files() {
+			tasks = append(tasks, poolVerifyTask{key: key, name: p.String(), file: f})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	if progress != nil {
+		progress.InitBar(int64(len(tasks)), false)
+		defer progress.ShutdownBar()
+	}
+
+	report := &PoolVerifyReport{Files: len(tasks), Problems: []*PoolFileProblem{}}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+
+	queue := make(chan *poolVerifyTask)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for task := range queue {
+				problem, err := verifyPoolFile(packagePool, checksumStorage, task)
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = fmt.Errorf("unable to verify %s of %s: %s", task.file.Filename, task.name, err)
+				}
+				if problem != nil {
+					report.Problems = append(report.Problems, problem)
+				}
+				mu.Unlock()
+
+				if progress != nil {
+					progress.AddBar(1)
+				}
+			}
+		}()
+	}
+
+	for i := range tasks {
+		queue <- &tasks[i]
+	}
+	close(queue)
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	sort.Slice(report.Problems, func(i, j int) bool {
+		if report.Problems[i].PackageKey == report.Problems[j].PackageKey {
+			return report.Problems[i].File.Filename < report.Problems[j].File.Filename
+		}
+		return report.Problems[i].PackageKey < report.Problems[j].PackageKey
+	})
+
+	return report, nil
+}
+
+// poolFileCandidatePaths returns possible locations of package file in the mirror: metadata doesn't
+// keep original path of the file, so it's guessed based on standard archive layout
+func (repo *RemoteRepo) poolFileCandidatePaths(p *Package, file *PackageFile) []string {
+	result := []string{}
+
+	if dir := p.Extra()["Directory"]; dir != "" {
+		result = append(result, filepath.Join(dir, file.Filename))
+	}
+
+	if repo.IsFlat() {
+		result = append(result, file.Filename)
+	} else if poolDir, err := p.PoolDirectory(); err == nil {
+		for _, component := range repo.Components {
+			result = append(result, filepath.Join("pool", component, poolDir, file.Filename))
+		}
+	}
+
+	return result
+}
+
+// RepairPoolFile re-downloads missing or corrupt file of mirrored package from the mirror (which should
+// be loaded completely), file is verified against checksums from package metadata
+//
+// If package doesn't belong to the mirror, false is returned
+func (repo *RemoteRepo) RepairPoolFile(problem *PoolFileProblem, d aptly.Downloader, packageCollection *PackageCollection,
+	packagePool aptly.PackagePool, checksumStorage aptly.ChecksumStorage) (bool, error) {
+	p, err := packageCollection.ByKey([]byte(problem.PackageKey))
+	if err != nil {
+		return false, err
+	}
+
+	if repo.RefList() == nil || !repo.RefList().Has(p) {
+		return false, nil
+	}
+
+	tempDir, err := ioutil.TempDir(os.TempDir(), "aptly")
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	tempPath := filepath.Join(tempDir, problem.File.Filename)
+	checksums := problem.File.Checksums
+
+	err = fmt.Errorf("no candidate locations")
+	for _, path := range repo.poolFileCandidatePaths(p, &problem.File) {
+		err = d.DownloadWithChecksum(gocontext.TODO(), repo.PackageURL(path).String(), tempPath, &checksums, false)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to download %s from mirror %s: %s", problem.File.Filename, repo.Name, err)
+	}
+
+	if problem.Problem == PoolFileCorrupt {
+		// corrupted file is not moved to trash, otherwise import would bring it back
+		_, err = packagePool.Purge(problem.PoolPath)
+		if err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+	}
+
+	poolPath, err := packagePool.Import(tempPath, problem.File.Filename, &checksums, true, checksumStorage)
+	if err != nil {
+		return false, err
+	}
+
+	if poolPath != problem.PoolPath {
+		// file landed at different location in the pool (e.g. it was at legacy path before), update package
+		files := p.Files()
+		for i := range files {
+			if files[i].Filename == problem.File.Filename {
+				files[i].PoolPath = poolPath
+			}
+		}
+		p.UpdateFiles(files)
+
+		err = packageCollection.Update(p)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	problem.Repaired = true
+
+	return true, nil
+}
+
+// RepairPoolFiles tries to repair each problem by re-downloading the file from mirrors which contain
+// the package, downloaderFor returns downloader for the mirror
+func RepairPoolFiles(problems []*PoolFileProblem, mirrors []*RemoteRepo, downloaderFor func(*RemoteRepo) (aptly.Downloader, error),
+	packageCollection *PackageCollection, packagePool aptly.PackagePool, checksumStorage aptly.ChecksumStorage, progress aptly.Progress) error {
+	downloaders := map[string]aptly.Downloader{}
+
+	for _, problem := range problems {
+		var lastErr error
+
+		for _, repo := range mirrors {
+			d, ok := downloaders[repo.UUID]
+			if !ok {
+				var err error
+
+				d, err = downloaderFor(repo)
+				if err != nil {
+					return err
+				}
+				downloaders[repo.UUID] = d
+			}
+
+			var repaired bool
+			repaired, lastErr = repo.RepairPoolFile(problem, d, packageCollection, packagePool, checksumStorage)
+			if repaired {
+				break
+			}
+		}
+
+		if progress == nil {
+			continue
+		}
+
+		if problem.Repaired {
+			progress.ColoredPrintf("@g[+]@| %s repaired", problem.File.Filename)
+		} else if lastErr != nil {
+			progress.ColoredPrintf("@y[!]@| @!unable to repair %s: %s@|", problem.File.Filename, lastErr)
+		} else {
+			progress.ColoredPrintf("@y[!]@| @!unable to repair %s: package doesn't belong to any mirror@|", problem.File.Filename)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package deb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aptly-dev/aptly/aptly"
+	"github.com/aptly-dev/aptly/database"
+	"github.com/aptly-dev/aptly/database/goleveldb"
+	"github.com/aptly-dev/aptly/files"
+	"github.com/aptly-dev/aptly/http"
+	"github.com/aptly-dev/aptly/utils"
+
+	. "gopkg.in/check.v1"
+)
+
+type PoolVerifySuite struct {
+	db                database.Storage
+	collectionFactory *CollectionFactory
+	packagePool       *files.PackagePool
+	cs                aptly.ChecksumStorage
+	p                 *Package
+	refs              *PackageRefList
+}
+
+var _ = Suite(&PoolVerifySuite{})
+
+const poolVerifyContent = "package contents"
+
+func (s *PoolVerifySuite) SetUpTest(c *C) {
+	s.db, _ = goleveldb.NewOpenDB(c.MkDir())
+	s.collectionFactory = NewCollectionFactory(s.db)
+	s.packagePool = files.NewPackagePool(c.MkDir(), false)
+	s.cs = files.NewMockChecksumStorage()
+
+	s.p = NewPackageFromControlFile(packageStanza.Copy())
+
+	tmpFile := filepath.Join(c.MkDir(), s.p.Files()[0].Filename)
+	c.Assert(ioutil.WriteFile(tmpFile, []byte(poolVerifyContent), 0644), IsNil)
+
+	checksums, err := utils.ChecksumsForFile(tmpFile)
+	c.Assert(err, IsNil)
+
+	files := s.p.Files()
+	files[0].Checksums = checksums
+	files[0].PoolPath, err = s.packagePool.Import(tmpFile, files[0].Filename, &files[0].Checksums, false, s.cs)
+	c.Assert(err, IsNil)
+	s.p.UpdateFiles(files)
+
+	c.Assert(s.collectionFactory.PackageCollection().Update(s.p), IsNil)
+
+	list := NewPackageList()
+	list.Add(s.p)
+	s.refs = NewPackageRefListFromPackageList(list)
+}
+
+func (s *PoolVerifySuite) TearDownTest(c *C) {
+	s.db.Close()
+}
+
+func (s *PoolVerifySuite) verify(c *C) *PoolVerifyReport {
+	report, err := VerifyPoolFiles(s.refs, s.collectionFactory.PackageCollection(), s.packagePool, s.cs, 2, nil)
+	c.Assert(err, IsNil)
+	c.Check(report.Files, Equals, 1)
+
+	return report
+}
+
+func (s *PoolVerifySuite) TestVerifyOK(c *C) {
+	c.Check(s.verify(c).Problems, HasLen, 0)
+}
+
+func (s *PoolVerifySuite) TestVerifyMissing(c *C) {
+	_, err := s.packagePool.Remove(s.p.Files()[0].PoolPath)
+	c.Assert(err, IsNil)
+
+	report := s.verify(c)
+	c.Assert(report.Problems, HasLen, 1)
+	c.Check(report.Problems[0].Problem, Equals, PoolFileMissing)
+	c.Check(report.Problems[0].Package, Equals, "alien-arena-common_7.40-2_i386")
+	c.Check(report.Problems[0].PoolPath, Equals, s.p.Files()[0].PoolPath)
+}
+
+func (s *PoolVerifySuite) TestVerifyCorrupt(c *C) {
+	c.Assert(ioutil.WriteFile(s.packagePool.FullPath(s.p.Files()[0].PoolPath), []byte("package c0ntents"), 0644), IsNil)
+
+	report := s.verify(c)
+	c.Assert(report.Problems, HasLen, 1)
+	c.Check(report.Problems[0].Problem, Equals, PoolFileCorrupt)
+	c.Check(report.Problems[0].Details, Matches, "md5 .*")
+
+	// checksum storage doesn't match file
+	c.Assert(ioutil.WriteFile(s.packagePool.FullPath(s.p.Files()[0].PoolPath), []byte(poolVerifyContent), 0644), IsNil)
+	c.Assert(s.cs.Update(s.p.Files()[0].PoolPath, &utils.ChecksumInfo{Size: 16, MD5: "abcdef"}), IsNil)
+
+	report = s.verify(c)
+	c.Assert(report.Problems, HasLen, 1)
+	c.Check(report.Problems[0].Details, Matches, "stored checksums mismatch: md5 .*")
+}
+
+func (s *PoolVerifySuite) TestRepair(c *C) {
+	c.Assert(ioutil.WriteFile(s.packagePool.FullPath(s.p.Files()[0].PoolPath), []byte("package c0ntents"), 0644), IsNil)
+
+	report := s.verify(c)
+	c.Assert(report.Problems, HasLen, 1)
+
+	other, _ := NewRemoteRepo("other", "http://example.com/other", "squeeze", []string{"main"}, []string{}, false, false, false)
+	other.packageRefs = NewPackageRefList()
+
+	repo, _ := NewRemoteRepo("yandex", "http://mirror.yandex.ru/debian", "squeeze", []string{"main", "contrib"}, []string{}, false, false, false)
+	repo.packageRefs = s.refs
+
+	d := http.NewFakeDownloader().
+		ExpectError("http://mirror.yandex.ru/debian/pool/main/a/alien-arena/alien-arena-common_7.40-2_i386.deb", &http.Error{Code: 404}).
+		ExpectResponse("http://mirror.yandex.ru/debian/pool/contrib/a/alien-arena/alien-arena-common_7.40-2_i386.deb", poolVerifyContent)
+
+	err := RepairPoolFiles(report.Problems, []*RemoteRepo{other, repo}, func(*RemoteRepo) (aptly.Downloader, error) { return d, nil },
+		s.collectionFactory.PackageCollection(), s.packagePool, s.cs, nil)
+	c.Assert(err, IsNil)
+	c.Check(d.Empty(), Equals, true)
+	c.Check(report.Problems[0].Repaired, Equals, true)
+
+	c.Check(s.verify(c).Problems, HasLen, 0)
+
+	// package not in any mirror
+	_, err = s.packagePool.Remove(s.p.Files()[0].PoolPath)
+	c.Assert(err, IsNil)
+
+	report = s.verify(c)
+	c.Assert(report.Problems, HasLen, 1)
+
+	err = RepairPoolFiles(report.Problems, []*RemoteRepo{other}, func(*RemoteRepo) (aptly.Downloader, error) { return d, nil },
+		s.collectionFactory.PackageCollection(), s.packagePool, s.cs, nil)
+	c.Assert(err, IsNil)
+	c.Check(report.Problems[0].Repaired, Equals, false)
+
+	_, err = os.Stat(s.packagePool.FullPath(s.p.Files()[0].PoolPath))
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
+func (s *PoolVerifySuite) TestRepairWithGracePeriod(c *C) {
+	s.packagePool.SetGracePeriod(time.Hour)
+
+	// corrupted file of the same size, so it can't be told apart from the good one by stat
+	c.Assert(ioutil.WriteFile(s.packagePool.FullPath(s.p.Files()[0].PoolPath), []byte("package c0ntents"), 0644), IsNil)
+
+	report := s.verify(c)
+	c.Assert(report.Problems, HasLen, 1)
+
+	repo, _ := NewRemoteRepo("yandex", "http://mirror.yandex.ru/debian", "squeeze", []string{"main"}, []string{}, false, false, false)
+	repo.packageRefs = s.refs
+
+	d := http.NewFakeDownloader().
+		ExpectResponse("http://mirror.yandex.ru/debian/pool/main/a/alien-arena/alien-arena-common_7.40-2_i386.deb", poolVerifyContent)
+
+	err := RepairPoolFiles(report.Problems, []*RemoteRepo{repo}, func(*RemoteRepo) (aptly.Downloader, error) { return d, nil },
+		s.collectionFactory.PackageCollection(), s.packagePool, s.cs, nil)
+	c.Assert(err, IsNil)
+	c.Check(d.Empty(), Equals, true)
+	c.Check(report.Problems[0].Repaired, Equals, true)
+
+	c.Check(s.verify(c).Problems, HasLen, 0)
+
+	content, err := ioutil.ReadFile(s.packagePool.FullPath(s.p.Files()[0].PoolPath))
+	c.Assert(err, IsNil)
+	c.Check(string(content), Equals, poolVerifyContent)
+}
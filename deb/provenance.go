@@ -0,0 +1,146 @@
+package deb
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aptly-dev/aptly/database"
+	"github.com/ugorji/go/codec"
+)
+
+// Ways package could be introduced into aptly
+const (
+	ProvenanceMirrorUpdate = "mirror update"
+	ProvenanceRepoAdd      = "repo add"
+	ProvenanceRepoInclude  = "repo include"
+)
+
+// PackageProvenance records where package has come from: the first mirror or local repo
+// package has been added to
+type PackageProvenance struct {
+	// How package has been introduced (ProvenanceMirrorUpdate, ...)
+	Action string
+	// UUID and name (at the time) of mirror or local repo
+	OriginUUID string
+	Origin     string
+	// Time package has been introduced
+	Date time.Time
+	// Authenticated API user who has uploaded the package
+	User string `json:",omitempty"`
+	// Name of .changes file and keys it has been signed with (for included packages)
+	ChangesFile   string   `json:",omitempty"`
+	SignatureKeys []string `json:",omitempty"`
+}
+
+// NewMirrorProvenance creates provenance for packages downloaded by mirror update
+func NewMirrorProvenance(repo *RemoteRepo) *PackageProvenance {
+	return &PackageProvenance{
+		Action:     ProvenanceMirrorUpdate,
+		OriginUUID: repo.UUID,
+		Origin:     repo.Name,
+		Date:       time.Now(),
+	}
+}
+
+// NewLocalRepoProvenance creates provenance for packages added to local repo
+func NewLocalRepoProvenance(repo *LocalRepo, user string) *PackageProvenance {
+	return &PackageProvenance{
+		Action:     ProvenanceRepoAdd,
+		OriginUUID: repo.UUID,
+		Origin:     repo.Name,
+		Date:       time.Now(),
+		User:       user,
+	}
+}
+
+// NewChangesProvenance creates provenance for packages included into local repo from .changes file
+func NewChangesProvenance(repo *LocalRepo, changes *Changes) *PackageProvenance {
+	provenance := &PackageProvenance{
+		Action:        ProvenanceRepoInclude,
+		OriginUUID:    repo.UUID,
+		Origin:        repo.Name,
+		Date:          time.Now(),
+		ChangesFile:   changes.ChangesName,
+		SignatureKeys: []string{},
+	}
+
+	for _, key := range changes.SignatureKeys {
+		provenance.SignatureKeys = append(provenance.SignatureKeys, string(key))
+	}
+
+	return provenance
+}
+
+// String returns human-readable description of provenance
+func (provenance *PackageProvenance) String() string {
+	result := fmt.Sprintf("%s %s at %s", provenance.Action, provenance.Origin, provenance.Date.Format(time.RFC3339))
+	if provenance.User != "" {
+		result += fmt.Sprintf(" by %s", provenance.User)
+	}
+	if provenance.ChangesFile != "" {
+		result += fmt.Sprintf(" from %s", provenance.ChangesFile)
+		if len(provenance.SignatureKeys) > 0 {
+			result += fmt.Sprintf(" signed by %s", strings.Join(provenance.SignatureKeys, ", "))
+		} else {
+			result += " (unsigned)"
+		}
+	}
+
+	return result
+}
+
+// provenanceKey is a DB key of package provenance
+func provenanceKey(key []byte) []byte {
+	return append([]byte("xO"), key...)
+}
+
+// Provenance loads provenance of the package by package key, nil is returned if provenance
+// hasn't been recorded (e.g. package has been added before provenance tracking was introduced)
+func (collection *PackageCollection) Provenance(key []byte) (*PackageProvenance, error) {
+	encoded, err := collection.db.Get(provenanceKey(key))
+	if err != nil {
+		if err == database.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	provenance := &PackageProvenance{}
+
+	decoder := codec.NewDecoderBytes(encoded, collection.codecHandle)
+	err = decoder.Decode(provenance)
+	if err != nil {
+		return nil, err
+	}
+
+	return provenance, nil
+}
+
+// UpdateProvenanceInTransaction records provenance of the package in the context of the outer
+// transaction, provenance is recorded only once: when package is seen for the first time
+func (collection *PackageCollection) UpdateProvenanceInTransaction(p *Package, provenance *PackageProvenance, transaction database.Transaction) error {
+	if provenance == nil {
+		return nil
+	}
+
+	key := provenanceKey(p.Key(""))
+
+	_, err := transaction.Get(key)
+	if err == nil {
+		// already recorded
+		return nil
+	} else if err != database.ErrNotFound {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	err = codec.NewEncoder(&buf, collection.codecHandle).Encode(provenance)
+	if err != nil {
+		return err
+	}
+
+	return transaction.Put(key, buf.Bytes())
+}
@@ -0,0 +1,79 @@
+package deb
+
+import (
+	"time"
+
+	"github.com/aptly-dev/aptly/database"
+	"github.com/aptly-dev/aptly/database/goleveldb"
+	"github.com/aptly-dev/aptly/pgp"
+
+	. "gopkg.in/check.v1"
+)
+
+type ProvenanceSuite struct {
+	db         database.Storage
+	collection *PackageCollection
+	p          *Package
+}
+
+var _ = Suite(&ProvenanceSuite{})
+
+func (s *ProvenanceSuite) SetUpTest(c *C) {
+	s.db, _ = goleveldb.NewOpenDB(c.MkDir())
+	s.collection = NewPackageCollection(s.db)
+	s.p = NewPackageFromControlFile(packageStanza.Copy())
+	c.Assert(s.collection.Update(s.p), IsNil)
+}
+
+func (s *ProvenanceSuite) TearDownTest(c *C) {
+	s.db.Close()
+}
+
+func (s *ProvenanceSuite) update(c *C, provenance *PackageProvenance) {
+	transaction, err := s.db.OpenTransaction()
+	c.Assert(err, IsNil)
+	defer transaction.Discard()
+
+	c.Assert(s.collection.UpdateProvenanceInTransaction(s.p, provenance, transaction), IsNil)
+	c.Assert(transaction.Commit(), IsNil)
+}
+
+func (s *ProvenanceSuite) TestProvenance(c *C) {
+	provenance, err := s.collection.Provenance(s.p.Key(""))
+	c.Assert(err, IsNil)
+	c.Check(provenance, IsNil)
+
+	repo := NewLocalRepo("repo", "")
+	changes := &Changes{ChangesName: "alien-arena_7.40-2_i386.changes", SignatureKeys: []pgp.Key{"21DBB89C16DB3E6D"}}
+
+	first := NewChangesProvenance(repo, changes)
+	first.Date = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	s.update(c, first)
+
+	// provenance is recorded only once
+	s.update(c, NewLocalRepoProvenance(NewLocalRepo("other", ""), "john"))
+
+	provenance, err = s.collection.Provenance(s.p.Key(""))
+	c.Assert(err, IsNil)
+	c.Assert(provenance, NotNil)
+	c.Check(provenance.Action, Equals, ProvenanceRepoInclude)
+	c.Check(provenance.OriginUUID, Equals, repo.UUID)
+	c.Check(provenance.String(), Equals,
+		"repo include repo at 2020-01-02T03:04:05Z from alien-arena_7.40-2_i386.changes signed by 21DBB89C16DB3E6D")
+
+	c.Assert(s.collection.DeleteByKey(s.p.Key(""), s.db), IsNil)
+
+	provenance, err = s.collection.Provenance(s.p.Key(""))
+	c.Assert(err, IsNil)
+	c.Check(provenance, IsNil)
+}
+
+func (s *ProvenanceSuite) TestString(c *C) {
+	provenance := NewLocalRepoProvenance(NewLocalRepo("repo", ""), "john")
+	provenance.Date = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	c.Check(provenance.String(), Equals, "repo add repo at 2020-01-02T03:04:05Z by john")
+
+	provenance = NewChangesProvenance(NewLocalRepo("repo", ""), &Changes{ChangesName: "a.changes"})
+	provenance.Date = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	c.Check(provenance.String(), Equals, "repo include repo at 2020-01-02T03:04:05Z from a.changes (unsigned)")
+}
@@ -0,0 +1,150 @@
+package deb
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type VersionedProvidesSuite struct {
+	list     *PackageList
+	packages map[string]*Package
+}
+
+var _ = Suite(&VersionedProvidesSuite{})
+
+// stanzas modelled after python-cffi and rust packaging, versioned Provides are used to express ABI ranges
+const versionedProvidesPackages = `Package: python3-cffi-backend
+Source: python-cffi
+Version: 1.14.5-1
+Installed-Size: 222
+Maintainer: Debian Python Team <team+python@tracker.debian.org>
+Architecture: amd64
+Provides: python3-cffi-backend-api-9729, python3-cffi-backend-api-max (= 10495), python3-cffi-backend-api-min (= 9729)
+Depends: python3 (<< 3.10), python3 (>= 3.9~), libc6 (>= 2.14), libffi7 (>= 3.3~20180313)
+Description: Foreign Function Interface for Python 3 calling C code - runtime
+Section: python
+Priority: optional
+Filename: pool/main/p/python-cffi/python3-cffi-backend_1.14.5-1_amd64.deb
+Size: 81692
+MD5sum: 0c0e8ec2b3ad24ec5de1f4f8d0b87d1d
+SHA256: 2a1dbe2e0b5c7cb81ba6c3d2ba0c49a8bd1e6be0a5cb9e0b2e7b7bb1d1ad4b0a
+
+Package: python3-cryptography
+Source: python-cryptography
+Version: 3.3.2-1
+Installed-Size: 1287
+Maintainer: Debian Python Team <team+python@tracker.debian.org>
+Architecture: amd64
+Depends: python3 (<< 3.10), python3 (>= 3.9~), python3-cffi-backend-api-min (<= 9729), python3-cffi-backend-api-max (>= 9729), python3-six (>= 1.4.1), python3:any, libc6 (>= 2.14), libssl1.1 (>= 1.1.1)
+Description: Python library exposing cryptographic recipes and primitives (Python 3)
+Section: python
+Priority: optional
+Filename: pool/main/p/python-cryptography/python3-cryptography_3.3.2-1_amd64.deb
+Size: 223300
+MD5sum: 84e8fbfd6f6d1a1aa4e77a6fd2bda8a4
+SHA256: 1d5ac2fbad9b2c1a7bd1cd4d3d5e3b2c46c7f1e7c7c3a4aaf9b9a7a8b0c2f9d1
+
+Package: cargo
+Version: 0.47.0-3+b1
+Installed-Size: 13316
+Maintainer: Debian Rust Maintainers <pkg-rust-maintainers@alioth-lists.debian.net>
+Architecture: amd64
+Provides: librust-cargo-dev (= 0.47.0)
+Depends: libc6 (>= 2.28), rustc (>= 1.24)
+Description: Rust package manager
+Section: devel
+Priority: optional
+Filename: pool/main/c/cargo/cargo_0.47.0-3+b1_amd64.deb
+Size: 3384268
+MD5sum: 9c1c4a7f95a8a4bce0b8e3f1d3c8a2b1
+SHA256: 5a6c07a64bd82c8ac1ea9e4e0b4bc6e9d1d2c6b3f0a3c8f9e7d6b5a4c3b2a190
+`
+
+func (s *VersionedProvidesSuite) SetUpTest(c *C) {
+	s.list = NewPackageList()
+	s.packages = map[string]*Package{}
+
+	reader := NewControlFileReader(bytes.NewBufferString(versionedProvidesPackages), false, false)
+	for {
+		stanza, err := reader.ReadStanza()
+		c.Assert(err, IsNil)
+		if stanza == nil {
+			break
+		}
+
+		p := NewPackageFromControlFile(stanza)
+		c.Assert(s.list.Add(p), IsNil)
+		s.packages[p.Name] = p
+	}
+
+	s.list.PrepareIndex()
+}
+
+func (s *VersionedProvidesSuite) TestProvidedPackages(c *C) {
+	c.Check(s.packages["python3-cffi-backend"].ProvidedPackages(), DeepEquals, []Dependency{
+		{Pkg: "python3-cffi-backend-api-9729", Relation: VersionDontCare},
+		{Pkg: "python3-cffi-backend-api-max", Relation: VersionEqual, Version: "10495"},
+		{Pkg: "python3-cffi-backend-api-min", Relation: VersionEqual, Version: "9729"},
+	})
+	c.Check(s.packages["python3-cffi-backend"].ProvidedNames(), DeepEquals,
+		[]string{"python3-cffi-backend-api-9729", "python3-cffi-backend-api-max", "python3-cffi-backend-api-min"})
+	c.Check(s.packages["python3-cryptography"].ProvidedPackages(), IsNil)
+}
+
+func (s *VersionedProvidesSuite) TestMatchesDependency(c *C) {
+	p := s.packages["python3-cffi-backend"]
+
+	// unversioned dependency is satisfied by both versioned and unversioned provides
+	c.Check(p.MatchesDependency(Dependency{Pkg: "python3-cffi-backend-api-9729"}), Equals, true)
+	c.Check(p.MatchesDependency(Dependency{Pkg: "python3-cffi-backend-api-max"}), Equals, true)
+
+	// versioned dependency is satisfied by versioned provides only
+	c.Check(p.MatchesDependency(Dependency{Pkg: "python3-cffi-backend-api-min", Relation: VersionLessOrEqual, Version: "9729"}), Equals, true)
+	c.Check(p.MatchesDependency(Dependency{Pkg: "python3-cffi-backend-api-min", Relation: VersionLess, Version: "9729"}), Equals, false)
+	c.Check(p.MatchesDependency(Dependency{Pkg: "python3-cffi-backend-api-max", Relation: VersionGreaterOrEqual, Version: "9729"}), Equals, true)
+	c.Check(p.MatchesDependency(Dependency{Pkg: "python3-cffi-backend-api-max", Relation: VersionGreater, Version: "10495"}), Equals, false)
+	c.Check(p.MatchesDependency(Dependency{Pkg: "python3-cffi-backend-api-9729", Relation: VersionEqual, Version: "9729"}), Equals, false)
+
+	// architecture is still checked
+	c.Check(p.MatchesDependency(Dependency{Pkg: "python3-cffi-backend-api-max", Relation: VersionGreaterOrEqual, Version: "9729", Architecture: "i386"}), Equals, false)
+
+	// package version is still matched by name
+	c.Check(p.MatchesDependency(Dependency{Pkg: "python3-cffi-backend", Relation: VersionGreaterOrEqual, Version: "1.14"}), Equals, true)
+	c.Check(s.packages["cargo"].MatchesDependency(Dependency{Pkg: "librust-cargo-dev", Relation: VersionGreaterOrEqual, Version: "0.47~"}), Equals, true)
+	c.Check(s.packages["cargo"].MatchesDependency(Dependency{Pkg: "librust-cargo-dev", Relation: VersionPatternMatch, Version: "0.4*"}), Equals, true)
+}
+
+func (s *VersionedProvidesSuite) TestSearch(c *C) {
+	backend := s.packages["python3-cffi-backend"]
+
+	c.Check(s.list.Search(Dependency{Pkg: "python3-cffi-backend-api-min", Relation: VersionLessOrEqual, Version: "9729", Architecture: "amd64"}, false),
+		DeepEquals, []*Package{backend})
+	c.Check(s.list.Search(Dependency{Pkg: "python3-cffi-backend-api-max", Relation: VersionGreaterOrEqual, Version: "9729", Architecture: "amd64"}, true),
+		DeepEquals, []*Package{backend})
+	c.Check(s.list.Search(Dependency{Pkg: "python3-cffi-backend-api-max", Relation: VersionGreaterOrEqual, Version: "10496", Architecture: "amd64"}, true),
+		IsNil)
+	c.Check(s.list.Search(Dependency{Pkg: "python3-cffi-backend-api-9729", Relation: VersionGreaterOrEqual, Version: "1", Architecture: "amd64"}, true),
+		IsNil)
+	c.Check(s.list.Search(Dependency{Pkg: "librust-cargo-dev", Architecture: "amd64"}, false), DeepEquals, []*Package{s.packages["cargo"]})
+}
+
+func (s *VersionedProvidesSuite) TestVerifyDependencies(c *C) {
+	missing, err := s.list.VerifyDependencies(0, []string{"amd64"}, s.list, nil)
+	c.Assert(err, IsNil)
+
+	names := []string{}
+	for _, dep := range missing {
+		names = append(names, dep.Pkg)
+	}
+
+	// python3-cffi-backend-api-* are satisfied via versioned provides
+	c.Check(names, DeepEquals, []string{"libc6", "rustc", "python3", "python3", "libc6", "libffi7", "python3-six", "python3", "libssl1.1"})
+}
+
+func (s *VersionedProvidesSuite) TestFilterWithDependencies(c *C) {
+	result, err := s.list.Filter([]PackageQuery{&PkgQuery{"python3-cryptography", "3.3.2-1", "amd64"}}, true, nil, 0, []string{"amd64"})
+	c.Assert(err, IsNil)
+	c.Check(result.Strings(), HasLen, 2)
+	c.Check(result.Has(s.packages["python3-cffi-backend"]), Equals, true)
+}
@@ -63,11 +63,17 @@ type PublishedRepo struct {
 	// Skip contents generation
 	SkipContents bool
 
+	// Generate Translation-<lang> indexes (and Description-md5)
+	Translations bool
+
 	// True if repo is being re-published
 	rePublishing bool
 
 	// Provide index files per hash also
 	AcquireByHash bool
+
+	// Overrides of package control fields by component
+	Overrides map[string]*PackageOverrides
 }
 
 // ParsePrefix splits [storage:]prefix into components
@@ -317,6 +323,7 @@ func (p *PublishedRepo) MarshalJSON() ([]byte, error) {
 		"Sources":              sources,
 		"Storage":              p.Storage,
 		"SkipContents":         p.SkipContents,
+		"Translations":         p.Translations,
 		"AcquireByHash":        p.AcquireByHash,
 	})
 }
@@ -442,6 +449,24 @@ func (p *PublishedRepo) UpdateSnapshot(component string, snapshot *Snapshot) {
 	p.rePublishing = true
 }
 
+// ComponentOverrides returns package overrides for the component (nil if no overrides)
+func (p *PublishedRepo) ComponentOverrides(component string) *PackageOverrides {
+	return p.Overrides[component]
+}
+
+// SetComponentOverrides replaces overrides of specified type for the component
+func (p *PublishedRepo) SetComponentOverrides(component, overrideType string, overrides map[string]map[string]string) error {
+	if p.Overrides == nil {
+		p.Overrides = map[string]*PackageOverrides{}
+	}
+
+	if p.Overrides[component] == nil {
+		p.Overrides[component] = NewPackageOverrides()
+	}
+
+	return p.Overrides[component].Set(overrideType, overrides)
+}
+
 // Encode does msgpack encoding of PublishedRepo
 func (p *PublishedRepo) Encode() []byte {
 	var buf bytes.Buffer
@@ -593,6 +618,7 @@ func (p *PublishedRepo) Publish(packagePool aptly.PackagePool, publishedStorageP
 		list.PrepareIndex()
 
 		contentIndexes := map[string]*ContentsIndex{}
+		translations := newTranslationIndexes(indexes, collectionFactory.PackageCollection(), component, p.Translations)
 
 		err = list.ForEachIndexed(func(pkg *Package) error {
 			if progress != nil {
@@ -655,7 +681,20 @@ func (p *PublishedRepo) Publish(packagePool aptly.PackagePool, publishedStorageP
 						return err
 					}
 
-					err = pkg.Stanza().WriteTo(bufWriter, pkg.IsSource, false, pkg.IsInstaller)
+					stanza := pkg.Stanza()
+					p.ComponentOverrides(component).Apply(pkg, stanza)
+
+					if pkg.translatable() {
+						err = translations.Process(pkg, stanza)
+						if err != nil {
+							return err
+						}
+					} else {
+						// translated descriptions are never published in udeb and source indexes
+						removeTranslations(stanza)
+					}
+
+					err = stanza.WriteTo(bufWriter, pkg.IsSource, false, pkg.IsInstaller)
 					if err != nil {
 						return err
 					}
@@ -702,6 +741,23 @@ func (p *PublishedRepo) Publish(packagePool aptly.PackagePool, publishedStorageP
 			progress.ShutdownBar()
 		}
 
+		for _, extra := range p.extraFiles(component) {
+			if extra.IsContents() && !p.SkipContents {
+				// Contents indexes are generated from packages
+				continue
+			}
+
+			arch, _ := extraFileArchitecture(extra.Path)
+			if arch != "" && arch != ArchitectureAll && !utils.StrSliceHasItem(p.Architectures, arch) {
+				continue
+			}
+
+			err = copyExtraFile(packagePool, &extra, indexes.ExtraIndex(component, extra.Path))
+			if err != nil {
+				return fmt.Errorf("unable to publish %s: %s", extra.Path, err)
+			}
+		}
+
 		udebs := []bool{false}
 		if hadUdebs {
 			udebs = append(udebs, true)
@@ -12,6 +12,7 @@ import (
 	"github.com/aptly-dev/aptly/database"
 	"github.com/aptly-dev/aptly/database/goleveldb"
 	"github.com/aptly-dev/aptly/files"
+	"github.com/aptly-dev/aptly/utils"
 	"github.com/ugorji/go/codec"
 
 	. "gopkg.in/check.v1"
@@ -353,6 +354,112 @@ func (s *PublishedRepoSuite) TestPublish(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *PublishedRepoSuite) TestPublishOverrides(c *C) {
+	c.Assert(s.repo.SetComponentOverrides("main", OverrideBinary, map[string]map[string]string{
+		"alien-arena-common": {"Priority": "extra", "Section": "contrib/games"},
+	}), IsNil)
+	c.Assert(s.repo.SetComponentOverrides("main", OverrideExtra, map[string]map[string]string{
+		"alien-arena-common": {"Task": "games"},
+	}), IsNil)
+
+	err := s.repo.Publish(s.packagePool, s.provider, s.factory, nil, nil, false)
+	c.Assert(err, IsNil)
+
+	pf, err := os.Open(filepath.Join(s.publishedStorage.PublicPath(), "ppa/dists/squeeze/main/binary-i386/Packages"))
+	c.Assert(err, IsNil)
+
+	cfr := NewControlFileReader(pf, false, false)
+	st, err := cfr.ReadStanza()
+	c.Assert(err, IsNil)
+
+	c.Check(st["Priority"], Equals, "extra")
+	c.Check(st["Section"], Equals, "contrib/games")
+	c.Check(st["Task"], Equals, "games")
+}
+
+func (s *PublishedRepoSuite) TestPublishTranslations(c *C) {
+	s.repo.Translations = true
+
+	err := s.repo.Publish(s.packagePool, s.provider, s.factory, nil, nil, false)
+	c.Assert(err, IsNil)
+
+	pf, err := os.Open(filepath.Join(s.publishedStorage.PublicPath(), "ppa/dists/squeeze/main/binary-i386/Packages"))
+	c.Assert(err, IsNil)
+
+	st, err := NewControlFileReader(pf, false, false).ReadStanza()
+	c.Assert(err, IsNil)
+	c.Check(st["Description-Md5"], Equals, DescriptionMD5(st["Description"]))
+
+	tf, err := os.Open(filepath.Join(s.publishedStorage.PublicPath(), "ppa/dists/squeeze/main/i18n/Translation-en"))
+	c.Assert(err, IsNil)
+
+	tst, err := NewControlFileReader(tf, false, false).ReadStanza()
+	c.Assert(err, IsNil)
+	c.Check(tst["Package"], Equals, "alien-arena-common")
+	c.Check(tst["Description-Md5"], Equals, st["Description-Md5"])
+	c.Check(tst["Description-en"], Equals, st["Description"])
+
+	c.Check(filepath.Join(s.publishedStorage.PublicPath(), "ppa/dists/squeeze/main/i18n/Translation-en.bz2"), PathExists)
+
+	rf, err := os.Open(filepath.Join(s.publishedStorage.PublicPath(), "ppa/dists/squeeze/Release"))
+	c.Assert(err, IsNil)
+
+	rst, err := NewControlFileReader(rf, true, false).ReadStanza()
+	c.Assert(err, IsNil)
+	c.Check(rst["SHA256"], Matches, "(?s).*main/i18n/Translation-en.gz\n.*")
+}
+
+func (s *PublishedRepoSuite) TestPublishExtras(c *C) {
+	for _, extra := range []struct{ path, contents string }{
+		{"dep11/icons-64x64.tar.gz", "icons"},
+		{"cnf/Commands-i386.xz", "commands"},
+		{"cnf/Commands-s390.xz", "commands s390"},
+		{"Contents-i386.gz", "contents"},
+	} {
+		tmpFilepath := filepath.Join(c.MkDir(), "file")
+		c.Assert(ioutil.WriteFile(tmpFilepath, []byte(extra.contents), 0644), IsNil)
+
+		file := ExtraFile{Component: "main", Path: extra.path}
+		file.Checksums, _ = utils.ChecksumsForFile(tmpFilepath)
+
+		var err error
+		file.PoolPath, err = s.packagePool.Import(tmpFilepath, filepath.Base(extra.path), &file.Checksums, false, s.cs)
+		c.Assert(err, IsNil)
+
+		s.snapshot.ExtraFiles = append(s.snapshot.ExtraFiles, file)
+	}
+
+	err := s.repo.Publish(s.packagePool, s.provider, s.factory, nil, nil, false)
+	c.Assert(err, IsNil)
+
+	root := filepath.Join(s.publishedStorage.PublicPath(), "ppa/dists/squeeze/main")
+	c.Check(filepath.Join(root, "dep11/icons-64x64.tar.gz"), PathExists)
+	c.Check(filepath.Join(root, "cnf/Commands-i386.xz"), PathExists)
+	c.Check(filepath.Join(root, "cnf/Commands-s390.xz"), Not(PathExists))
+	c.Check(filepath.Join(root, "Contents-i386.gz"), PathExists)
+
+	contents, _ := ioutil.ReadFile(filepath.Join(root, "cnf/Commands-i386.xz"))
+	c.Check(string(contents), Equals, "commands")
+
+	rf, err := os.Open(filepath.Join(s.publishedStorage.PublicPath(), "ppa/dists/squeeze/Release"))
+	c.Assert(err, IsNil)
+
+	rst, err := NewControlFileReader(rf, true, false).ReadStanza()
+	c.Assert(err, IsNil)
+	c.Check(rst["SHA256"], Matches, "(?s).*main/dep11/icons-64x64.tar.gz\n.*")
+	c.Check(rst["SHA256"], Matches, "(?s).*main/cnf/Commands-i386.xz\n.*")
+
+	// generated Contents take precedence over mirrored ones
+	s.repo.SkipContents = false
+	s.repo.Distribution = "wheezy"
+
+	err = s.repo.Publish(s.packagePool, s.provider, s.factory, nil, nil, false)
+	c.Assert(err, IsNil)
+
+	contents, _ = ioutil.ReadFile(filepath.Join(s.publishedStorage.PublicPath(), "ppa/dists/wheezy/main/Contents-i386.gz"))
+	c.Check(string(contents), Not(Equals), "contents")
+}
+
 func (s *PublishedRepoSuite) TestPublishNoSigner(c *C) {
 	err := s.repo.Publish(s.packagePool, s.provider, s.factory, nil, nil, false)
 	c.Assert(err, IsNil)
@@ -418,6 +525,18 @@ func (s *PublishedRepoSuite) TestRefKey(c *C) {
 	c.Check(s.repo.RefKey("main"), DeepEquals, []byte("E"+s.repo.UUID+"main"))
 }
 
+func (s *PublishedRepoSuite) TestEncodeDecodeOverrides(c *C) {
+	s.repo.SetComponentOverrides("main", OverrideSource, map[string]map[string]string{"alien-arena": {"Section": "games"}})
+
+	encoded := s.repo.Encode()
+	repo := &PublishedRepo{}
+	err := repo.Decode(encoded)
+	c.Assert(err, IsNil)
+
+	c.Check(repo.ComponentOverrides("main").Source, DeepEquals, map[string]map[string]string{"alien-arena": {"Section": "games"}})
+	c.Check(repo.ComponentOverrides("contrib"), IsNil)
+}
+
 func (s *PublishedRepoSuite) TestEncodeDecode(c *C) {
 	encoded := s.repo.Encode()
 	repo := &PublishedRepo{}
@@ -58,6 +58,9 @@ type FieldQuery struct {
 	Relation int
 	Value    string
 	Regexp   *regexp.Regexp `codec:"-"`
+
+	// packages matched by $ReverseDepends, resolved against the list being filtered
+	reverseDepends map[*Package]bool
 }
 
 // PkgQuery is search request against specific package
@@ -161,9 +164,18 @@ func (q *FieldQuery) Matches(pkg PackageLike) bool {
 	if q.Field == "$Architecture" && q.Relation == VersionEqual {
 		return pkg.MatchesArchitecture(q.Value)
 	}
+	if q.Field == "$ReverseDepends" {
+		// matches packages which have hard dependency on the package named in the query,
+		// see prepareReverseDependsQueries
+		p, ok := pkg.(*Package)
+		return ok && q.reverseDepends[p]
+	}
 
-	field := pkg.GetField(q.Field)
+	return q.matchesValue(pkg.GetField(q.Field))
+}
 
+// matchesValue compares field value with query value according to relation
+func (q *FieldQuery) matchesValue(field string) bool {
 	switch q.Relation {
 	case VersionDontCare:
 		return field != ""
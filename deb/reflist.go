@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"sort"
+	"time"
 
 	"github.com/AlekSi/pointer"
 	"github.com/ugorji/go/codec"
@@ -396,3 +397,111 @@ func (l *PackageRefList) FilterLatestRefs() {
 		lastArch, lastName, lastVer = arch, name, ver
 	}
 }
+
+// RetainLatestRefs returns new reflist which keeps only keep latest versions
+// of each package (by architecture and name), ordered with CompareVersions.
+//
+// Refs which share the same version (but differ in files) are counted as
+// one version. If keep is not positive, all the refs are retained.
+func (l *PackageRefList) RetainLatestRefs(keep int) *PackageRefList {
+	result := &PackageRefList{Refs: make([][]byte, 0, l.Len())}
+
+	if keep <= 0 {
+		result.Refs = append(result.Refs, l.Refs...)
+		return result
+	}
+
+	splitRef := func(ref []byte) (arch, name, version []byte) {
+		parts := bytes.Split(ref[1:], []byte(" "))
+		return parts[0], parts[1], parts[2]
+	}
+
+	for start := 0; start < len(l.Refs); {
+		arch, name, _ := splitRef(l.Refs[start])
+
+		// refs are sorted, so all versions of the same package are adjacent
+		end := start + 1
+		for ; end < len(l.Refs); end++ {
+			a, n, _ := splitRef(l.Refs[end])
+			if !bytes.Equal(a, arch) || !bytes.Equal(n, name) {
+				break
+			}
+		}
+
+		group := make([][]byte, end-start)
+		copy(group, l.Refs[start:end])
+
+		sort.SliceStable(group, func(i, j int) bool {
+			_, _, vi := splitRef(group[i])
+			_, _, vj := splitRef(group[j])
+			return CompareVersions(string(vi), string(vj)) > 0
+		})
+
+		var lastVersion []byte
+		versions := 0
+
+		for _, ref := range group {
+			_, _, version := splitRef(ref)
+			if lastVersion == nil || CompareVersions(string(version), string(lastVersion)) != 0 {
+				versions++
+				lastVersion = version
+			}
+
+			if versions > keep {
+				break
+			}
+
+			result.Refs = append(result.Refs, ref)
+		}
+
+		start = end
+	}
+
+	sort.Sort(result)
+
+	return result
+}
+
+// RetainRefs applies retention rules to the reflist: ref is retained if it is one of
+// keepVersions latest versions of the package (see RetainLatestRefs) or if package
+// has been introduced into aptly less than maxAge ago.
+//
+// Introduction date comes from package provenance, so it is the date package key has been
+// first seen anywhere in aptly, not the date package was added to this list. Packages
+// without recorded provenance are always retained. Age rule never drops the latest version of the package.
+// Rule is disabled if its value is zero, if both rules are disabled, all the refs are retained.
+func (l *PackageRefList) RetainRefs(keepVersions int, maxAge time.Duration, collection *PackageCollection) (*PackageRefList, error) {
+	if maxAge <= 0 {
+		return l.RetainLatestRefs(keepVersions), nil
+	}
+
+	retained := map[string]bool{}
+
+	keep := keepVersions
+	if keep <= 0 {
+		keep = 1
+	}
+	for _, ref := range l.RetainLatestRefs(keep).Refs {
+		retained[string(ref)] = true
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	result := &PackageRefList{Refs: make([][]byte, 0, l.Len())}
+
+	for _, ref := range l.Refs {
+		if !retained[string(ref)] {
+			provenance, err := collection.Provenance(ref)
+			if err != nil {
+				return nil, err
+			}
+
+			if provenance != nil && provenance.Date.Before(cutoff) {
+				continue
+			}
+		}
+
+		result.Refs = append(result.Refs, ref)
+	}
+
+	return result, nil
+}
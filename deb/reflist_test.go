@@ -2,6 +2,7 @@ package deb
 
 import (
 	"errors"
+	"time"
 
 	"github.com/aptly-dev/aptly/database/goleveldb"
 
@@ -352,3 +353,107 @@ func (s *PackageRefListSuite) TestFilterLatestRefs(c *C) {
 	c.Check(toStrSlice(result), DeepEquals,
 		[]string{"Pi386 dpkg 1.6", "Pi386 lib 1.2"})
 }
+
+func (s *PackageRefListSuite) TestRetainLatestRefs(c *C) {
+	packages := []*Package{
+		{Name: "lib", Version: "1.0", Architecture: "i386"},
+		{Name: "lib", Version: "1.2~bp1", Architecture: "i386"},
+		{Name: "lib", Version: "1.2", Architecture: "i386"},
+		{Name: "lib", Version: "1.2", Architecture: "amd64"},
+		{Name: "dpkg", Version: "1.2", Architecture: "i386"},
+		{Name: "dpkg", Version: "1.3", Architecture: "i386"},
+		{Name: "dpkg", Version: "1.3~bp2", Architecture: "i386"},
+		{Name: "dpkg", Version: "1.5", Architecture: "i386"},
+		{Name: "dpkg", Version: "1.6", Architecture: "i386"},
+		{Name: "dpkg-dev", Version: "1.0", Architecture: "i386"},
+	}
+
+	rl := NewPackageList()
+	for _, p := range packages {
+		rl.Add(p)
+	}
+
+	reflist := NewPackageRefListFromPackageList(rl)
+
+	c.Check(toStrSlice(reflist.RetainLatestRefs(1)), DeepEquals,
+		[]string{"Pamd64 lib 1.2", "Pi386 dpkg 1.6", "Pi386 dpkg-dev 1.0", "Pi386 lib 1.2"})
+	c.Check(toStrSlice(reflist.RetainLatestRefs(2)), DeepEquals,
+		[]string{"Pamd64 lib 1.2", "Pi386 dpkg 1.5", "Pi386 dpkg 1.6", "Pi386 dpkg-dev 1.0", "Pi386 lib 1.2", "Pi386 lib 1.2~bp1"})
+	c.Check(toStrSlice(reflist.RetainLatestRefs(0)), DeepEquals, toStrSlice(reflist))
+
+	// original reflist is not modified
+	c.Check(reflist.Len(), Equals, 10)
+
+	// same version with different files counts as single version
+	dup := &PackageRefList{Refs: [][]byte{
+		[]byte("Pi386 app 1.0 00000001"),
+		[]byte("Pi386 app 1.1 00000001"),
+		[]byte("Pi386 app 1.1 00000002"),
+	}}
+	c.Check(toStrSlice(dup.RetainLatestRefs(1)), DeepEquals,
+		[]string{"Pi386 app 1.1 00000001", "Pi386 app 1.1 00000002"})
+}
+
+func (s *PackageRefListSuite) TestRetainRefs(c *C) {
+	db, _ := goleveldb.NewOpenDB(c.MkDir())
+	defer db.Close()
+
+	collection := NewPackageCollection(db)
+
+	packages := []*Package{
+		{Name: "lib", Version: "1.0", Architecture: "i386"},
+		{Name: "lib", Version: "1.1", Architecture: "i386"},
+		{Name: "lib", Version: "1.2", Architecture: "i386"},
+		{Name: "lib", Version: "1.3", Architecture: "i386"},
+		{Name: "dpkg", Version: "1.5", Architecture: "i386"},
+		{Name: "dpkg", Version: "1.6", Architecture: "i386"},
+	}
+
+	ages := map[string]time.Duration{
+		"lib 1.0":  200 * 24 * time.Hour,
+		"lib 1.1":  100 * 24 * time.Hour,
+		"lib 1.2":  10 * 24 * time.Hour,
+		"lib 1.3":  1 * 24 * time.Hour,
+		"dpkg 1.6": 300 * 24 * time.Hour,
+		// dpkg 1.5 has no provenance
+	}
+
+	rl := NewPackageList()
+	for _, p := range packages {
+		rl.Add(p)
+
+		age, ok := ages[p.Name+" "+p.Version]
+		if !ok {
+			continue
+		}
+
+		transaction, err := db.OpenTransaction()
+		c.Assert(err, IsNil)
+		c.Assert(collection.UpdateProvenanceInTransaction(p, &PackageProvenance{Action: ProvenanceRepoAdd, Date: time.Now().Add(-age)}, transaction), IsNil)
+		c.Assert(transaction.Commit(), IsNil)
+	}
+
+	reflist := NewPackageRefListFromPackageList(rl)
+
+	// age only: latest version and packages of unknown age are always kept
+	result, err := reflist.RetainRefs(0, 90*24*time.Hour, collection)
+	c.Assert(err, IsNil)
+	c.Check(toStrSlice(result), DeepEquals,
+		[]string{"Pi386 dpkg 1.5", "Pi386 dpkg 1.6", "Pi386 lib 1.2", "Pi386 lib 1.3"})
+
+	// either of the rules keeps the version
+	result, err = reflist.RetainRefs(3, 5*24*time.Hour, collection)
+	c.Assert(err, IsNil)
+	c.Check(toStrSlice(result), DeepEquals,
+		[]string{"Pi386 dpkg 1.5", "Pi386 dpkg 1.6", "Pi386 lib 1.1", "Pi386 lib 1.2", "Pi386 lib 1.3"})
+
+	// count only
+	result, err = reflist.RetainRefs(1, 0, collection)
+	c.Assert(err, IsNil)
+	c.Check(toStrSlice(result), DeepEquals, []string{"Pi386 dpkg 1.6", "Pi386 lib 1.3"})
+
+	// no rules
+	result, err = reflist.RetainRefs(0, 0, collection)
+	c.Assert(err, IsNil)
+	c.Check(toStrSlice(result), DeepEquals, toStrSlice(reflist))
+}
@@ -2,13 +2,16 @@ package deb
 
 import (
 	"bytes"
+	"compress/gzip"
 	gocontext "context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -71,12 +74,42 @@ type RemoteRepo struct {
 	DownloadUdebs bool
 	// Should we download installer files?
 	DownloadInstaller bool
+	// Should we download translated descriptions (i18n/Translation-*)?
+	DownloadTranslations bool
+	// HTTP transport options (proxy, TLS, credentials), applied on top of global ones
+	DownloadOptions utils.DownloadOptions
+	// Alternate archive roots (mirrors of ArchiveRoot) to download from on failures
+	FallbackArchiveRoots []string
+	// Spread downloads across ArchiveRoot and fallbacks round-robin instead of trying them in order
+	RoundRobinArchiveRoots bool
+	// Date of archive snapshot (snapshot.debian.org layout) ArchiveRoot is pinned to
+	SnapshotDate time.Time
+	// Should we download auxiliary metadata (dep11, cnf, Contents)?
+	DownloadExtras bool
+	// Auxiliary metadata files of the last update
+	ExtraFiles []ExtraFile
 	// "Snapshot" of current list of packages
 	packageRefs *PackageRefList
 	// Parsed archived root
 	archiveRootURL *url.URL
 	// Current list of packages (filled while updating mirror)
 	packageList *PackageList
+	// Directory to keep last downloaded indexes in (for pdiff updates)
+	indexCacheDir string
+	// Number of package indexes downloaded & parsed in parallel
+	indexConcurrency int
+	// Base URLs of aptly API of peers which pools are consulted before upstream
+	poolPeers []string
+	// Statistics of current update (for update report)
+	updateStarted   time.Time
+	downloadedFiles int
+	downloadedBytes int64
+	// Report of the last update (filled by FinalizeDownload)
+	updateReport *MirrorUpdateReport
+	// Auxiliary metadata files downloaded during update
+	extraFiles []ExtraFile
+	// Translated descriptions downloaded during update: Description-md5 -> language -> description
+	translations map[string]map[string]string
 }
 
 // NewRemoteRepo creates new instance of Debian remote repository with specified params
@@ -117,15 +150,65 @@ func NewRemoteRepo(name string, archiveRoot string, distribution string, compone
 	return result, nil
 }
 
+// SetIndexCacheDir enables keeping last downloaded package indexes in the directory,
+// so that next update could download only pdiff patches (Packages.diff/Index)
+func (repo *RemoteRepo) SetIndexCacheDir(dir string) {
+	repo.indexCacheDir = dir
+}
+
+// SetIndexConcurrency sets number of package indexes downloaded and parsed in parallel
+// while updating mirror
+func (repo *RemoteRepo) SetIndexConcurrency(concurrency int) {
+	repo.indexConcurrency = concurrency
+}
+
+// SetPoolPeers sets base URLs of aptly API instances which package pools
+// are consulted (by checksum) before downloading package files from upstream
+func (repo *RemoteRepo) SetPoolPeers(peers []string) {
+	repo.poolPeers = peers
+}
+
+// PoolPeerURL returns URL of the file in the package pool of peer aptly instance
+func PoolPeerURL(peer string, file *PackageFile) string {
+	return fmt.Sprintf("%s/api/pool/sha256/%s/%s?size=%d", strings.TrimSuffix(peer, "/"),
+		file.Checksums.SHA256, url.PathEscape(file.Filename), file.Checksums.Size)
+}
+
+// IndexCachePath returns directory with cached package indexes of the mirror
+func (repo *RemoteRepo) IndexCachePath(dir string) string {
+	return filepath.Join(dir, repo.UUID)
+}
+
 // SetArchiveRoot of remote repo
-func (repo *RemoteRepo) SetArchiveRoot(archiveRoot string) {
+func (repo *RemoteRepo) SetArchiveRoot(archiveRoot string) error {
 	repo.ArchiveRoot = archiveRoot
-	repo.prepare()
+	return repo.prepare()
+}
+
+// isLocalArchivePath checks whether archive root is a path to local archive: path should be
+// absolute or explicitly relative, so that URL with missing scheme is not mistaken for a path
+func isLocalArchivePath(archiveRoot string) bool {
+	return filepath.IsAbs(archiveRoot) || archiveRoot == "." || archiveRoot == ".." ||
+		strings.HasPrefix(archiveRoot, "./") || strings.HasPrefix(archiveRoot, "../")
 }
 
 func (repo *RemoteRepo) prepare() error {
 	var err error
 
+	// plain directory path is local archive
+	if repo.ArchiveRoot != "" && !strings.Contains(repo.ArchiveRoot, "://") {
+		if !isLocalArchivePath(repo.ArchiveRoot) {
+			return fmt.Errorf("archive root %s is neither URL nor absolute (or ./ relative) path to local archive", repo.ArchiveRoot)
+		}
+
+		var absPath string
+		absPath, err = filepath.Abs(repo.ArchiveRoot)
+		if err != nil {
+			return err
+		}
+		repo.ArchiveRoot = (&url.URL{Scheme: "file", Path: absPath}).String()
+	}
+
 	// Add final / to URL
 	if !strings.HasSuffix(repo.ArchiveRoot, "/") {
 		repo.ArchiveRoot = repo.ArchiveRoot + "/"
@@ -147,6 +230,12 @@ func (repo *RemoteRepo) String() string {
 	if repo.DownloadInstaller {
 		srcFlag += " [installer]"
 	}
+	if repo.DownloadTranslations {
+		srcFlag += " [i18n]"
+	}
+	if repo.DownloadExtras {
+		srcFlag += " [extras]"
+	}
 	distribution := repo.Distribution
 	if distribution == "" {
 		distribution = "./"
@@ -154,6 +243,11 @@ func (repo *RemoteRepo) String() string {
 	return fmt.Sprintf("[%s]: %s %s%s", repo.Name, repo.ArchiveRoot, distribution, srcFlag)
 }
 
+// IsLocal determines if repository is mirrored from local filesystem (file://)
+func (repo *RemoteRepo) IsLocal() bool {
+	return repo.archiveRootURL != nil && repo.archiveRootURL.Scheme == "file"
+}
+
 // IsFlat determines if repository is flat
 func (repo *RemoteRepo) IsFlat() bool {
 	// aptly < 0.5.1 had Distribution = "" for flat repos
@@ -428,6 +522,7 @@ func (repo *RemoteRepo) DownloadPackageIndexes(progress aptly.Progress, d aptly.
 		panic("packageList != nil")
 	}
 	repo.packageList = NewPackageList()
+	repo.updateStarted = time.Now()
 
 	// Download and parse all Packages & Source files
 	packagesPaths := [][]string{}
@@ -454,107 +549,561 @@ func (repo *RemoteRepo) DownloadPackageIndexes(progress aptly.Progress, d aptly.
 		}
 	}
 
-	for _, info := range packagesPaths {
-		path, kind, component, architecture := info[0], info[1], info[2], info[3]
-		packagesReader, packagesFile, err := http.DownloadTryCompression(gocontext.TODO(), d, repo.IndexesRootURL(), path, repo.ReleaseFiles, ignoreMismatch)
+	concurrency := repo.indexConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(packagesPaths) {
+		concurrency = len(packagesPaths)
+	}
 
-		isInstaller := kind == PackageTypeInstaller
-		if err != nil {
-			if _, ok := err.(*http.NoCandidateFoundError); isInstaller && ok {
-				// checking if gpg file is only needed when checksums matches are required.
-				// otherwise there actually has been no candidate found and we can continue
-				if ignoreMismatch {
-					continue
-				}
+	// with parallel downloads progress bar tracks number of indexes processed,
+	// messages of index downloads are still passed through
+	indexProgress := progress
+	if concurrency > 1 && progress != nil {
+		indexProgress = &messagesOnlyProgress{progress: progress}
+		progress.InitBar(int64(len(packagesPaths)), false)
+	}
 
-				// some repos do not have installer hashsum file listed in release file but provide a separate gpg file
-				hashsumPath := repo.IndexesRootURL().ResolveReference(&url.URL{Path: path}).String()
-				packagesFile, err = http.DownloadTemp(gocontext.TODO(), d, hashsumPath)
-				if err != nil {
-					if herr, ok := err.(*http.Error); ok && (herr.Code == 404 || herr.Code == 403) {
-						// installer files are not available in all components and architectures
-						// so ignore it if not found
-						continue
-					}
+	// indexes are downloaded & parsed in parallel, but results are merged into the
+	// package list in the order of packagesPaths, so that duplicates are resolved the same way
+	results := make([]chan packageIndexResult, len(packagesPaths))
+	for i := range results {
+		results[i] = make(chan packageIndexResult, 1)
+	}
+
+	queue := make(chan int)
+	abort := make(chan struct{})
+
+	go func() {
+		defer close(queue)
+		for i := range packagesPaths {
+			select {
+			case queue <- i:
+			case <-abort:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
 
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range queue {
+				packages, err := repo.downloadPackageIndex(indexProgress, d, verifier, ignoreMismatch, packagesPaths[idx])
+				results[idx] <- packageIndexResult{packages: packages, err: err}
+			}
+		}()
+	}
+
+	err := func() error {
+		defer func() {
+			close(abort)
+			wg.Wait()
+		}()
+
+		for idx := range packagesPaths {
+			result := <-results[idx]
+			if result.err != nil {
+				return result.err
+			}
+
+			if concurrency > 1 && progress != nil {
+				progress.AddBar(1)
+			}
+
+			for _, p := range result.packages {
+				err := repo.packageList.Add(p)
+				if _, ok := err.(*PackageConflictError); ok {
+					if progress != nil {
+						progress.ColoredPrintf("@y[!]@| @!skipping package %s: duplicate in packages index@|", p)
+					}
+				} else if err != nil {
 					return err
 				}
+			}
+		}
 
-				if verifier != nil {
-					hashsumGpgPath := repo.IndexesRootURL().ResolveReference(&url.URL{Path: path + ".gpg"}).String()
-					var filesig *os.File
-					filesig, err = http.DownloadTemp(gocontext.TODO(), d, hashsumGpgPath)
-					if err != nil {
-						return err
-					}
+		return nil
+	}()
 
-					err = verifier.VerifyDetachedSignature(filesig, packagesFile, false)
-					if err != nil {
-						return err
-					}
+	if concurrency > 1 && progress != nil {
+		progress.ShutdownBar()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if repo.DownloadTranslations && !repo.IsFlat() {
+		err := repo.downloadTranslations(progress, d, ignoreMismatch)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// messagesOnlyProgress is aptly.Progress shared by parallel index downloads: messages
+// are passed to wrapped progress (serialized), while progress bar updates are ignored
+type messagesOnlyProgress struct {
+	sync.Mutex
+	progress aptly.Progress
+}
+
+// Check interface
+var (
+	_ aptly.Progress = &messagesOnlyProgress{}
+)
+
+func (p *messagesOnlyProgress) Write(s []byte) (int, error) { return len(s), nil }
+func (p *messagesOnlyProgress) Start()                      {}
+func (p *messagesOnlyProgress) Shutdown()                   {}
+func (p *messagesOnlyProgress) InitBar(int64, bool)         {}
+func (p *messagesOnlyProgress) ShutdownBar()                {}
+func (p *messagesOnlyProgress) AddBar(int)                  {}
+func (p *messagesOnlyProgress) SetBar(int)                  {}
+
+func (p *messagesOnlyProgress) Flush() {
+	p.Lock()
+	defer p.Unlock()
+
+	p.progress.Flush()
+}
+
+func (p *messagesOnlyProgress) Printf(msg string, a ...interface{}) {
+	p.Lock()
+	defer p.Unlock()
 
-					_, err = packagesFile.Seek(0, 0)
+	p.progress.Printf(msg, a...)
+}
+
+func (p *messagesOnlyProgress) ColoredPrintf(msg string, a ...interface{}) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.progress.ColoredPrintf(msg, a...)
+}
+
+func (p *messagesOnlyProgress) PrintfStdErr(msg string, a ...interface{}) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.progress.PrintfStdErr(msg, a...)
+}
+
+// packageIndexResult is result of downloading & parsing single package index
+type packageIndexResult struct {
+	packages []*Package
+	err      error
+}
+
+// downloadPackageIndex downloads & parses single package index, info is
+// [path, kind, component, architecture]; nil is returned if index is optional and missing
+func (repo *RemoteRepo) downloadPackageIndex(progress aptly.Progress, d aptly.Downloader, verifier pgp.Verifier,
+	ignoreMismatch bool, info []string) ([]*Package, error) {
+	path, kind, component, architecture := info[0], info[1], info[2], info[3]
+	isInstaller := kind == PackageTypeInstaller
+
+	var (
+		packages       []*Package
+		packagesReader io.Reader
+		packagesFile   *os.File
+		cacheFile      *os.File
+		err            error
+	)
+
+	usePDiffs := !isInstaller && repo.pdiffsAvailable(path)
+	if usePDiffs {
+		packagesFile, err = repo.updateIndexWithPDiffs(d, path)
+		if err != nil {
+			if progress != nil {
+				progress.ColoredPrintf("@y[!]@| @!unable to update %s with pdiffs: %s, downloading full index@|", path, err)
+			}
+		} else if packagesFile != nil {
+			packagesReader = packagesFile
+		}
+	}
+
+	if packagesFile == nil {
+		packagesReader, packagesFile, err = http.DownloadTryCompression(gocontext.TODO(), d, repo.IndexesRootURL(), path, repo.ReleaseFiles, ignoreMismatch)
+
+		if err == nil && usePDiffs {
+			// keep uncompressed copy of the index for the next pdiff update
+			cacheFile, err = repo.createIndexCacheFile(path)
+			if err != nil {
+				packagesFile.Close()
+				return nil, err
+			}
+			defer func() {
+				// drop incomplete cache file, unless it has been committed
+				if cacheFile != nil {
+					cacheFile.Close()
+					os.Remove(cacheFile.Name())
 				}
+			}()
+
+			packagesReader = io.TeeReader(packagesReader, cacheFile)
+		}
+	}
 
-				packagesReader = packagesFile
+	if err != nil {
+		if _, ok := err.(*http.NoCandidateFoundError); isInstaller && ok {
+			// checking if gpg file is only needed when checksums matches are required.
+			// otherwise there actually has been no candidate found and we can skip this index
+			if ignoreMismatch {
+				return nil, nil
 			}
 
+			// some repos do not have installer hashsum file listed in release file but provide a separate gpg file
+			hashsumPath := repo.IndexesRootURL().ResolveReference(&url.URL{Path: path}).String()
+			packagesFile, err = http.DownloadTemp(gocontext.TODO(), d, hashsumPath)
 			if err != nil {
-				return err
+				if herr, ok := err.(*http.Error); ok && (herr.Code == 404 || herr.Code == 403) {
+					// installer files are not available in all components and architectures
+					// so ignore it if not found
+					return nil, nil
+				}
+
+				return nil, err
+			}
+
+			if verifier != nil {
+				hashsumGpgPath := repo.IndexesRootURL().ResolveReference(&url.URL{Path: path + ".gpg"}).String()
+				var filesig *os.File
+				filesig, err = http.DownloadTemp(gocontext.TODO(), d, hashsumGpgPath)
+				if err != nil {
+					return nil, err
+				}
+
+				err = verifier.VerifyDetachedSignature(filesig, packagesFile, false)
+				if err != nil {
+					return nil, err
+				}
+
+				_, err = packagesFile.Seek(0, 0)
 			}
+
+			packagesReader = packagesFile
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer packagesFile.Close()
+
+	if progress != nil {
+		stat, _ := packagesFile.Stat()
+		progress.InitBar(stat.Size(), true)
+	}
+
+	sreader := NewControlFileReader(packagesReader, false, isInstaller)
+
+	for {
+		stanza, err := sreader.ReadStanza()
+		if err != nil {
+			return nil, err
+		}
+		if stanza == nil {
+			break
 		}
-		defer packagesFile.Close()
 
 		if progress != nil {
-			stat, _ := packagesFile.Stat()
-			progress.InitBar(stat.Size(), true)
+			off, _ := packagesFile.Seek(0, 1)
+			progress.SetBar(int(off))
 		}
 
-		sreader := NewControlFileReader(packagesReader, false, isInstaller)
+		var p *Package
 
-		for {
-			stanza, err := sreader.ReadStanza()
+		if kind == PackageTypeBinary {
+			p = NewPackageFromControlFile(stanza)
+		} else if kind == PackageTypeUdeb {
+			p = NewUdebPackageFromControlFile(stanza)
+		} else if kind == PackageTypeSource {
+			p, err = NewSourcePackageFromControlFile(stanza)
 			if err != nil {
-				return err
+				return nil, err
 			}
-			if stanza == nil {
+		} else if kind == PackageTypeInstaller {
+			p, err = NewInstallerPackageFromControlFile(stanza, repo, component, architecture, d)
+			if err != nil {
+				return nil, err
+			}
+		}
+		packages = append(packages, p)
+	}
+
+	if cacheFile != nil {
+		err = repo.commitIndexCacheFile(path, cacheFile)
+		cacheFile = nil
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if progress != nil {
+		progress.ShutdownBar()
+	}
+
+	return packages, nil
+}
+
+// pdiffsAvailable checks whether index could be updated with pdiffs
+func (repo *RemoteRepo) pdiffsAvailable(path string) bool {
+	if repo.indexCacheDir == "" {
+		return false
+	}
+
+	_, ok := repo.ReleaseFiles[path+".diff/Index"]
+	return ok
+}
+
+func (repo *RemoteRepo) indexCacheFilename(path string) string {
+	return filepath.Join(repo.IndexCachePath(repo.indexCacheDir), strings.Replace(path, "/", "_", -1))
+}
+
+// createIndexCacheFile creates temporary file to store downloaded index to
+func (repo *RemoteRepo) createIndexCacheFile(path string) (*os.File, error) {
+	filename := repo.indexCacheFilename(path)
+
+	err := os.MkdirAll(filepath.Dir(filename), 0777)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create index cache: %s", err)
+	}
+
+	return os.Create(filename + ".new")
+}
+
+// commitIndexCacheFile replaces cached index with completely downloaded one
+func (repo *RemoteRepo) commitIndexCacheFile(path string, cacheFile *os.File) error {
+	err := cacheFile.Close()
+	if err != nil {
+		return fmt.Errorf("unable to write index cache: %s", err)
+	}
+
+	return os.Rename(cacheFile.Name(), repo.indexCacheFilename(path))
+}
+
+// updateIndexWithPDiffs brings cached copy of the index to the current state by applying
+// pdiff patches, opened cached index is returned on success
+//
+// If there's no cached copy, nil file is returned
+func (repo *RemoteRepo) updateIndexWithPDiffs(d aptly.Downloader, path string) (*os.File, error) {
+	filename := repo.indexCacheFilename(path)
+
+	cached, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer cached.Close()
+
+	checksum, err := utils.ChecksumsForFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	indexPath := path + ".diff/Index"
+	expected := repo.ReleaseFiles[indexPath]
+
+	indexFile, err := http.DownloadTempWithChecksum(gocontext.TODO(), d, repo.IndexesRootURL().ResolveReference(&url.URL{Path: indexPath}).String(), &expected, false)
+	if err != nil {
+		return nil, err
+	}
+	defer indexFile.Close()
+
+	index, err := ParsePDiffIndex(indexFile)
+	if err != nil {
+		return nil, err
+	}
+
+	patches, err := index.PatchesFor(checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(patches) > 0 {
+		var (
+			current        io.Reader = cached
+			result         *os.File
+			checksumWriter *utils.ChecksumWriter
+		)
+
+		for _, patch := range patches {
+			var next *os.File
+			next, err = os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".patched.*")
+			if err != nil {
 				break
 			}
 
-			if progress != nil {
-				off, _ := packagesFile.Seek(0, 1)
-				progress.SetBar(int(off))
+			checksumWriter = utils.NewChecksumWriter()
+			err = repo.applyPDiffPatch(d, path, index, patch, current, io.MultiWriter(next, checksumWriter))
+			if err == nil {
+				_, err = next.Seek(0, 0)
 			}
 
-			var p *Package
+			if result != nil {
+				result.Close()
+				os.Remove(result.Name())
+			}
+			result, current = next, next
 
-			if kind == PackageTypeBinary {
-				p = NewPackageFromControlFile(stanza)
-			} else if kind == PackageTypeUdeb {
-				p = NewUdebPackageFromControlFile(stanza)
-			} else if kind == PackageTypeSource {
-				p, err = NewSourcePackageFromControlFile(stanza)
-				if err != nil {
-					return err
-				}
-			} else if kind == PackageTypeInstaller {
-				p, err = NewInstallerPackageFromControlFile(stanza, repo, component, architecture, d)
-				if err != nil {
-					return err
-				}
+			if err != nil {
+				break
 			}
-			err = repo.packageList.Add(p)
-			if _, ok := err.(*PackageConflictError); ok {
-				if progress != nil {
-					progress.ColoredPrintf("@y[!]@| @!skipping package %s: duplicate in packages index@|", p)
+		}
+
+		if err == nil && !PDiffChecksumMatches(index.Current, checksumWriter.Sum()) {
+			err = fmt.Errorf("checksum mismatch after applying patches")
+		}
+
+		if err != nil {
+			if result != nil {
+				result.Close()
+				os.Remove(result.Name())
+			}
+			return nil, err
+		}
+
+		err = repo.commitIndexCacheFile(path, result)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return os.Open(filename)
+}
+
+// applyPDiffPatch downloads single pdiff patch, verifies it and applies to src, writing result to dst
+func (repo *RemoteRepo) applyPDiffPatch(d aptly.Downloader, path string, index *PDiffIndex, patch PDiffEntry, src io.Reader, dst io.Writer) error {
+	patchURL := repo.IndexesRootURL().ResolveReference(&url.URL{Path: path + ".diff/" + patch.Name + ".gz"}).String()
+
+	var (
+		patchFile *os.File
+		err       error
+	)
+
+	if expected := index.DownloadChecksum(patch); expected != nil {
+		patchFile, err = http.DownloadTempWithChecksum(gocontext.TODO(), d, patchURL, expected, false)
+	} else {
+		patchFile, err = http.DownloadTemp(gocontext.TODO(), d, patchURL)
+	}
+	if err != nil {
+		return err
+	}
+	defer patchFile.Close()
+
+	gzReader, err := gzip.NewReader(patchFile)
+	if err != nil {
+		return fmt.Errorf("unable to read patch %s: %s", patch.Name, err)
+	}
+
+	// patch is verified before it is applied, so that corrupted patch doesn't produce garbage
+	checksumWriter := utils.NewChecksumWriter()
+	_, err = io.Copy(checksumWriter, gzReader)
+	if err != nil {
+		return fmt.Errorf("unable to read patch %s: %s", patch.Name, err)
+	}
+
+	if !PDiffChecksumMatches(patch.Checksum, checksumWriter.Sum()) {
+		return fmt.Errorf("checksum mismatch for patch %s", patch.Name)
+	}
+
+	_, err = patchFile.Seek(0, 0)
+	if err == nil {
+		err = gzReader.Reset(patchFile)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read patch %s: %s", patch.Name, err)
+	}
+
+	err = ApplyEdPatch(dst, src, gzReader)
+	if err != nil {
+		return fmt.Errorf("unable to apply patch %s: %s", patch.Name, err)
+	}
+
+	return nil
+}
+
+// TranslationLanguages returns list of languages which have Translation-<lang> indexes
+// for the component listed in Release file
+func (repo *RemoteRepo) TranslationLanguages(component string) []string {
+	prefix := component + "/i18n/Translation-"
+	langs := []string{}
+
+	for path := range repo.ReleaseFiles {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+
+		lang := strings.TrimPrefix(path, prefix)
+		for _, ext := range []string{".gz", ".bz2", ".xz"} {
+			lang = strings.TrimSuffix(lang, ext)
+		}
+
+		langs = append(langs, lang)
+	}
+
+	langs = utils.StrSliceDeduplicate(langs)
+	sort.Strings(langs)
+
+	return langs
+}
+
+// downloadTranslations downloads Translation-<lang> indexes and picks descriptions of downloaded packages,
+// translations are stored separately from packages in FinalizeDownload
+func (repo *RemoteRepo) downloadTranslations(progress aptly.Progress, d aptly.Downloader, ignoreMismatch bool) error {
+	repo.translations = map[string]map[string]string{}
+
+	for _, component := range repo.Components {
+		for _, lang := range repo.TranslationLanguages(component) {
+			path := component + "/i18n/Translation-" + lang
+
+			translationReader, translationFile, err := http.DownloadTryCompression(gocontext.TODO(), d, repo.IndexesRootURL(), path, repo.ReleaseFiles, ignoreMismatch)
+			if err != nil {
+				if _, ok := err.(*http.NoCandidateFoundError); ok {
+					// Release file might list only compression variants not supported by aptly
+					continue
 				}
-			} else if err != nil {
 				return err
 			}
-		}
 
-		if progress != nil {
-			progress.ShutdownBar()
+			translations, err := ParseTranslations(translationReader, lang)
+			translationFile.Close()
+			if err != nil {
+				return fmt.Errorf("unable to parse %s: %s", path, err)
+			}
+
+			applied := 0
+			_ = repo.packageList.ForEach(func(p *Package) error {
+				if !p.translatable() {
+					return nil
+				}
+
+				description, ok := translations[p.Extra()["Description-Md5"]]
+				if !ok {
+					return nil
+				}
+
+				if repo.translations[p.Extra()["Description-Md5"]] == nil {
+					repo.translations[p.Extra()["Description-Md5"]] = map[string]string{}
+				}
+				repo.translations[p.Extra()["Description-Md5"]][lang] = description
+				applied++
+
+				return nil
+			})
+
+			if progress != nil {
+				progress.Printf("Applied %d translated descriptions from %s\n", applied, path)
+			}
 		}
 	}
 
@@ -605,6 +1154,12 @@ func (repo *RemoteRepo) BuildDownloadQueue(packagePool aptly.PackagePool, packag
 			key := task.File.DownloadURL()
 			idx, found := seen[key]
 			if !found {
+				if len(repo.poolPeers) > 0 && !repo.IsLocal() && task.File.Checksums.SHA256 != "" {
+					for _, peer := range repo.poolPeers {
+						task.PeerURLs = append(task.PeerURLs, PoolPeerURL(peer, task.File))
+					}
+				}
+
 				queue = append(queue, task)
 				downloadSize += task.File.Checksums.Size
 				seen[key] = len(queue) - 1
@@ -623,7 +1178,19 @@ func (repo *RemoteRepo) BuildDownloadQueue(packagePool aptly.PackagePool, packag
 	return
 }
 
-// FinalizeDownload swaps for final value of package refs
+// SetDownloadStats records number of package files and bytes downloaded during update
+func (repo *RemoteRepo) SetDownloadStats(files int, bytes int64) {
+	repo.downloadedFiles = files
+	repo.downloadedBytes = bytes
+}
+
+// UpdateReport returns report of the update finished by FinalizeDownload, report should be
+// saved with RemoteRepoCollection.AddUpdateReport once mirror itself is saved
+func (repo *RemoteRepo) UpdateReport() *MirrorUpdateReport {
+	return repo.updateReport
+}
+
+// FinalizeDownload swaps for final value of package refs and builds update report
 func (repo *RemoteRepo) FinalizeDownload(collectionFactory *CollectionFactory, progress aptly.Progress) error {
 	transaction, err := collectionFactory.PackageCollection().db.OpenTransaction()
 	if err != nil {
@@ -639,6 +1206,9 @@ func (repo *RemoteRepo) FinalizeDownload(collectionFactory *CollectionFactory, p
 
 	var i int
 
+	provenance := NewMirrorProvenance(repo)
+	provenance.Date = repo.LastDownloadDate
+
 	// update all the packages in collection
 	err = repo.packageList.ForEach(func(p *Package) error {
 		i++
@@ -647,12 +1217,32 @@ func (repo *RemoteRepo) FinalizeDownload(collectionFactory *CollectionFactory, p
 		}
 		// download process might have updated checksums
 		p.UpdateFiles(p.Files())
-		return collectionFactory.PackageCollection().UpdateInTransaction(p, transaction)
+
+		// package extra is not available once package is saved
+		var translations map[string]string
+		if len(repo.translations) > 0 && p.translatable() {
+			translations = repo.translations[p.Extra()["Description-Md5"]]
+		}
+
+		e := collectionFactory.PackageCollection().UpdateInTransaction(p, transaction)
+		if e != nil {
+			return e
+		}
+		e = collectionFactory.PackageCollection().UpdateTranslationsInTransaction(p, translations, transaction)
+		if e != nil {
+			return e
+		}
+		return collectionFactory.PackageCollection().UpdateProvenanceInTransaction(p, provenance, transaction)
 	})
 
+	oldRefs := repo.packageRefs
+
 	if err == nil {
 		repo.packageRefs = NewPackageRefListFromPackageList(repo.packageList)
 		repo.packageList = nil
+		repo.translations = nil
+		repo.ExtraFiles = repo.extraFiles
+		repo.extraFiles = nil
 	}
 
 	if progress != nil {
@@ -662,7 +1252,26 @@ func (repo *RemoteRepo) FinalizeDownload(collectionFactory *CollectionFactory, p
 	if err != nil {
 		return err
 	}
-	return transaction.Commit()
+
+	err = transaction.Commit()
+	if err != nil {
+		return err
+	}
+
+	report, err := NewMirrorUpdateReport(repo, oldRefs, repo.packageRefs, collectionFactory.PackageCollection())
+	if err != nil {
+		return err
+	}
+
+	report.Date = repo.LastDownloadDate
+	report.DownloadedFiles, report.DownloadedBytes = repo.downloadedFiles, repo.downloadedBytes
+	if !repo.updateStarted.IsZero() {
+		report.Duration = report.Date.Sub(repo.updateStarted)
+	}
+
+	repo.updateReport = report
+
+	return nil
 }
 
 // Encode does msgpack encoding of RemoteRepo
@@ -927,5 +1536,11 @@ func (collection *RemoteRepoCollection) Drop(repo *RemoteRepo) error {
 		return err
 	}
 
+	for _, key := range collection.db.KeysByPrefix(repo.historyPrefix()) {
+		if err = transaction.Delete(key); err != nil {
+			return err
+		}
+	}
+
 	return transaction.Commit()
 }
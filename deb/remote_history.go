@@ -0,0 +1,180 @@
+package deb
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/ugorji/go/codec"
+)
+
+// MaxUpdateReports is number of latest update reports kept for each mirror
+const MaxUpdateReports = 100
+
+// MirrorPackageUpgrade is a package which version has changed during mirror update
+type MirrorPackageUpgrade struct {
+	Name         string
+	Architecture string
+	OldVersion   string
+	NewVersion   string
+}
+
+// MirrorUpdateReport is a changelog of single mirror update
+type MirrorUpdateReport struct {
+	// Mirror UUID and name (at the time of update)
+	MirrorUUID string
+	Mirror     string
+	// Time update has been finished
+	Date time.Time
+	// Duration of the update (from the start of package indexes download)
+	Duration time.Duration
+	// Number of package files and bytes downloaded
+	DownloadedFiles int
+	DownloadedBytes int64
+	// Package changes (as name_version_arch)
+	Added      []string
+	Removed    []string
+	Upgraded   []MirrorPackageUpgrade
+	Downgraded []MirrorPackageUpgrade
+}
+
+// NewMirrorUpdateReport builds report by comparing package lists before and after update
+func NewMirrorUpdateReport(repo *RemoteRepo, oldRefs, newRefs *PackageRefList, packageCollection *PackageCollection) (*MirrorUpdateReport, error) {
+	if oldRefs == nil {
+		oldRefs = NewPackageRefList()
+	}
+
+	diff, err := oldRefs.Diff(newRefs, packageCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &MirrorUpdateReport{
+		MirrorUUID: repo.UUID,
+		Mirror:     repo.Name,
+		Date:       time.Now(),
+		Added:      []string{},
+		Removed:    []string{},
+		Upgraded:   []MirrorPackageUpgrade{},
+		Downgraded: []MirrorPackageUpgrade{},
+	}
+
+	// packages with the same name & architecture on both sides are upgrades (or downgrades),
+	// they are paired here, as diff doesn't always pair them
+	type nameArch struct{ name, arch string }
+	removed := map[nameArch]*Package{}
+
+	for _, pdiff := range diff {
+		if pdiff.Left != nil && pdiff.Right == nil {
+			removed[nameArch{pdiff.Left.Name, pdiff.Left.Architecture}] = pdiff.Left
+		}
+	}
+
+	for _, pdiff := range diff {
+		left, right := pdiff.Left, pdiff.Right
+		if left == nil {
+			left = removed[nameArch{right.Name, right.Architecture}]
+			if left != nil {
+				delete(removed, nameArch{right.Name, right.Architecture})
+			}
+		} else if right == nil {
+			continue
+		}
+
+		if left == nil {
+			report.Added = append(report.Added, right.String())
+			continue
+		}
+
+		change := MirrorPackageUpgrade{
+			Name:         left.Name,
+			Architecture: left.Architecture,
+			OldVersion:   left.Version,
+			NewVersion:   right.Version,
+		}
+
+		if CompareVersions(change.OldVersion, change.NewVersion) > 0 {
+			report.Downgraded = append(report.Downgraded, change)
+		} else {
+			report.Upgraded = append(report.Upgraded, change)
+		}
+	}
+
+	for _, pdiff := range diff {
+		if pdiff.Left != nil && pdiff.Right == nil && removed[nameArch{pdiff.Left.Name, pdiff.Left.Architecture}] == pdiff.Left {
+			report.Removed = append(report.Removed, pdiff.Left.String())
+		}
+	}
+
+	return report, nil
+}
+
+// String returns summary of the report
+func (report *MirrorUpdateReport) String() string {
+	return fmt.Sprintf("%s: %d added, %d removed, %d upgraded, %d downgraded, %d files downloaded (%d bytes) in %s",
+		report.Date.Format(time.RFC3339), len(report.Added), len(report.Removed), len(report.Upgraded),
+		len(report.Downgraded), report.DownloadedFiles, report.DownloadedBytes, report.Duration.Round(time.Second))
+}
+
+// Key is a unique id in DB, reports of the mirror are sorted by date
+func (report *MirrorUpdateReport) Key() []byte {
+	return []byte(fmt.Sprintf("H%s%020d", report.MirrorUUID, report.Date.UnixNano()))
+}
+
+// Encode does msgpack encoding of MirrorUpdateReport
+func (report *MirrorUpdateReport) Encode() []byte {
+	var buf bytes.Buffer
+
+	encoder := codec.NewEncoder(&buf, &codec.MsgpackHandle{})
+	encoder.Encode(report)
+
+	return buf.Bytes()
+}
+
+// Decode decodes msgpack representation into MirrorUpdateReport
+func (report *MirrorUpdateReport) Decode(input []byte) error {
+	decoder := codec.NewDecoderBytes(input, &codec.MsgpackHandle{})
+	return decoder.Decode(report)
+}
+
+// historyPrefix is a DB prefix of update reports of the mirror
+func (repo *RemoteRepo) historyPrefix() []byte {
+	return []byte("H" + repo.UUID)
+}
+
+// AddUpdateReport saves report of the mirror update, only MaxUpdateReports latest
+// reports of the mirror are kept
+func (collection *RemoteRepoCollection) AddUpdateReport(report *MirrorUpdateReport) error {
+	err := collection.db.Put(report.Key(), report.Encode())
+	if err != nil {
+		return err
+	}
+
+	// keys are sorted by date, oldest first
+	keys := collection.db.KeysByPrefix([]byte("H" + report.MirrorUUID))
+	for len(keys) > MaxUpdateReports {
+		if err = collection.db.Delete(keys[0]); err != nil {
+			return err
+		}
+		keys = keys[1:]
+	}
+
+	return nil
+}
+
+// UpdateReports returns reports of mirror updates, oldest first
+func (collection *RemoteRepoCollection) UpdateReports(repo *RemoteRepo) ([]*MirrorUpdateReport, error) {
+	result := []*MirrorUpdateReport{}
+
+	err := collection.db.ProcessByPrefix(repo.historyPrefix(), func(key, blob []byte) error {
+		report := &MirrorUpdateReport{}
+		if err := report.Decode(blob); err != nil {
+			return fmt.Errorf("error decoding mirror update report: %s", err)
+		}
+
+		result = append(result, report)
+		return nil
+	})
+
+	return result, err
+}
@@ -1,11 +1,18 @@
 package deb
 
 import (
+	"bytes"
+	"compress/gzip"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/aptly-dev/aptly/aptly"
 	"github.com/aptly-dev/aptly/console"
@@ -111,6 +118,37 @@ func (s *RemoteRepoSuite) TestInvalidURL(c *C) {
 	c.Assert(err, ErrorMatches, ".*(hexadecimal escape in host|percent-encoded characters in host|invalid URL escape).*")
 }
 
+func (s *RemoteRepoSuite) TestLocalArchive(c *C) {
+	c.Check(s.repo.IsLocal(), Equals, false)
+
+	repo, err := NewRemoteRepo("local", "/srv/debian", "squeeze", []string{"main"}, []string{}, false, false, false)
+	c.Assert(err, IsNil)
+	c.Check(repo.ArchiveRoot, Equals, "file:///srv/debian/")
+	c.Check(repo.IsLocal(), Equals, true)
+	c.Check(repo.ReleaseURL("Release").String(), Equals, "file:///srv/debian/dists/squeeze/Release")
+	c.Check(repo.PackageURL("pool/main/a/aptly.deb").Path, Equals, "/srv/debian/pool/main/a/aptly.deb")
+
+	repo, err = NewRemoteRepo("local", "file:///srv/debian", "squeeze", []string{"main"}, []string{}, false, false, false)
+	c.Assert(err, IsNil)
+	c.Check(repo.IsLocal(), Equals, true)
+
+	cwd, _ := os.Getwd()
+	repo, err = NewRemoteRepo("local", "./debian", "squeeze", []string{"main"}, []string{}, false, false, false)
+	c.Assert(err, IsNil)
+	c.Check(repo.ArchiveRoot, Equals, "file://"+filepath.Join(cwd, "debian")+"/")
+
+	// URL with missing scheme is not a path
+	_, err = NewRemoteRepo("local", "deb.debian.org/debian", "squeeze", []string{"main"}, []string{}, false, false, false)
+	c.Check(err, ErrorMatches, "archive root deb.debian.org/debian is neither URL nor .*")
+
+	_, err = NewRemoteRepo("local", "debian", "squeeze", []string{"main"}, []string{}, false, false, false)
+	c.Check(err, NotNil)
+
+	c.Check(repo.SetArchiveRoot("deb.debian.org/debian"), NotNil)
+	c.Check(repo.SetArchiveRoot("/srv/debian"), IsNil)
+	c.Check(repo.ArchiveRoot, Equals, "file:///srv/debian/")
+}
+
 func (s *RemoteRepoSuite) TestFlatCreation(c *C) {
 	c.Check(s.flat.IsFlat(), Equals, true)
 	c.Check(s.flat.Distribution, Equals, "./")
@@ -286,8 +324,20 @@ func (s *RemoteRepoSuite) TestDownload(c *C) {
 	c.Check(size, Equals, int64(3))
 	c.Check(queue, HasLen, 1)
 	c.Check(queue[0].File.DownloadURL(), Equals, "pool/main/a/amanda/amanda-client_3.3.1-3~bpo60+1_amd64.deb")
+	c.Check(queue[0].PeerURLs, HasLen, 0)
 
-	s.repo.FinalizeDownload(s.collectionFactory, nil)
+	s.repo.SetPoolPeers([]string{"http://peer1:8080", "http://peer2/aptly/"})
+	queue, _, err = s.repo.BuildDownloadQueue(s.packagePool, s.collectionFactory.PackageCollection(), s.cs, false)
+	c.Assert(err, IsNil)
+	c.Check(queue[0].PeerURLs, DeepEquals, []string{
+		"http://peer1:8080/api/pool/sha256/3608bca1e44ea6c4d268eb6db02260269892c0b42b86bbf1e77a6fa16c3c9282/amanda-client_3.3.1-3~bpo60+1_amd64.deb?size=3",
+		"http://peer2/aptly/api/pool/sha256/3608bca1e44ea6c4d268eb6db02260269892c0b42b86bbf1e77a6fa16c3c9282/amanda-client_3.3.1-3~bpo60+1_amd64.deb?size=3",
+	})
+	s.repo.SetPoolPeers(nil)
+
+	s.repo.SetDownloadStats(1, 3)
+	err = s.repo.FinalizeDownload(s.collectionFactory, nil)
+	c.Assert(err, IsNil)
 	c.Assert(s.repo.packageRefs, NotNil)
 
 	pkg, err := s.collectionFactory.PackageCollection().ByKey(s.repo.packageRefs.Refs[0])
@@ -295,6 +345,21 @@ func (s *RemoteRepoSuite) TestDownload(c *C) {
 
 	c.Check(pkg.Name, Equals, "amanda-client")
 
+	report := s.repo.UpdateReport()
+	c.Assert(report, NotNil)
+	c.Check(report.Mirror, Equals, "yandex")
+	c.Check(report.Added, DeepEquals, []string{"amanda-client_1:3.3.1-3~bpo60+1_i386"})
+	c.Check(report.Removed, HasLen, 0)
+	c.Check(report.Upgraded, HasLen, 0)
+	c.Check(report.DownloadedFiles, Equals, 1)
+	c.Check(report.DownloadedBytes, Equals, int64(3))
+	c.Check(report.Duration > 0, Equals, true)
+
+	// report is saved only when mirror is saved
+	reports, err := s.collectionFactory.RemoteRepoCollection().UpdateReports(s.repo)
+	c.Assert(err, IsNil)
+	c.Check(reports, HasLen, 0)
+
 	// Next call must return an empty download list with option "skip-existing-packages"
 	s.downloader.ExpectResponse("http://mirror.yandex.ru/debian/dists/squeeze/Release", exampleReleaseFile)
 	err = s.repo.Fetch(s.downloader, nil)
@@ -382,6 +447,236 @@ func (s *RemoteRepoSuite) TestDownloadWithInstaller(c *C) {
 	c.Check(pkg.Name, Equals, "installer")
 }
 
+func (s *RemoteRepoSuite) TestDownloadWithPDiffs(c *C) {
+	checksum := func(data string) utils.ChecksumInfo {
+		w := utils.NewChecksumWriter()
+		w.Write([]byte(data))
+		return w.Sum()
+	}
+
+	updatedPackagesFile := strings.Replace(examplePackagesFile, "Priority: optional", "Priority: extra", 1)
+	lineNo := strings.Count(examplePackagesFile[:strings.Index(examplePackagesFile, "Priority: optional")], "\n") + 1
+	patch := fmt.Sprintf("%dc\nPriority: extra\n.\n", lineNo)
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	gz.Write([]byte(patch))
+	gz.Close()
+	compressedPatch := buf.String()
+
+	pdiffIndex := fmt.Sprintf("SHA256-Current: %s %d\nSHA256-History:\n %s %d T-1\nSHA256-Patches:\n %s %d T-1\nSHA256-Download:\n %s %d T-1.gz\n",
+		checksum(updatedPackagesFile).SHA256, len(updatedPackagesFile),
+		checksum(examplePackagesFile).SHA256, len(examplePackagesFile),
+		checksum(patch).SHA256, len(patch),
+		checksum(compressedPatch).SHA256, len(compressedPatch))
+
+	cacheDir := c.MkDir()
+	s.repo.Architectures = []string{"i386"}
+	s.repo.SetIndexCacheDir(cacheDir)
+	s.repo.ReleaseFiles = map[string]utils.ChecksumInfo{
+		"main/binary-i386/Packages":            checksum(examplePackagesFile),
+		"main/binary-i386/Packages.diff/Index": checksum(pdiffIndex),
+	}
+
+	// no cached index yet, full index is downloaded
+	downloader := http.NewFakeDownloader()
+	downloader.ExpectResponse("http://mirror.yandex.ru/debian/dists/squeeze/main/binary-i386/Packages", examplePackagesFile)
+
+	err := s.repo.DownloadPackageIndexes(s.progress, downloader, nil, s.collectionFactory, false)
+	c.Assert(err, IsNil)
+	c.Assert(downloader.Empty(), Equals, true)
+
+	cached := filepath.Join(s.repo.IndexCachePath(cacheDir), "main_binary-i386_Packages")
+	contents, _ := ioutil.ReadFile(cached)
+	c.Check(string(contents), Equals, examplePackagesFile)
+
+	// index is brought up to date with patches
+	s.repo.packageList = nil
+	s.repo.ReleaseFiles["main/binary-i386/Packages"] = checksum(updatedPackagesFile)
+
+	downloader = http.NewFakeDownloader()
+	downloader.ExpectResponse("http://mirror.yandex.ru/debian/dists/squeeze/main/binary-i386/Packages.diff/Index", pdiffIndex)
+	downloader.ExpectResponse("http://mirror.yandex.ru/debian/dists/squeeze/main/binary-i386/Packages.diff/T-1.gz", compressedPatch)
+
+	err = s.repo.DownloadPackageIndexes(s.progress, downloader, nil, s.collectionFactory, false)
+	c.Assert(err, IsNil)
+	c.Assert(downloader.Empty(), Equals, true)
+	c.Assert(s.repo.packageList.Len(), Equals, 1)
+
+	s.repo.packageList.ForEach(func(p *Package) error {
+		c.Check(p.Name, Equals, "amanda-client")
+		c.Check(p.Extra()["Priority"], Equals, "extra")
+		return nil
+	})
+
+	contents, _ = ioutil.ReadFile(cached)
+	c.Check(string(contents), Equals, updatedPackagesFile)
+
+	// cached index is unknown to the pdiff history, falling back to full download
+	c.Assert(ioutil.WriteFile(cached, []byte("garbage\n"), 0644), IsNil)
+	s.repo.packageList = nil
+
+	downloader = http.NewFakeDownloader()
+	downloader.ExpectResponse("http://mirror.yandex.ru/debian/dists/squeeze/main/binary-i386/Packages.diff/Index", pdiffIndex)
+	downloader.ExpectResponse("http://mirror.yandex.ru/debian/dists/squeeze/main/binary-i386/Packages", updatedPackagesFile)
+
+	err = s.repo.DownloadPackageIndexes(s.progress, downloader, nil, s.collectionFactory, false)
+	c.Assert(err, IsNil)
+	c.Assert(downloader.Empty(), Equals, true)
+
+	contents, _ = ioutil.ReadFile(cached)
+	c.Check(string(contents), Equals, updatedPackagesFile)
+}
+
+func (s *RemoteRepoSuite) TestDownloadParallel(c *C) {
+	checksum := func(data string) utils.ChecksumInfo {
+		w := utils.NewChecksumWriter()
+		w.Write([]byte(data))
+		return w.Sum()
+	}
+
+	amd64PackagesFile := strings.Replace(examplePackagesFile, "Architecture: i386", "Architecture: amd64", 1)
+	// same package as in main, but with different contents
+	contribPackagesFile := strings.Replace(examplePackagesFile, "Priority: optional", "Priority: extra", 1)
+
+	indexes := map[string]string{
+		"main/binary-i386/Packages":     examplePackagesFile,
+		"main/binary-amd64/Packages":    amd64PackagesFile,
+		"contrib/binary-i386/Packages":  contribPackagesFile,
+		"contrib/binary-amd64/Packages": amd64PackagesFile,
+	}
+
+	for i := 0; i < 5; i++ {
+		s.repo.packageList = nil
+		s.repo.Components = []string{"main", "contrib"}
+		s.repo.Architectures = []string{"i386", "amd64"}
+		s.repo.SetIndexConcurrency(4)
+		s.repo.ReleaseFiles = map[string]utils.ChecksumInfo{}
+
+		downloader := http.NewFakeDownloader()
+		for path, contents := range indexes {
+			s.repo.ReleaseFiles[path] = checksum(contents)
+			downloader.AnyExpectResponse("http://mirror.yandex.ru/debian/dists/squeeze/"+path, contents)
+		}
+
+		err := s.repo.DownloadPackageIndexes(s.progress, downloader, nil, s.collectionFactory, false)
+		c.Assert(err, IsNil)
+		c.Assert(s.repo.packageList.Len(), Equals, 2)
+
+		// duplicate is resolved in favor of the index listed first
+		s.repo.packageList.ForEach(func(p *Package) error {
+			c.Check(p.Extra()["Priority"], Equals, "optional")
+			return nil
+		})
+	}
+
+	// errors are reported regardless of the order of completion
+	s.repo.packageList = nil
+	downloader := http.NewFakeDownloader()
+	downloader.AnyExpectResponse("http://mirror.yandex.ru/debian/dists/squeeze/main/binary-i386/Packages", examplePackagesFile)
+
+	err := s.repo.DownloadPackageIndexes(s.progress, downloader, nil, s.collectionFactory, false)
+	c.Assert(err, ErrorMatches, ".*unexpected request.*")
+}
+
+type recordingProgress struct {
+	aptly.Progress
+	sync.Mutex
+	messages []string
+}
+
+func (p *recordingProgress) ColoredPrintf(msg string, a ...interface{}) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.messages = append(p.messages, fmt.Sprintf(msg, a...))
+}
+
+func (s *RemoteRepoSuite) TestDownloadParallelWarnings(c *C) {
+	checksum := func(data string) utils.ChecksumInfo {
+		w := utils.NewChecksumWriter()
+		w.Write([]byte(data))
+		return w.Sum()
+	}
+
+	cacheDir := c.MkDir()
+	s.repo.Components = []string{"main", "contrib"}
+	s.repo.Architectures = []string{"i386"}
+	s.repo.SetIndexConcurrency(2)
+	s.repo.SetIndexCacheDir(cacheDir)
+	s.repo.ReleaseFiles = map[string]utils.ChecksumInfo{
+		"main/binary-i386/Packages":            checksum(examplePackagesFile),
+		"main/binary-i386/Packages.diff/Index": {},
+		"contrib/binary-i386/Packages":         checksum(examplePackagesFile),
+	}
+
+	// cached index is present, but pdiff index is not available
+	c.Assert(os.MkdirAll(s.repo.IndexCachePath(cacheDir), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(s.repo.IndexCachePath(cacheDir), "main_binary-i386_Packages"), []byte("garbage\n"), 0644), IsNil)
+
+	downloader := http.NewFakeDownloader()
+	downloader.AnyExpectResponse("http://mirror.yandex.ru/debian/dists/squeeze/main/binary-i386/Packages", examplePackagesFile)
+	downloader.AnyExpectResponse("http://mirror.yandex.ru/debian/dists/squeeze/contrib/binary-i386/Packages", examplePackagesFile)
+
+	progress := &recordingProgress{Progress: s.progress}
+
+	err := s.repo.DownloadPackageIndexes(progress, downloader, nil, s.collectionFactory, false)
+	c.Assert(err, IsNil)
+
+	c.Assert(progress.messages, HasLen, 1)
+	c.Check(progress.messages[0], Matches, ".*unable to update main/binary-i386/Packages with pdiffs: .*")
+}
+
+func (s *RemoteRepoSuite) TestTranslationLanguages(c *C) {
+	s.repo.ReleaseFiles = map[string]utils.ChecksumInfo{
+		"main/binary-i386/Packages":      {},
+		"main/i18n/Translation-en":       {},
+		"main/i18n/Translation-en.bz2":   {},
+		"main/i18n/Translation-pt_BR.xz": {},
+		"contrib/i18n/Translation-de":    {},
+	}
+
+	c.Check(s.repo.TranslationLanguages("main"), DeepEquals, []string{"en", "pt_BR"})
+	c.Check(s.repo.TranslationLanguages("contrib"), DeepEquals, []string{"de"})
+	c.Check(s.repo.TranslationLanguages("non-free"), DeepEquals, []string{})
+}
+
+func (s *RemoteRepoSuite) TestDownloadTranslations(c *C) {
+	s.repo.Architectures = []string{"i386"}
+	s.repo.DownloadTranslations = true
+
+	err := s.repo.Fetch(s.downloader, nil)
+	c.Assert(err, IsNil)
+
+	translation := "Package: amanda-client\nDescription-md5: 21af3684379a64cacc51c39152ab1062\nDescription-de: Amanda (Client)\n Langer Text.\n\n"
+	checksums := utils.NewChecksumWriter()
+	checksums.Write([]byte(translation))
+	s.repo.ReleaseFiles["main/i18n/Translation-de"] = checksums.Sum()
+
+	s.downloader.ExpectError("http://mirror.yandex.ru/debian/dists/squeeze/main/binary-i386/Packages.bz2", &http.Error{Code: 404})
+	s.downloader.ExpectError("http://mirror.yandex.ru/debian/dists/squeeze/main/binary-i386/Packages.gz", &http.Error{Code: 404})
+	s.downloader.ExpectResponse("http://mirror.yandex.ru/debian/dists/squeeze/main/binary-i386/Packages", examplePackagesFile)
+	s.downloader.ExpectResponse("http://mirror.yandex.ru/debian/dists/squeeze/main/i18n/Translation-de", translation)
+
+	err = s.repo.DownloadPackageIndexes(s.progress, s.downloader, nil, s.collectionFactory, false)
+	c.Assert(err, IsNil)
+	c.Assert(s.downloader.Empty(), Equals, true)
+
+	err = s.repo.FinalizeDownload(s.collectionFactory, nil)
+	c.Assert(err, IsNil)
+
+	// translations are not part of package record
+	pkg, err := s.collectionFactory.PackageCollection().ByKey(s.repo.packageRefs.Refs[0])
+	c.Assert(err, IsNil)
+	c.Check(pkg.Extra()["Description"], Equals, " Advanced Maryland Automatic Network Disk Archiver (Client)\n")
+	_, ok := pkg.Extra()["Description-de"]
+	c.Check(ok, Equals, false)
+
+	translations, err := s.collectionFactory.PackageCollection().Translations(s.repo.packageRefs.Refs[0])
+	c.Assert(err, IsNil)
+	c.Check(translations, DeepEquals, map[string]string{"de": " Amanda (Client)\n Langer Text.\n"})
+}
+
 func (s *RemoteRepoSuite) TestDownloadWithSources(c *C) {
 	s.repo.Architectures = []string{"i386"}
 	s.repo.DownloadSources = true
@@ -777,6 +1072,106 @@ func (s *RemoteRepoCollectionSuite) TestDrop(c *C) {
 	c.Check(s.collection.Drop(repo1), ErrorMatches, "repo not found")
 }
 
+func (s *RemoteRepoCollectionSuite) TestUpdateReports(c *C) {
+	repo1, _ := NewRemoteRepo("yandex", "http://mirror.yandex.ru/debian/", "squeeze", []string{"main"}, []string{}, false, false, false)
+	s.collection.Add(repo1)
+
+	repo2, _ := NewRemoteRepo("tyndex", "http://mirror.yandex.ru/debian/", "wheezy", []string{"main"}, []string{}, false, false, false)
+	s.collection.Add(repo2)
+
+	now := time.Now()
+	for i, repo := range []*RemoteRepo{repo1, repo2, repo1} {
+		report := &MirrorUpdateReport{MirrorUUID: repo.UUID, Mirror: repo.Name, Date: now.Add(time.Duration(i) * time.Hour),
+			Added: []string{fmt.Sprintf("pkg%d_1.0_i386", i)}}
+		c.Assert(s.collection.AddUpdateReport(report), IsNil)
+	}
+
+	reports, err := s.collection.UpdateReports(repo1)
+	c.Assert(err, IsNil)
+	c.Assert(reports, HasLen, 2)
+	c.Check(reports[0].Added, DeepEquals, []string{"pkg0_1.0_i386"})
+	c.Check(reports[1].Added, DeepEquals, []string{"pkg2_1.0_i386"})
+	c.Check(reports[1].Date.Equal(now.Add(2*time.Hour)), Equals, true)
+
+	c.Assert(s.collection.Drop(repo1), IsNil)
+
+	reports, err = s.collection.UpdateReports(repo1)
+	c.Assert(err, IsNil)
+	c.Check(reports, HasLen, 0)
+
+	reports, err = s.collection.UpdateReports(repo2)
+	c.Assert(err, IsNil)
+	c.Check(reports, HasLen, 1)
+}
+
+func (s *RemoteRepoCollectionSuite) TestUpdateReportsPruned(c *C) {
+	repo, _ := NewRemoteRepo("yandex", "http://mirror.yandex.ru/debian/", "squeeze", []string{"main"}, []string{}, false, false, false)
+	s.collection.Add(repo)
+
+	now := time.Now()
+	for i := 0; i < MaxUpdateReports+5; i++ {
+		report := &MirrorUpdateReport{MirrorUUID: repo.UUID, Mirror: repo.Name, Date: now.Add(time.Duration(i) * time.Hour),
+			DownloadedFiles: i}
+		c.Assert(s.collection.AddUpdateReport(report), IsNil)
+	}
+
+	reports, err := s.collection.UpdateReports(repo)
+	c.Assert(err, IsNil)
+	c.Assert(reports, HasLen, MaxUpdateReports)
+	c.Check(reports[0].DownloadedFiles, Equals, 5)
+	c.Check(reports[MaxUpdateReports-1].DownloadedFiles, Equals, MaxUpdateReports+4)
+}
+
+func (s *RemoteRepoCollectionSuite) TestNewMirrorUpdateReport(c *C) {
+	packageCollection := NewPackageCollection(s.db)
+
+	stanza := packageStanza.Copy()
+	stanza["Version"] = "7.40-3"
+	newer := NewPackageFromControlFile(stanza)
+	older := NewPackageFromControlFile(packageStanza.Copy())
+
+	stanza = packageStanza.Copy()
+	stanza["Package"] = "mars-invaders"
+	removed := NewPackageFromControlFile(stanza)
+
+	stanza = packageStanza.Copy()
+	stanza["Package"] = "lonely-strangers"
+	added := NewPackageFromControlFile(stanza)
+
+	stanza = packageStanza.Copy()
+	stanza["Package"] = "unknown-planet"
+	stanza["Version"] = "2.0"
+	upgradedFrom := NewPackageFromControlFile(stanza)
+
+	stanza = packageStanza.Copy()
+	stanza["Package"] = "unknown-planet"
+	stanza["Version"] = "2.0+b1"
+	upgradedTo := NewPackageFromControlFile(stanza)
+
+	for _, p := range []*Package{newer, older, removed, added, upgradedFrom, upgradedTo} {
+		c.Assert(packageCollection.Update(p), IsNil)
+	}
+
+	oldList := NewPackageList()
+	c.Assert(oldList.Add(newer), IsNil)
+	c.Assert(oldList.Add(removed), IsNil)
+	c.Assert(oldList.Add(upgradedFrom), IsNil)
+
+	newList := NewPackageList()
+	c.Assert(newList.Add(older), IsNil)
+	c.Assert(newList.Add(added), IsNil)
+	c.Assert(newList.Add(upgradedTo), IsNil)
+
+	repo, _ := NewRemoteRepo("yandex", "http://mirror.yandex.ru/debian/", "squeeze", []string{"main"}, []string{}, false, false, false)
+
+	report, err := NewMirrorUpdateReport(repo, NewPackageRefListFromPackageList(oldList), NewPackageRefListFromPackageList(newList), packageCollection)
+	c.Assert(err, IsNil)
+	c.Check(report.Added, DeepEquals, []string{added.String()})
+	c.Check(report.Removed, DeepEquals, []string{removed.String()})
+	c.Check(report.Upgraded, DeepEquals, []MirrorPackageUpgrade{{Name: "unknown-planet", Architecture: "i386", OldVersion: "2.0", NewVersion: "2.0+b1"}})
+	c.Check(report.Downgraded, DeepEquals, []MirrorPackageUpgrade{{Name: "alien-arena-common", Architecture: "i386", OldVersion: "7.40-3", NewVersion: "7.40-2"}})
+}
+
 const exampleReleaseFile = `Origin: LP-PPA-agenda-developers-daily
 Label: Agenda Daily Builds
 Suite: precise
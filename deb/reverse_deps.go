@@ -0,0 +1,192 @@
+package deb
+
+import (
+	"sort"
+)
+
+// ReverseDependency is a package which depends on some other package
+type ReverseDependency struct {
+	// Package which has the dependency
+	Package *Package
+	// Field dependency comes from (Depends, Pre-Depends, ...)
+	Field string
+	// Dependency as written in the field (with all the variants)
+	Dependency string
+
+	dep Dependency
+}
+
+// ReverseDependencyIndex answers the question "which packages in the list depend on package X"
+type ReverseDependencyIndex struct {
+	// dependency package name -> dependencies naming that package
+	index map[string][]*ReverseDependency
+}
+
+// packageDependencyField is a list of dependencies from one field of the package
+type packageDependencyField struct {
+	name         string
+	dependencies []string
+}
+
+// dependencyFields returns dependency fields of the package which are followed according to options
+func (p *Package) dependencyFields(options int) []packageDependencyField {
+	deps := p.Deps()
+
+	fields := []packageDependencyField{
+		{"Pre-Depends", deps.PreDepends},
+		{"Depends", deps.Depends},
+	}
+
+	if options&DepFollowRecommends == DepFollowRecommends {
+		fields = append(fields, packageDependencyField{"Recommends", deps.Recommends})
+	}
+
+	if options&DepFollowSuggests == DepFollowSuggests {
+		fields = append(fields, packageDependencyField{"Suggests", deps.Suggests})
+	}
+
+	if options&DepFollowBuild == DepFollowBuild {
+		fields = append(fields,
+			packageDependencyField{"Build-Depends", deps.BuildDepends},
+			packageDependencyField{"Build-Depends-Indep", deps.BuildDependsInDep})
+	}
+
+	return fields
+}
+
+// NewReverseDependencyIndex builds reverse dependency index for the package list,
+// dependencies are followed according to options (DepFollowRecommends, DepFollowSuggests, DepFollowBuild)
+func NewReverseDependencyIndex(list *PackageList, options int) *ReverseDependencyIndex {
+	idx := &ReverseDependencyIndex{
+		index: make(map[string][]*ReverseDependency),
+	}
+
+	list.ForEach(func(p *Package) error {
+		for _, field := range p.dependencyFields(options) {
+			for _, dependency := range field.dependencies {
+				variants, err := ParseDependencyVariants(dependency)
+				if err != nil {
+					// broken dependencies can't point to any package
+					continue
+				}
+
+				for _, dep := range variants {
+					idx.index[dep.Pkg] = append(idx.index[dep.Pkg], &ReverseDependency{
+						Package:    p,
+						Field:      field.name,
+						Dependency: dependency,
+						dep:        dep,
+					})
+				}
+			}
+		}
+
+		return nil
+	})
+
+	return idx
+}
+
+// Lookup returns packages from the list which depend on package p, either directly by name
+// or via virtual package p provides, results are sorted by package and field
+func (idx *ReverseDependencyIndex) Lookup(p *Package) []*ReverseDependency {
+	result := []*ReverseDependency{}
+
+	if p.IsSource {
+		// source packages can't satisfy dependencies
+		return result
+	}
+
+	type seenKey struct {
+		pkg               *Package
+		field, dependency string
+	}
+	seen := make(map[seenKey]bool)
+
+	names := append([]string{p.Name}, p.ProvidedNames()...)
+	for _, name := range names {
+		for _, rdep := range idx.index[name] {
+			if rdep.Package.Equals(p) {
+				continue
+			}
+
+			if !rdep.Package.IsSource && !p.MatchesArchitecture(rdep.Package.Architecture) &&
+				rdep.Package.Architecture != ArchitectureAll {
+				continue
+			}
+
+			if !p.MatchesDependency(rdep.dep) {
+				continue
+			}
+
+			key := seenKey{rdep.Package, rdep.Field, rdep.Dependency}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			result = append(result, rdep)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		iName, jName := result[i].Package.String(), result[j].Package.String()
+		if iName != jName {
+			return iName < jName
+		}
+		if result[i].Field != result[j].Field {
+			return result[i].Field < result[j].Field
+		}
+		return result[i].Dependency < result[j].Dependency
+	})
+
+	return result
+}
+
+// reverseDependsQueries returns $ReverseDepends queries from the query tree
+func reverseDependsQueries(query PackageQuery) (result []*FieldQuery) {
+	switch q := query.(type) {
+	case *OrQuery:
+		result = append(reverseDependsQueries(q.L), reverseDependsQueries(q.R)...)
+	case *AndQuery:
+		result = append(reverseDependsQueries(q.L), reverseDependsQueries(q.R)...)
+	case *NotQuery:
+		result = reverseDependsQueries(q.Q)
+	case *FieldQuery:
+		if q.Field == "$ReverseDepends" {
+			result = []*FieldQuery{q}
+		}
+	}
+
+	return
+}
+
+// HasReverseDependsQuery returns true if query uses $ReverseDepends, which could be resolved
+// only by filtering package list (see PackageList.Filter), not by scanning package collection
+func HasReverseDependsQuery(query PackageQuery) bool {
+	return len(reverseDependsQueries(query)) > 0
+}
+
+// prepareReverseDependsQueries resolves $ReverseDepends queries in the query tree against
+// the whole list, so that they match the same packages as ReverseDependencyIndex reports
+// no matter which subset of the list they're evaluated against later on
+func prepareReverseDependsQueries(query PackageQuery, list *PackageList) {
+	queries := reverseDependsQueries(query)
+	if len(queries) == 0 {
+		return
+	}
+
+	idx := NewReverseDependencyIndex(list, DepFollowBuild)
+
+	for _, q := range queries {
+		q.reverseDepends = make(map[*Package]bool)
+		list.ForEach(func(p *Package) error {
+			if q.matchesValue(p.Name) {
+				for _, rdep := range idx.Lookup(p) {
+					q.reverseDepends[rdep.Package] = true
+				}
+			}
+			return nil
+		})
+	}
+}
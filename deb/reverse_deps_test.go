@@ -0,0 +1,98 @@
+package deb
+
+import (
+	"regexp"
+	"sort"
+
+	. "gopkg.in/check.v1"
+)
+
+type ReverseDependencySuite struct {
+	list     *PackageList
+	packages map[string]*Package
+}
+
+var _ = Suite(&ReverseDependencySuite{})
+
+func (s *ReverseDependencySuite) SetUpTest(c *C) {
+	s.list = NewPackageList()
+	s.packages = map[string]*Package{}
+
+	for _, p := range []*Package{
+		{Name: "dpkg", Version: "1.7", Architecture: "i386", Provides: []string{"package-installer"}, deps: &PackageDependencies{}},
+		{Name: "lib", Version: "1.0", Architecture: "i386", deps: &PackageDependencies{PreDepends: []string{"dpkg (>= 1.6)"}}},
+		{Name: "data", Version: "1.1", Architecture: "all", deps: &PackageDependencies{PreDepends: []string{"dpkg (>= 1.6)"}}},
+		{Name: "mailer", Version: "3.5.8", Architecture: "i386", Provides: []string{"mail-agent"}, deps: &PackageDependencies{}},
+		{Name: "app", Version: "1.1", Architecture: "i386", deps: &PackageDependencies{Depends: []string{"lib (>> 0.9)", "data (>= 1.0) | mail-agent"}}},
+		{Name: "app", Version: "1.1", Architecture: "amd64", deps: &PackageDependencies{PreDepends: []string{"dpkg (>= 1.6)"}}},
+		{Name: "old", Version: "0.1", Architecture: "i386", deps: &PackageDependencies{Depends: []string{"dpkg (<< 1.5)"}}},
+		{Name: "installer", Version: "2.0", Architecture: "i386", deps: &PackageDependencies{Depends: []string{"package-installer"}}},
+		{Name: "tool", Version: "2.0", Architecture: "i386", deps: &PackageDependencies{Recommends: []string{"dpkg"}}},
+		{Name: "app", Version: "1.1", Architecture: "source", IsSource: true, deps: &PackageDependencies{BuildDepends: []string{"dpkg (>= 1.0)"}}},
+	} {
+		s.list.Add(p)
+		s.packages[p.String()] = p
+	}
+}
+
+func (s *ReverseDependencySuite) lookup(idx *ReverseDependencyIndex, key string) []string {
+	result := []string{}
+	for _, rdep := range idx.Lookup(s.packages[key]) {
+		result = append(result, rdep.Package.String()+" "+rdep.Field+": "+rdep.Dependency)
+	}
+	return result
+}
+
+func (s *ReverseDependencySuite) TestLookup(c *C) {
+	idx := NewReverseDependencyIndex(s.list, 0)
+
+	c.Check(s.lookup(idx, "dpkg_1.7_i386"), DeepEquals, []string{
+		"data_1.1_all Pre-Depends: dpkg (>= 1.6)",
+		"installer_2.0_i386 Depends: package-installer",
+		"lib_1.0_i386 Pre-Depends: dpkg (>= 1.6)",
+	})
+	c.Check(s.lookup(idx, "data_1.1_all"), DeepEquals, []string{"app_1.1_i386 Depends: data (>= 1.0) | mail-agent"})
+	c.Check(s.lookup(idx, "mailer_3.5.8_i386"), DeepEquals, []string{"app_1.1_i386 Depends: data (>= 1.0) | mail-agent"})
+	c.Check(s.lookup(idx, "app_1.1_i386"), DeepEquals, []string{})
+	c.Check(s.lookup(idx, "app_1.1_source"), DeepEquals, []string{})
+}
+
+func (s *ReverseDependencySuite) TestLookupOptions(c *C) {
+	idx := NewReverseDependencyIndex(s.list, DepFollowRecommends|DepFollowBuild)
+
+	c.Check(s.lookup(idx, "dpkg_1.7_i386"), DeepEquals, []string{
+		"app_1.1_source Build-Depends: dpkg (>= 1.0)",
+		"data_1.1_all Pre-Depends: dpkg (>= 1.6)",
+		"installer_2.0_i386 Depends: package-installer",
+		"lib_1.0_i386 Pre-Depends: dpkg (>= 1.6)",
+		"tool_2.0_i386 Recommends: dpkg",
+	})
+}
+
+func (s *ReverseDependencySuite) filter(c *C, q PackageQuery) []string {
+	s.list.PrepareIndex()
+
+	result, err := s.list.Filter([]PackageQuery{q}, false, nil, 0, nil)
+	c.Assert(err, IsNil)
+
+	names := result.Strings()
+	sort.Strings(names)
+	return names
+}
+
+func (s *ReverseDependencySuite) TestReverseDependsQuery(c *C) {
+	// same packages as reported by reverse dependency index: versions, architectures and provides are honored
+	c.Check(s.filter(c, &FieldQuery{Field: "$ReverseDepends", Relation: VersionEqual, Value: "dpkg"}), DeepEquals,
+		[]string{"Pall data 1.1", "Pi386 installer 2.0", "Pi386 lib 1.0", "Psource app 1.1"})
+
+	c.Check(s.filter(c, &FieldQuery{Field: "$ReverseDepends", Relation: VersionRegexp, Value: "^mail", Regexp: regexp.MustCompile("^mail")}),
+		DeepEquals, []string{"Pi386 app 1.1"})
+
+	// package depended upon doesn't have to match the rest of the query
+	c.Check(s.filter(c, &AndQuery{
+		L: &FieldQuery{Field: "$Architecture", Relation: VersionEqual, Value: "all"},
+		R: &FieldQuery{Field: "$ReverseDepends", Relation: VersionEqual, Value: "dpkg"},
+	}), DeepEquals, []string{"Pall data 1.1"})
+
+	c.Check(s.filter(c, &FieldQuery{Field: "$ReverseDepends", Relation: VersionEqual, Value: "missing"}), DeepEquals, []string{})
+}
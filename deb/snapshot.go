@@ -35,6 +35,9 @@ type Snapshot struct {
 	NotAutomatic         string
 	ButAutomaticUpgrades string
 
+	// Auxiliary metadata files (dep11, cnf, Contents) of the mirror snapshot was created from
+	ExtraFiles []ExtraFile `codec:"ExtraFiles,omitempty" json:"-"`
+
 	packageRefs *PackageRefList
 }
 
@@ -54,6 +57,7 @@ func NewSnapshotFromRepository(name string, repo *RemoteRepo) (*Snapshot, error)
 		Origin:               repo.Meta["Origin"],
 		NotAutomatic:         repo.Meta["NotAutomatic"],
 		ButAutomaticUpgrades: repo.Meta["ButAutomaticUpgrades"],
+		ExtraFiles:           repo.ExtraFiles,
 		packageRefs:          repo.packageRefs,
 	}, nil
 }
@@ -0,0 +1,226 @@
+package deb
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aptly-dev/aptly/database"
+	"github.com/ugorji/go/codec"
+)
+
+// TranslationFieldPrefix is prefix of translated description fields (Description-<lang>)
+const TranslationFieldPrefix = "Description-"
+
+// isTranslationField checks whether field is translated description (Description-<lang>)
+func isTranslationField(field string) bool {
+	return strings.HasPrefix(field, TranslationFieldPrefix) && !strings.EqualFold(field, "Description-md5")
+}
+
+// DescriptionMD5 calculates Description-md5 of package description as stored in stanza
+// (short description with leading space, followed by long description lines)
+func DescriptionMD5(description string) string {
+	description = strings.TrimPrefix(description, " ")
+	if !strings.HasSuffix(description, "\n") {
+		description += "\n"
+	}
+
+	return fmt.Sprintf("%x", md5.Sum([]byte(description)))
+}
+
+// ParseTranslations reads Translation-<lang> index, returning map of Description-md5 to
+// translated description
+func ParseTranslations(r io.Reader, lang string) (map[string]string, error) {
+	result := map[string]string{}
+	field := TranslationFieldPrefix + lang
+
+	reader := NewControlFileReader(r, false, false)
+	for {
+		stanza, err := reader.ReadStanza()
+		if err != nil {
+			return nil, err
+		}
+		if stanza == nil {
+			break
+		}
+
+		md5sum := stanza["Description-Md5"]
+		description := stanza[field]
+		if md5sum == "" || description == "" {
+			continue
+		}
+
+		result[md5sum] = description
+	}
+
+	return result, nil
+}
+
+// translatable checks whether translated descriptions are published for the package: source,
+// installer and udeb packages don't have translations
+func (p *Package) translatable() bool {
+	return !p.IsSource && !p.IsInstaller && !p.IsUdeb
+}
+
+// translationKey is a DB key of package translations
+func translationKey(key []byte) []byte {
+	return append([]byte("xT"), key...)
+}
+
+// Translations loads translated descriptions (by language) of the package by package key, nil is
+// returned if there are no translations
+//
+// Translations are kept out of package record, so that they are loaded only when publishing.
+func (collection *PackageCollection) Translations(key []byte) (map[string]string, error) {
+	encoded, err := collection.db.Get(translationKey(key))
+	if err != nil {
+		if err == database.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	translations := map[string]string{}
+
+	decoder := codec.NewDecoderBytes(encoded, collection.codecHandle)
+	err = decoder.Decode(&translations)
+	if err != nil {
+		return nil, err
+	}
+
+	return translations, nil
+}
+
+// UpdateTranslationsInTransaction stores translated descriptions of the package in the context
+// of the outer transaction, replacing previously stored ones (if there are no translations,
+// previously stored ones are removed)
+func (collection *PackageCollection) UpdateTranslationsInTransaction(p *Package, translations map[string]string, transaction database.Transaction) error {
+	if len(translations) == 0 {
+		return transaction.Delete(translationKey(p.Key("")))
+	}
+
+	var buf bytes.Buffer
+
+	err := codec.NewEncoder(&buf, collection.codecHandle).Encode(translations)
+	if err != nil {
+		return err
+	}
+
+	return transaction.Put(translationKey(p.Key("")), buf.Bytes())
+}
+
+// removeTranslations removes translated descriptions (Description-<lang>) from the stanza, returning
+// them by language
+func removeTranslations(stanza Stanza) map[string]string {
+	translations := map[string]string{}
+	for field, value := range stanza {
+		if isTranslationField(field) {
+			translations[field[len(TranslationFieldPrefix):]] = value
+			delete(stanza, field)
+		}
+	}
+
+	return translations
+}
+
+// translationIndexes writes Translation-<lang> indexes for a component while publishing
+type translationIndexes struct {
+	indexes    *indexFiles
+	collection *PackageCollection
+	component  string
+	enabled    bool
+	seen       map[string]struct{}
+}
+
+func newTranslationIndexes(indexes *indexFiles, collection *PackageCollection, component string, enabled bool) *translationIndexes {
+	return &translationIndexes{
+		indexes:    indexes,
+		collection: collection,
+		component:  component,
+		enabled:    enabled,
+		seen:       map[string]struct{}{},
+	}
+}
+
+// Process moves translated descriptions of the package (from the stanza and stored ones) into
+// Translation-<lang> indexes, Description-md5 is added to the stanza when missing
+//
+// If translations are disabled, translated descriptions are just removed from the stanza
+func (t *translationIndexes) Process(pkg *Package, stanza Stanza) error {
+	translations := removeTranslations(stanza)
+
+	description := stanza["Description"]
+	if !t.enabled || description == "" {
+		return nil
+	}
+
+	if t.collection != nil {
+		stored, err := t.collection.Translations(pkg.Key(""))
+		if err != nil {
+			return err
+		}
+
+		for lang, translation := range stored {
+			if _, ok := translations[lang]; !ok {
+				translations[lang] = translation
+			}
+		}
+	}
+
+	calculated := DescriptionMD5(description)
+	md5sum := stanza["Description-Md5"]
+	if md5sum == "" {
+		md5sum = calculated
+		stanza["Description-Md5"] = md5sum
+	}
+
+	// if Description-md5 doesn't match, description in the stanza is not the full one
+	if _, ok := translations["en"]; !ok && md5sum == calculated {
+		translations["en"] = description
+	}
+
+	langs := make([]string, 0, len(translations))
+	for lang := range translations {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	for _, lang := range langs {
+		key := lang + " " + pkg.Name + " " + md5sum
+		if _, ok := t.seen[key]; ok {
+			continue
+		}
+		t.seen[key] = struct{}{}
+
+		w, err := t.indexes.TranslationIndex(t.component, lang).BufWriter()
+		if err != nil {
+			return err
+		}
+
+		err = writeTranslation(w, pkg.Name, md5sum, lang, translations[lang])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeTranslation(w *bufio.Writer, name, md5sum, lang, description string) error {
+	err := writeField(w, "Package", name, false)
+	if err == nil {
+		err = writeField(w, "Description-md5", md5sum, false)
+	}
+	if err == nil {
+		err = writeField(w, TranslationFieldPrefix+lang, description, false)
+	}
+	if err == nil {
+		err = w.WriteByte('\n')
+	}
+
+	return err
+}
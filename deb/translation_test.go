@@ -0,0 +1,174 @@
+package deb
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/aptly-dev/aptly/database"
+	"github.com/aptly-dev/aptly/database/goleveldb"
+
+	. "gopkg.in/check.v1"
+)
+
+type TranslationSuite struct {
+	db      database.Storage
+	factory *CollectionFactory
+}
+
+var _ = Suite(&TranslationSuite{})
+
+func (s *TranslationSuite) SetUpTest(c *C) {
+	s.db, _ = goleveldb.NewOpenDB(c.MkDir())
+	s.factory = NewCollectionFactory(s.db)
+}
+
+func (s *TranslationSuite) TearDownTest(c *C) {
+	s.db.Close()
+}
+
+func (s *TranslationSuite) TestDescriptionMD5(c *C) {
+	c.Check(DescriptionMD5(" SMBIOS/DMI table decoder (udeb)\n"), Equals, "bdfb786c6a57097be8c8600b800e749f")
+	c.Check(DescriptionMD5(" SMBIOS/DMI table decoder (udeb)"), Equals, "bdfb786c6a57097be8c8600b800e749f")
+}
+
+func (s *TranslationSuite) TestIsTranslationField(c *C) {
+	c.Check(isTranslationField("Description-en"), Equals, true)
+	c.Check(isTranslationField("Description-pt_BR"), Equals, true)
+	c.Check(isTranslationField("Description-Md5"), Equals, false)
+	c.Check(isTranslationField("Description"), Equals, false)
+	c.Check(canonicalCase("description-pt_BR"), Equals, "Description-pt_BR")
+	c.Check(canonicalCase("Description-md5"), Equals, "Description-Md5")
+}
+
+func (s *TranslationSuite) TestParseTranslations(c *C) {
+	translations, err := ParseTranslations(strings.NewReader(`Package: dmidecode
+Description-md5: 1234
+Description-de: SMBIOS/DMI-Tabellen-Dekoder
+ Langer Text.
+ .
+ Zweiter Absatz.
+
+Package: broken
+Description-md5: 5678
+
+`), "de")
+	c.Assert(err, IsNil)
+	c.Check(translations, DeepEquals, map[string]string{
+		"1234": " SMBIOS/DMI-Tabellen-Dekoder\n Langer Text.\n .\n Zweiter Absatz.\n",
+	})
+}
+
+func (s *TranslationSuite) TestStoredTranslations(c *C) {
+	p := NewPackageFromControlFile(packageStanza.Copy())
+	collection := s.factory.PackageCollection()
+	c.Assert(collection.Update(p), IsNil)
+
+	translations, err := collection.Translations(p.Key(""))
+	c.Assert(err, IsNil)
+	c.Check(translations, IsNil)
+
+	transaction, err := s.db.OpenTransaction()
+	c.Assert(err, IsNil)
+	c.Assert(collection.UpdateTranslationsInTransaction(p, map[string]string{"de": " Kurz\n"}, transaction), IsNil)
+	c.Assert(transaction.Commit(), IsNil)
+
+	translations, err = collection.Translations(p.Key(""))
+	c.Assert(err, IsNil)
+	c.Check(translations, DeepEquals, map[string]string{"de": " Kurz\n"})
+
+	// package record is not affected
+	p2, err := collection.ByKey(p.Key(""))
+	c.Assert(err, IsNil)
+	c.Check(p2.Extra(), DeepEquals, p.Extra())
+
+	// translations dropped upstream are removed
+	transaction, err = s.db.OpenTransaction()
+	c.Assert(err, IsNil)
+	c.Assert(collection.UpdateTranslationsInTransaction(p, nil, transaction), IsNil)
+	c.Assert(transaction.Commit(), IsNil)
+
+	translations, err = collection.Translations(p.Key(""))
+	c.Assert(err, IsNil)
+	c.Check(translations, IsNil)
+
+	transaction, err = s.db.OpenTransaction()
+	c.Assert(err, IsNil)
+	c.Assert(collection.UpdateTranslationsInTransaction(p, map[string]string{"de": " Kurz\n"}, transaction), IsNil)
+	c.Assert(transaction.Commit(), IsNil)
+
+	// translations are removed along with the package
+	batch := s.db.CreateBatch()
+	c.Assert(collection.DeleteByKey(p.Key(""), batch), IsNil)
+	c.Assert(batch.Write(), IsNil)
+
+	translations, err = collection.Translations(p.Key(""))
+	c.Assert(err, IsNil)
+	c.Check(translations, IsNil)
+}
+
+func (s *TranslationSuite) TestProcess(c *C) {
+	pkg := &Package{Name: "aptly", Version: "1.0", Architecture: "i386"}
+
+	indexes := newIndexFiles(nil, "", c.MkDir(), "", false)
+	translations := newTranslationIndexes(indexes, nil, "main", true)
+
+	stanza := Stanza{"Package": "aptly", "Description": " Short\n long\n", "Description-de": " Kurz\n lang\n"}
+	c.Assert(translations.Process(pkg, stanza.Copy()), IsNil)
+	// same package for another architecture
+	c.Assert(translations.Process(pkg, stanza), IsNil)
+
+	c.Check(stanza["Description-Md5"], Equals, DescriptionMD5(" Short\n long\n"))
+	_, ok := stanza["Description-de"]
+	c.Check(ok, Equals, false)
+
+	md5sum := stanza["Description-Md5"]
+	for lang, expected := range map[string]string{
+		"en": "Package: aptly\nDescription-md5: " + md5sum + "\nDescription-en: Short\n long\n\n",
+		"de": "Package: aptly\nDescription-md5: " + md5sum + "\nDescription-de: Kurz\n lang\n\n",
+	} {
+		file := indexes.TranslationIndex("main", lang)
+		c.Assert(file.w.Flush(), IsNil)
+
+		contents, err := ioutil.ReadFile(file.tempFilename)
+		c.Assert(err, IsNil)
+		c.Check(string(contents), Equals, expected)
+	}
+
+	// short description with upstream Description-md5 is not published as English translation
+	translations = newTranslationIndexes(newIndexFiles(nil, "", c.MkDir(), "", false), nil, "main", true)
+	stanza = Stanza{"Package": "aptly", "Description": " Short\n", "Description-Md5": "1234"}
+	c.Assert(translations.Process(pkg, stanza), IsNil)
+	c.Check(translations.indexes.indexes, HasLen, 0)
+
+	// stored translations are published
+	transaction, err := s.db.OpenTransaction()
+	c.Assert(err, IsNil)
+	c.Assert(s.factory.PackageCollection().UpdateTranslationsInTransaction(pkg, map[string]string{"en": " Short\n long\n"}, transaction), IsNil)
+	c.Assert(transaction.Commit(), IsNil)
+
+	indexes = newIndexFiles(nil, "", c.MkDir(), "", false)
+	translations = newTranslationIndexes(indexes, s.factory.PackageCollection(), "main", true)
+	stanza = Stanza{"Package": "aptly", "Description": " Short\n", "Description-Md5": "1234"}
+	c.Assert(translations.Process(pkg, stanza), IsNil)
+	c.Check(stanza, DeepEquals, Stanza{"Package": "aptly", "Description": " Short\n", "Description-Md5": "1234"})
+
+	file := indexes.TranslationIndex("main", "en")
+	c.Assert(file.w.Flush(), IsNil)
+	contents, err := ioutil.ReadFile(file.tempFilename)
+	c.Assert(err, IsNil)
+	c.Check(string(contents), Equals, "Package: aptly\nDescription-md5: 1234\nDescription-en: Short\n long\n\n")
+
+	// translations disabled: fields are just stripped
+	translations = newTranslationIndexes(newIndexFiles(nil, "", c.MkDir(), "", false), s.factory.PackageCollection(), "main", false)
+	stanza = Stanza{"Package": "aptly", "Description": " Short\n", "Description-de": " Kurz\n"}
+	c.Assert(translations.Process(pkg, stanza), IsNil)
+	c.Check(stanza, DeepEquals, Stanza{"Package": "aptly", "Description": " Short\n"})
+	c.Check(translations.indexes.indexes, HasLen, 0)
+}
+
+func (s *TranslationSuite) TestTranslatable(c *C) {
+	c.Check((&Package{Name: "aptly"}).translatable(), Equals, true)
+	c.Check((&Package{Name: "aptly", IsSource: true}).translatable(), Equals, false)
+	c.Check((&Package{Name: "aptly-udeb", IsUdeb: true}).translatable(), Equals, false)
+	c.Check((&Package{Name: "installer", IsInstaller: true}).translatable(), Equals, false)
+}
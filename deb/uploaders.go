@@ -10,6 +10,10 @@ import (
 	"github.com/aptly-dev/aptly/utils"
 )
 
+// UploaderUserPrefix marks entries in allow/deny lists which refer to authenticated API users
+// (as opposed to PGP key IDs), e.g. "user:jane"
+const UploaderUserPrefix = "user:"
+
 // UploadersRule is single rule of format: what packages can group or key upload
 type UploadersRule struct {
 	Condition         string       `json:"condition"`
@@ -23,7 +27,7 @@ func (u UploadersRule) String() string {
 	return string(b)
 }
 
-// Uploaders is configuration of restrictions for .changes file importing
+// Uploaders is configuration of restrictions for package importing
 type Uploaders struct {
 	Groups map[string][]string `json:"groups"`
 	Rules  []UploadersRule     `json:"rules"`
@@ -79,25 +83,52 @@ func (u *Uploaders) ExpandGroups(items []string) []string {
 	return utils.StrSliceDeduplicate(result)
 }
 
+// Compile parses conditions of all the rules into package queries
+func (u *Uploaders) Compile(parseQuery parseQuery) error {
+	var err error
+
+	for i := range u.Rules {
+		u.Rules[i].CompiledCondition, err = parseQuery(u.Rules[i].Condition)
+		if err != nil {
+			return fmt.Errorf("error parsing query %s: %s", u.Rules[i].Condition, err)
+		}
+	}
+
+	return nil
+}
+
 // IsAllowed checks whether listed keys are allowed to upload given .changes file
 func (u *Uploaders) IsAllowed(changes *Changes) error {
+	// .changes files are always expected to be signed, even if rule allows '*'
+	if len(changes.SignatureKeys) == 0 {
+		return fmt.Errorf("denied as no rule matches")
+	}
+
+	return u.IsAllowedPackage(changes, changes.SignatureKeys, "")
+}
+
+// IsAllowedPackage checks whether package (or .changes file) could be uploaded
+// by the owner of one of the signature keys or by authenticated API user (if not empty)
+func (u *Uploaders) IsAllowedPackage(pkg PackageLike, keys []pgp.Key, user string) error {
+	identities := append([]pgp.Key(nil), keys...)
+	if user != "" {
+		identities = append(identities, pgp.Key(UploaderUserPrefix+user))
+	}
+
 	for _, rule := range u.Rules {
-		if rule.CompiledCondition.Matches(changes) {
+		if rule.CompiledCondition.Matches(pkg) {
+			// "*" matches any upload, including unsigned ones without any identity
 			deny := u.ExpandGroups(rule.Deny)
-			for _, key := range changes.SignatureKeys {
-				for _, item := range deny {
-					if item == "*" || key.Matches(pgp.Key(item)) {
-						return fmt.Errorf("denied according to rule: %s", rule)
-					}
+			for _, item := range deny {
+				if item == "*" || matchesAnyIdentity(identities, pgp.Key(item)) {
+					return fmt.Errorf("denied according to rule: %s", rule)
 				}
 			}
 
 			allow := u.ExpandGroups(rule.Allow)
-			for _, key := range changes.SignatureKeys {
-				for _, item := range allow {
-					if item == "*" || key.Matches(pgp.Key(item)) {
-						return nil
-					}
+			for _, item := range allow {
+				if item == "*" || matchesAnyIdentity(identities, pgp.Key(item)) {
+					return nil
 				}
 			}
 		}
@@ -105,3 +136,14 @@ func (u *Uploaders) IsAllowed(changes *Changes) error {
 
 	return fmt.Errorf("denied as no rule matches")
 }
+
+// matchesAnyIdentity checks whether any of the identities matches key
+func matchesAnyIdentity(identities []pgp.Key, key pgp.Key) bool {
+	for _, identity := range identities {
+		if identity.Matches(key) {
+			return true
+		}
+	}
+
+	return false
+}
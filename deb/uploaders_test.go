@@ -1,6 +1,8 @@
 package deb
 
 import (
+	"fmt"
+
 	"github.com/aptly-dev/aptly/pgp"
 	. "gopkg.in/check.v1"
 )
@@ -79,3 +81,69 @@ func (s *UploadersSuite) TestIsAllowed(c *C) {
 	c.Check(u.IsAllowed(&Changes{SignatureKeys: []pgp.Key{"ABCD1234", "45678901"}, Stanza: Stanza{"Source": "some-calamares"}}),
 		ErrorMatches, "denied according to rule: {\"condition\":\"\",\"allow\":null,\"deny\":\\[\"45678901\",\"12345678\"\\]}")
 }
+
+func (s *UploadersSuite) TestIsAllowedPackage(c *C) {
+	u := &Uploaders{
+		Groups: map[string][]string{
+			"admins": {"user:jane", "37E1C17570096AD1"},
+		},
+		Rules: []UploadersRule{
+			{
+				CompiledCondition: &FieldQuery{Field: "Name", Relation: VersionEqual, Value: "calamares"},
+				Allow:             []string{"admins"},
+			},
+			{
+				CompiledCondition: &FieldQuery{Field: "Name", Relation: VersionEqual, Value: "calamares"},
+				Deny:              []string{"user:bob"},
+			},
+		},
+	}
+
+	pkg := &Package{Name: "calamares", Version: "1.0", Architecture: "amd64"}
+
+	// no identities
+	c.Check(u.IsAllowedPackage(pkg, nil, ""), ErrorMatches, "denied as no rule matches")
+
+	// allowed by key or by user from the group
+	c.Check(u.IsAllowedPackage(pkg, []pgp.Key{"70096AD1"}, ""), IsNil)
+	c.Check(u.IsAllowedPackage(pkg, nil, "jane"), IsNil)
+
+	// users and keys are not interchangeable
+	c.Check(u.IsAllowedPackage(pkg, []pgp.Key{"jane"}, ""), ErrorMatches, "denied as no rule matches")
+	c.Check(u.IsAllowedPackage(pkg, nil, "37E1C17570096AD1"), ErrorMatches, "denied as no rule matches")
+
+	// denied user
+	c.Check(u.IsAllowedPackage(pkg, nil, "bob"), ErrorMatches, "denied according to rule: .*user:bob.*")
+	c.Check(u.IsAllowedPackage(&Package{Name: "other"}, nil, "jane"), ErrorMatches, "denied as no rule matches")
+
+	// unsigned .deb uploaded anonymously is accepted by rule allowing "*"
+	u.Rules = append(u.Rules,
+		UploadersRule{
+			CompiledCondition: &FieldQuery{Field: "Name", Relation: VersionEqual, Value: "public"},
+			Allow:             []string{"*"},
+		},
+		UploadersRule{
+			CompiledCondition: &FieldQuery{Field: "Name", Relation: VersionEqual, Value: "private"},
+			Deny:              []string{"*"},
+		})
+	c.Check(u.IsAllowedPackage(&Package{Name: "public", Version: "1.0", Architecture: "amd64"}, nil, ""), IsNil)
+	c.Check(u.IsAllowedPackage(&Package{Name: "private", Version: "1.0", Architecture: "amd64"}, nil, ""),
+		ErrorMatches, "denied according to rule: .*")
+}
+
+func (s *UploadersSuite) TestCompile(c *C) {
+	u := &Uploaders{
+		Rules: []UploadersRule{
+			{Condition: "Source (calamares)"},
+		},
+	}
+
+	c.Check(u.Compile(func(q string) (PackageQuery, error) {
+		return &FieldQuery{Field: "Source", Relation: VersionEqual, Value: "calamares"}, nil
+	}), IsNil)
+	c.Check(u.Rules[0].CompiledCondition, NotNil)
+
+	c.Check(u.Compile(func(q string) (PackageQuery, error) {
+		return nil, fmt.Errorf("bad query")
+	}), ErrorMatches, "error parsing query Source \\(calamares\\): bad query")
+}
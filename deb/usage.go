@@ -0,0 +1,245 @@
+package deb
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aptly-dev/aptly/aptly"
+)
+
+// Kinds of objects referencing package files
+const (
+	UsageMirror    = "mirror"
+	UsageLocalRepo = "repo"
+	UsageSnapshot  = "snapshot"
+	UsagePublished = "publish"
+)
+
+// UsageEntry is storage usage of a single mirror, local repo, snapshot or published repository
+type UsageEntry struct {
+	// Kind of the object (UsageMirror, UsageLocalRepo, ...) and its name
+	Kind string
+	Name string
+	// Number of packages and files in the pool referenced by the object
+	Packages int
+	Files    int
+	// Size of all the files referenced by the object
+	TotalSize int64
+	// Size of the files referenced only by the object (would be freed if object were dropped)
+	UniqueSize int64
+}
+
+// UsageReport is storage usage of the package pool
+type UsageReport struct {
+	// Number and size of all the files referenced by any object
+	Files     int
+	TotalSize int64
+	// Usage per object
+	Entries []*UsageEntry
+}
+
+type usageObject struct {
+	entry *UsageEntry
+	refs  []*PackageRefList
+	// auxiliary files referenced directly (not via packages)
+	extraFiles []ExtraFile
+	// pool paths of all the referenced files
+	paths []string
+}
+
+type usageFile struct {
+	size int64
+	// number of objects referencing the file
+	objects int
+}
+
+// ComputeUsage calculates storage usage of package pool per mirror, local repo, snapshot and
+// published repository based on package reference lists and sizes of package files
+//
+// Published repositories are counted as objects on their own, so files of published snapshot
+// are not unique to the snapshot (dropping the snapshot wouldn't free them while it's published).
+func ComputeUsage(collectionFactory *CollectionFactory, packagePool aptly.PackagePool, progress aptly.Progress) (*UsageReport, error) {
+	objects := []*usageObject{}
+
+	err := collectionFactory.RemoteRepoCollection().ForEach(func(repo *RemoteRepo) error {
+		e := collectionFactory.RemoteRepoCollection().LoadComplete(repo)
+		if e != nil {
+			return e
+		}
+
+		objects = append(objects, &usageObject{
+			entry:      &UsageEntry{Kind: UsageMirror, Name: repo.Name},
+			refs:       []*PackageRefList{repo.RefList()},
+			extraFiles: repo.ExtraFiles,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = collectionFactory.LocalRepoCollection().ForEach(func(repo *LocalRepo) error {
+		e := collectionFactory.LocalRepoCollection().LoadComplete(repo)
+		if e != nil {
+			return e
+		}
+
+		objects = append(objects, &usageObject{
+			entry: &UsageEntry{Kind: UsageLocalRepo, Name: repo.Name},
+			refs:  []*PackageRefList{repo.RefList()},
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = collectionFactory.SnapshotCollection().ForEach(func(snapshot *Snapshot) error {
+		e := collectionFactory.SnapshotCollection().LoadComplete(snapshot)
+		if e != nil {
+			return e
+		}
+
+		objects = append(objects, &usageObject{
+			entry:      &UsageEntry{Kind: UsageSnapshot, Name: snapshot.Name},
+			refs:       []*PackageRefList{snapshot.RefList()},
+			extraFiles: snapshot.ExtraFiles,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = collectionFactory.PublishedRepoCollection().ForEach(func(published *PublishedRepo) error {
+		e := collectionFactory.PublishedRepoCollection().LoadComplete(published, collectionFactory)
+		if e != nil {
+			return e
+		}
+
+		object := &usageObject{
+			entry: &UsageEntry{Kind: UsagePublished, Name: fmt.Sprintf("%s/%s", published.StoragePrefix(), published.Distribution)},
+		}
+		for _, component := range published.Components() {
+			object.refs = append(object.refs, published.RefList(component))
+		}
+
+		objects = append(objects, object)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if progress != nil {
+		progress.InitBar(int64(len(objects)), false)
+	}
+
+	// pool paths and sizes of package files, cached by package key
+	packageFiles := map[string][]string{}
+	poolFiles := map[string]*usageFile{}
+
+	addFile := func(object *usageObject, seen map[string]bool, path string, size int64) {
+		if path == "" || seen[path] {
+			return
+		}
+		seen[path] = true
+
+		file := poolFiles[path]
+		if file == nil {
+			file = &usageFile{size: size}
+			poolFiles[path] = file
+		}
+		file.objects++
+
+		object.paths = append(object.paths, path)
+	}
+
+	for _, object := range objects {
+		seen := map[string]bool{}
+		packages := map[string]bool{}
+
+		for _, refs := range object.refs {
+			if refs == nil {
+				continue
+			}
+
+			err = refs.ForEach(func(key []byte) error {
+				packages[string(key)] = true
+
+				paths, ok := packageFiles[string(key)]
+				if !ok {
+					p, e := collectionFactory.PackageCollection().ByKey(key)
+					if e != nil {
+						return fmt.Errorf("unable to load package %s: %s", key, e)
+					}
+
+					for _, f := range p.Files() {
+						path, e := f.GetPoolPath(packagePool)
+						if e != nil {
+							return e
+						}
+
+						paths = append(paths, path)
+						if poolFiles[path] == nil {
+							poolFiles[path] = &usageFile{size: f.Checksums.Size}
+						}
+					}
+					packageFiles[string(key)] = paths
+				}
+
+				for _, path := range paths {
+					addFile(object, seen, path, poolFiles[path].size)
+				}
+
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, f := range object.extraFiles {
+			addFile(object, seen, f.PoolPath, f.Checksums.Size)
+		}
+
+		object.entry.Packages = len(packages)
+
+		if progress != nil {
+			progress.AddBar(1)
+		}
+	}
+
+	if progress != nil {
+		progress.ShutdownBar()
+	}
+
+	report := &UsageReport{Entries: make([]*UsageEntry, 0, len(objects))}
+
+	for _, file := range poolFiles {
+		if file.objects > 0 {
+			report.Files++
+			report.TotalSize += file.size
+		}
+	}
+
+	for _, object := range objects {
+		for _, path := range object.paths {
+			file := poolFiles[path]
+
+			object.entry.Files++
+			object.entry.TotalSize += file.size
+			if file.objects == 1 {
+				object.entry.UniqueSize += file.size
+			}
+		}
+
+		report.Entries = append(report.Entries, object.entry)
+	}
+
+	sort.SliceStable(report.Entries, func(i, j int) bool {
+		return report.Entries[i].UniqueSize > report.Entries[j].UniqueSize
+	})
+
+	return report, nil
+}
@@ -0,0 +1,78 @@
+package deb
+
+import (
+	"github.com/aptly-dev/aptly/database"
+	"github.com/aptly-dev/aptly/database/goleveldb"
+	"github.com/aptly-dev/aptly/files"
+
+	. "gopkg.in/check.v1"
+)
+
+type UsageSuite struct {
+	db                database.Storage
+	collectionFactory *CollectionFactory
+	packagePool       *files.PackagePool
+}
+
+var _ = Suite(&UsageSuite{})
+
+func (s *UsageSuite) SetUpTest(c *C) {
+	s.db, _ = goleveldb.NewOpenDB(c.MkDir())
+	s.collectionFactory = NewCollectionFactory(s.db)
+	s.packagePool = files.NewPackagePool(c.MkDir(), false)
+
+	stanza := packageStanza.Copy()
+	p1 := NewPackageFromControlFile(stanza)
+
+	stanza = packageStanza.Copy()
+	stanza["Version"] = "7.40-3"
+	stanza["Filename"] = "pool/contrib/a/alien-arena/alien-arena-common_7.40-3_i386.deb"
+	stanza["SHA256"] = "ab4afb9885cba6dc70cccd05b910b2dbccc02c5900578be5e99f0d3dbf9d76a5"
+	stanza["Size"] = "1000"
+	p2 := NewPackageFromControlFile(stanza)
+
+	c.Assert(s.collectionFactory.PackageCollection().Update(p1), IsNil)
+	c.Assert(s.collectionFactory.PackageCollection().Update(p2), IsNil)
+
+	list := NewPackageList()
+	list.Add(p1)
+	list.Add(p2)
+
+	repo := NewLocalRepo("repo", "")
+	repo.UpdateRefList(NewPackageRefListFromPackageList(list))
+	c.Assert(s.collectionFactory.LocalRepoCollection().Add(repo), IsNil)
+
+	list = NewPackageList()
+	list.Add(p1)
+
+	snapshot := NewSnapshotFromRefList("snap", nil, NewPackageRefListFromPackageList(list), "")
+	c.Assert(s.collectionFactory.SnapshotCollection().Add(snapshot), IsNil)
+}
+
+func (s *UsageSuite) TearDownTest(c *C) {
+	s.db.Close()
+}
+
+func (s *UsageSuite) TestComputeUsage(c *C) {
+	report, err := ComputeUsage(s.collectionFactory, s.packagePool, nil)
+	c.Assert(err, IsNil)
+
+	c.Check(report.Files, Equals, 2)
+	c.Check(report.TotalSize, Equals, int64(188518))
+	c.Assert(report.Entries, HasLen, 2)
+
+	c.Check(*report.Entries[0], DeepEquals, UsageEntry{Kind: UsageLocalRepo, Name: "repo", Packages: 2, Files: 2,
+		TotalSize: 188518, UniqueSize: 1000})
+	c.Check(*report.Entries[1], DeepEquals, UsageEntry{Kind: UsageSnapshot, Name: "snap", Packages: 1, Files: 1,
+		TotalSize: 187518, UniqueSize: 0})
+}
+
+func (s *UsageSuite) TestComputeUsageEmpty(c *C) {
+	db, _ := goleveldb.NewOpenDB(c.MkDir())
+	defer db.Close()
+
+	report, err := ComputeUsage(NewCollectionFactory(db), s.packagePool, nil)
+	c.Assert(err, IsNil)
+	c.Check(report.Files, Equals, 0)
+	c.Check(report.Entries, HasLen, 0)
+}
@@ -2,12 +2,12 @@ package files
 
 import (
 	"fmt"
-	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/pborman/uuid"
 
@@ -21,6 +21,9 @@ type PackagePool struct {
 
 	rootPath           string
 	supportLegacyPaths bool
+	// Removed files are moved to trash and purged after grace period
+	trashPath   string
+	gracePeriod time.Duration
 }
 
 // Check interface
@@ -40,9 +43,16 @@ func NewPackagePool(root string, supportLegacyPaths bool) *PackagePool {
 	return &PackagePool{
 		rootPath:           rootPath,
 		supportLegacyPaths: supportLegacyPaths,
+		trashPath:          filepath.Join(filepath.Dir(rootPath), "trash"),
 	}
 }
 
+// SetGracePeriod enables trash: removed files are kept in trash for grace period before being purged
+// (see PurgeTrash), so that files which are still in use could be restored
+func (pool *PackagePool) SetGracePeriod(gracePeriod time.Duration) {
+	pool.gracePeriod = gracePeriod
+}
+
 // LegacyPath returns path relative to pool's root for pre-1.1 aptly (based on MD5)
 func (pool *PackagePool) LegacyPath(filename string, checksums *utils.ChecksumInfo) (string, error) {
 	filename = filepath.Base(filename)
@@ -123,21 +133,154 @@ func (pool *PackagePool) FilepathList(progress aptly.Progress) ([]string, error)
 }
 
 // Remove deletes file in package pool returns its size
+//
+// If grace period is set, file is moved to trash instead
 func (pool *PackagePool) Remove(path string) (size int64, err error) {
 	pool.Lock()
 	defer pool.Unlock()
 
-	path = filepath.Join(pool.rootPath, path)
+	return pool.remove(path, pool.gracePeriod > 0)
+}
 
-	info, err := os.Stat(path)
+// Purge deletes file in package pool immediately, bypassing the trash, returns its size
+//
+// It should be used for files which should never be restored, e.g. corrupted ones
+func (pool *PackagePool) Purge(path string) (size int64, err error) {
+	pool.Lock()
+	defer pool.Unlock()
+
+	return pool.remove(path, false)
+}
+
+// remove deletes file or moves it to trash; should be called with lock held
+func (pool *PackagePool) remove(path string, toTrash bool) (size int64, err error) {
+	fullPath := filepath.Join(pool.rootPath, path)
+
+	info, err := os.Stat(fullPath)
 	if err != nil {
 		return 0, err
 	}
 
-	err = os.Remove(path)
+	if !toTrash {
+		err = os.Remove(fullPath)
+		return info.Size(), err
+	}
+
+	trashPath := filepath.Join(pool.trashPath, path)
+
+	err = os.MkdirAll(filepath.Dir(trashPath), 0777)
+	if err != nil {
+		return 0, err
+	}
+
+	err = os.Rename(fullPath, trashPath)
+	if err != nil {
+		return 0, err
+	}
+
+	// modification time records the moment file has been trashed
+	now := time.Now()
+	err = os.Chtimes(trashPath, now, now)
+
 	return info.Size(), err
 }
 
+// restore moves file back from trash, returning true if file has been found in trash;
+// should be called with lock held
+func (pool *PackagePool) restore(path string) (bool, error) {
+	trashPath := filepath.Join(pool.trashPath, path)
+
+	_, err := os.Stat(trashPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	fullPath := filepath.Join(pool.rootPath, path)
+
+	err = os.MkdirAll(filepath.Dir(fullPath), 0777)
+	if err != nil {
+		return false, err
+	}
+
+	return true, os.Rename(trashPath, fullPath)
+}
+
+// statOrRestore returns info of the file in the pool: file which has been removed while still
+// in use (e.g. by cleanup running concurrently with import) is brought back from trash first;
+// it is used only on import, so that readers never change the pool; should be called with lock held
+func (pool *PackagePool) statOrRestore(path string) (os.FileInfo, error) {
+	fullPath := filepath.Join(pool.rootPath, path)
+
+	info, err := os.Stat(fullPath)
+	if err == nil || !os.IsNotExist(err) {
+		return info, err
+	}
+
+	restored, e := pool.restore(path)
+	if e != nil {
+		return nil, e
+	}
+	if !restored {
+		return nil, err
+	}
+
+	return os.Stat(fullPath)
+}
+
+// PurgeTrash deletes files which have been in trash longer than grace period,
+// returns number of files deleted and disk space freed
+func (pool *PackagePool) PurgeTrash(progress aptly.Progress) (count int, size int64, err error) {
+	pool.Lock()
+	defer pool.Unlock()
+
+	deadline := time.Now().Add(-pool.gracePeriod)
+	dirs := []string{}
+
+	err = filepath.Walk(pool.trashPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == pool.trashPath {
+				return filepath.SkipDir
+			}
+			return err
+		}
+
+		if info.IsDir() {
+			if path != pool.trashPath {
+				dirs = append(dirs, path)
+			}
+			return nil
+		}
+
+		if info.ModTime().After(deadline) {
+			return nil
+		}
+
+		err = os.Remove(path)
+		if err != nil {
+			return err
+		}
+
+		count++
+		size += info.Size()
+
+		if progress != nil {
+			progress.AddBar(1)
+		}
+
+		return nil
+	})
+
+	// remove empty directories, deepest first; non-empty ones fail to be removed
+	for i := len(dirs) - 1; i >= 0; i-- {
+		os.Remove(dirs[i])
+	}
+
+	return
+}
+
 func (pool *PackagePool) ensureChecksums(poolPath, fullPoolPath string, checksumStorage aptly.ChecksumStorage) (targetChecksums *utils.ChecksumInfo, err error) {
 	targetChecksums, err = checksumStorage.Get(poolPath)
 	if err != nil {
@@ -195,6 +338,7 @@ func (pool *PackagePool) Verify(poolPath, basename string, checksums *utils.Chec
 				// unable to stat target location?
 				return "", false, err
 			}
+
 			// doesn't exist, skip it
 			continue
 		}
@@ -262,7 +406,8 @@ func (pool *PackagePool) Import(srcPath, basename string, checksums *utils.Check
 
 	fullPoolPath := filepath.Join(pool.rootPath, poolPath)
 
-	targetInfo, err := os.Stat(fullPoolPath)
+	// file already in the pool (or in trash) is not copied again
+	targetInfo, err := pool.statOrRestore(poolPath)
 	if err != nil {
 		if !os.IsNotExist(err) {
 			// unable to stat target location?
@@ -344,7 +489,7 @@ func (pool *PackagePool) Import(srcPath, basename string, checksums *utils.Check
 	}
 
 	if err != nil {
-		// different filesystems or failed hardlink, fallback to copy
+		// different filesystems or failed hardlink, fallback to reflink or copy
 		var target *os.File
 		target, err = os.Create(fullPoolPath)
 		if err != nil {
@@ -352,7 +497,7 @@ func (pool *PackagePool) Import(srcPath, basename string, checksums *utils.Check
 		}
 		defer target.Close()
 
-		_, err = io.Copy(target, source)
+		err = cloneOrCopy(target, source)
 
 		if err == nil {
 			err = target.Close()
@@ -378,6 +523,101 @@ func (pool *PackagePool) Import(srcPath, basename string, checksums *utils.Check
 	return poolPath, err
 }
 
+// MigrateLegacyFile places file stored at legacy (MD5-based) path at its SHA256-based location
+//
+// File is hardlinked (or copied, if hardlinking fails), legacy copy is kept: it should be removed
+// once package records are updated. If file is already at new location (e.g. interrupted migration
+// is resumed), it's only verified, so legacy copy might be missing. On success checksums are filled
+// back with complete information about the file.
+func (pool *PackagePool) MigrateLegacyFile(legacyPath, basename string, checksums *utils.ChecksumInfo, checksumStorage aptly.ChecksumStorage) (string, error) {
+	pool.Lock()
+	defer pool.Unlock()
+
+	legacyFullPath := filepath.Join(pool.rootPath, legacyPath)
+
+	if checksums.SHA256 == "" {
+		// SHA256 is required to build new location, checksums are stored for legacy path
+		// as well, so that location could be found if migration is resumed
+		stored, err := pool.ensureChecksums(legacyPath, legacyFullPath, checksumStorage)
+		if err != nil {
+			return "", err
+		}
+		checksums.SHA256 = stored.SHA256
+	}
+
+	poolPath, err := pool.buildPoolPath(basename, checksums)
+	if err != nil {
+		return "", err
+	}
+
+	fullPoolPath := filepath.Join(pool.rootPath, poolPath)
+
+	targetInfo, err := os.Stat(fullPoolPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		err = pool.migrateLegacyFile(legacyFullPath, fullPoolPath)
+		if err != nil {
+			return "", err
+		}
+	} else if checksums.Size != 0 && targetInfo.Size() != checksums.Size {
+		return "", fmt.Errorf("unable to migrate %s: file %s already exists", legacyPath, fullPoolPath)
+	}
+
+	targetChecksums, err := pool.ensureChecksums(poolPath, fullPoolPath, checksumStorage)
+	if err != nil {
+		return "", err
+	}
+
+	if checksums.MD5 != "" && targetChecksums.MD5 != checksums.MD5 ||
+		targetChecksums.SHA256 != checksums.SHA256 {
+		return "", fmt.Errorf("unable to migrate %s: checksums of %s don't match", legacyPath, poolPath)
+	}
+
+	*checksums = *targetChecksums
+	return poolPath, nil
+}
+
+// migrateLegacyFile links or copies file at legacy path to new location, copy goes through
+// temporary file, so that interrupted migration doesn't leave truncated files in the pool
+func (pool *PackagePool) migrateLegacyFile(legacyFullPath, fullPoolPath string) error {
+	err := os.MkdirAll(filepath.Dir(fullPoolPath), 0777)
+	if err != nil {
+		return err
+	}
+
+	err = os.Link(legacyFullPath, fullPoolPath)
+	if err == nil || os.IsNotExist(err) {
+		return err
+	}
+
+	source, err := os.Open(legacyFullPath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	tempPath := fullPoolPath + ".migrate"
+	target, err := os.Create(tempPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempPath)
+	defer target.Close()
+
+	err = cloneOrCopy(target, source)
+	if err == nil {
+		err = target.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tempPath, fullPoolPath)
+}
+
 // Open returns io.ReadCloser to access the file
 func (pool *PackagePool) Open(path string) (aptly.ReadSeekerCloser, error) {
 	return os.Open(filepath.Join(pool.rootPath, path))
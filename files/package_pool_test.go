@@ -6,7 +6,9 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/aptly-dev/aptly/aptly"
 	"github.com/aptly-dev/aptly/utils"
@@ -91,6 +93,158 @@ func (s *PackagePoolSuite) TestRemove(c *C) {
 	c.Check(list, DeepEquals, []string{"ae/0c/1.deb", "bd/0a/3.deb", "bd/0b/4.deb"})
 }
 
+func (s *PackagePoolSuite) TestRemoveToTrash(c *C) {
+	s.pool.SetGracePeriod(time.Hour)
+
+	path, err := s.pool.Import(s.debFile, filepath.Base(s.debFile), &s.checksum, false, s.cs)
+	c.Assert(err, IsNil)
+
+	size, err := s.pool.Remove(path)
+	c.Check(err, IsNil)
+	c.Check(size, Equals, int64(2738))
+
+	list, err := s.pool.FilepathList(nil)
+	c.Check(err, IsNil)
+	c.Check(list, HasLen, 0)
+
+	_, err = os.Stat(filepath.Join(s.pool.trashPath, path))
+	c.Check(err, IsNil)
+
+	// trashed file is not in the pool, verify doesn't bring it back
+	checksum := s.checksum
+	_, exists, err := s.pool.Verify("", filepath.Base(s.debFile), &checksum, s.cs)
+	c.Check(err, IsNil)
+	c.Check(exists, Equals, false)
+	_, err = os.Stat(filepath.Join(s.pool.trashPath, path))
+	c.Check(err, IsNil)
+
+	// file still in use is restored from trash on import
+	checksum = s.checksum
+	importedPath, err := s.pool.Import(s.debFile, filepath.Base(s.debFile), &checksum, false, s.cs)
+	c.Check(err, IsNil)
+	c.Check(importedPath, Equals, path)
+	_, err = os.Stat(s.pool.FullPath(path))
+	c.Check(err, IsNil)
+
+	// trash is purged after grace period
+	_, err = s.pool.Remove(path)
+	c.Assert(err, IsNil)
+
+	count, _, err := s.pool.PurgeTrash(nil)
+	c.Check(err, IsNil)
+	c.Check(count, Equals, 0)
+
+	old := time.Now().Add(-2 * time.Hour)
+	c.Assert(os.Chtimes(filepath.Join(s.pool.trashPath, path), old, old), IsNil)
+
+	count, size, err = s.pool.PurgeTrash(nil)
+	c.Check(err, IsNil)
+	c.Check(count, Equals, 1)
+	c.Check(size, Equals, int64(2738))
+
+	_, err = os.Stat(filepath.Join(s.pool.trashPath, filepath.Dir(path)))
+	c.Check(os.IsNotExist(err), Equals, true)
+
+	checksum = s.checksum
+	_, exists, err = s.pool.Verify("", filepath.Base(s.debFile), &checksum, s.cs)
+	c.Check(err, IsNil)
+	c.Check(exists, Equals, false)
+}
+
+func (s *PackagePoolSuite) TestTrashedFileRestored(c *C) {
+	s.pool.SetGracePeriod(time.Hour)
+
+	path, err := s.pool.Import(s.debFile, filepath.Base(s.debFile), &s.checksum, false, s.cs)
+	c.Assert(err, IsNil)
+
+	trashed := func() bool {
+		_, err := os.Stat(filepath.Join(s.pool.trashPath, path))
+		return err == nil
+	}
+
+	// import of the same file brings it back instead of copying it again
+	_, err = s.pool.Remove(path)
+	c.Assert(err, IsNil)
+	checksum := s.checksum
+	importedPath, err := s.pool.Import(s.debFile, filepath.Base(s.debFile), &checksum, false, s.cs)
+	c.Check(err, IsNil)
+	c.Check(importedPath, Equals, path)
+	c.Check(trashed(), Equals, false)
+
+	// readers don't change the pool: trashed file is reported as missing
+	_, err = s.pool.Remove(path)
+	c.Assert(err, IsNil)
+
+	_, err = s.pool.Open(path)
+	c.Check(os.IsNotExist(err), Equals, true)
+	_, err = s.pool.Stat(path)
+	c.Check(os.IsNotExist(err), Equals, true)
+	c.Check(os.IsNotExist(s.pool.Link(path, filepath.Join(c.MkDir(), "link.deb"))), Equals, true)
+	c.Check(trashed(), Equals, true)
+}
+
+func (s *PackagePoolSuite) TestPurge(c *C) {
+	s.pool.SetGracePeriod(time.Hour)
+
+	path, err := s.pool.Import(s.debFile, filepath.Base(s.debFile), &s.checksum, false, s.cs)
+	c.Assert(err, IsNil)
+
+	size, err := s.pool.Purge(path)
+	c.Check(err, IsNil)
+	c.Check(size, Equals, int64(2738))
+
+	_, err = os.Stat(s.pool.FullPath(path))
+	c.Check(os.IsNotExist(err), Equals, true)
+	_, err = os.Stat(filepath.Join(s.pool.trashPath, path))
+	c.Check(os.IsNotExist(err), Equals, true)
+
+	_, err = s.pool.Purge(path)
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
+func (s *PackagePoolSuite) TestImportRaceWithCleanup(c *C) {
+	s.pool.SetGracePeriod(time.Hour)
+
+	for i := 0; i < 50; i++ {
+		var (
+			wg   sync.WaitGroup
+			path string
+			err  error
+		)
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			checksum := s.checksum
+			path, err = s.pool.Import(s.debFile, filepath.Base(s.debFile), &checksum, false, s.cs)
+		}()
+		go func() {
+			defer wg.Done()
+			// db cleanup removing the file as unreferenced, it might not exist yet
+			s.pool.Remove("c7/6b/4bd12fd92e4dfe1b55b18a67a669_libboost-program-options-dev_1.49.0.1_i386.deb")
+		}()
+		wg.Wait()
+
+		c.Assert(err, IsNil)
+
+		// whatever the order, file is either in the pool or in trash, so importing it again makes it available
+		checksum := s.checksum
+		path, err = s.pool.Import(s.debFile, filepath.Base(s.debFile), &checksum, false, s.cs)
+		c.Assert(err, IsNil)
+
+		f, err := s.pool.Open(path)
+		c.Assert(err, IsNil)
+		f.Close()
+	}
+}
+
+func (s *PackagePoolSuite) TestPurgeTrashEmpty(c *C) {
+	count, size, err := s.pool.PurgeTrash(nil)
+	c.Check(err, IsNil)
+	c.Check(count, Equals, 0)
+	c.Check(size, Equals, int64(0))
+}
+
 func (s *PackagePoolSuite) TestImportOk(c *C) {
 	path, err := s.pool.Import(s.debFile, filepath.Base(s.debFile), &s.checksum, false, s.cs)
 	c.Check(err, IsNil)
@@ -172,6 +326,42 @@ func (s *PackagePoolSuite) TestVerifyLegacy(c *C) {
 	c.Check(s.checksum.SHA512, Equals, "d7302241373da972aa9b9e71d2fd769b31a38f71182aa71bc0d69d090d452c69bb74b8612c002ccf8a89c279ced84ac27177c8b92d20f00023b3d268e6cec69c")
 }
 
+func (s *PackagePoolSuite) TestMigrateLegacyFile(c *C) {
+	legacyPath := "00/35/libboost-program-options-dev_1.49.0.1_i386.deb"
+	os.MkdirAll(filepath.Join(s.pool.rootPath, "00", "35"), 0755)
+	err := utils.CopyFile(s.debFile, filepath.Join(s.pool.rootPath, legacyPath))
+	c.Assert(err, IsNil)
+
+	s.checksum.Size = 2738
+	path, err := s.pool.MigrateLegacyFile(legacyPath, filepath.Base(s.debFile), &s.checksum, s.cs)
+	c.Assert(err, IsNil)
+	c.Check(path, Equals, "c7/6b/4bd12fd92e4dfe1b55b18a67a669_libboost-program-options-dev_1.49.0.1_i386.deb")
+	c.Check(s.checksum.SHA512, Equals, "d7302241373da972aa9b9e71d2fd769b31a38f71182aa71bc0d69d090d452c69bb74b8612c002ccf8a89c279ced84ac27177c8b92d20f00023b3d268e6cec69c")
+
+	info, err := s.pool.Stat(path)
+	c.Assert(err, IsNil)
+	c.Check(info.Size(), Equals, int64(2738))
+
+	// legacy file is kept
+	_, err = s.pool.Stat(legacyPath)
+	c.Check(err, IsNil)
+
+	// resuming after legacy file has been removed
+	_, err = s.pool.Remove(legacyPath)
+	c.Assert(err, IsNil)
+
+	// SHA256 is recovered from checksums stored for legacy path
+	checksum := utils.ChecksumInfo{MD5: s.checksum.MD5, Size: 2738}
+	path2, err := s.pool.MigrateLegacyFile(legacyPath, filepath.Base(s.debFile), &checksum, s.cs)
+	c.Assert(err, IsNil)
+	c.Check(path2, Equals, path)
+	c.Check(checksum, DeepEquals, s.checksum)
+
+	// nothing to migrate
+	_, err = s.pool.MigrateLegacyFile("00/35/other.deb", "other.deb", &utils.ChecksumInfo{MD5: "0035"}, s.cs)
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
 func (s *PackagePoolSuite) TestVerify(c *C) {
 	// file doesn't exist yet
 	ppath, exists, err := s.pool.Verify("", filepath.Base(s.debFile), &s.checksum, s.cs)
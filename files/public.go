@@ -30,6 +30,7 @@ const (
 	LinkMethodHardLink uint = iota
 	LinkMethodSymLink
 	LinkMethodCopy
+	LinkMethodReflink
 )
 
 // Constants defining the type of file verification for LinkMethodCopy
@@ -40,10 +41,12 @@ const (
 
 // NewPublishedStorage creates new instance of PublishedStorage which specified root
 func NewPublishedStorage(root string, linkMethod string, verifyMethod string) *PublishedStorage {
-	// Ensure linkMethod is one of 'hardlink', 'symlink', 'copy'
+	// Ensure linkMethod is one of 'hardlink', 'symlink', 'copy', 'reflink'
 	var verifiedLinkMethod uint
 
-	if strings.EqualFold(linkMethod, "copy") {
+	if strings.EqualFold(linkMethod, "reflink") {
+		verifiedLinkMethod = LinkMethodReflink
+	} else if strings.EqualFold(linkMethod, "copy") {
 		verifiedLinkMethod = LinkMethodCopy
 	} else if strings.EqualFold(linkMethod, "symlink") {
 		verifiedLinkMethod = LinkMethodSymLink
@@ -145,7 +148,7 @@ func (storage *PublishedStorage) LinkFromPool(publishedDirectory, fileName strin
 			return err
 		}
 
-		if storage.linkMethod == LinkMethodCopy {
+		if storage.linkMethod == LinkMethodCopy || storage.linkMethod == LinkMethodReflink {
 			if storage.verifyMethod == VerificationMethodFileSize {
 				// if source and destination have the same size, no need to copy
 				if srcStat.Size() == dstStat.Size() {
@@ -190,7 +193,7 @@ func (storage *PublishedStorage) LinkFromPool(publishedDirectory, fileName strin
 	}
 
 	// destination doesn't exist (or forced), create link or copy
-	if storage.linkMethod == LinkMethodCopy {
+	if storage.linkMethod == LinkMethodCopy || storage.linkMethod == LinkMethodReflink {
 		var r aptly.ReadSeekerCloser
 		r, err = sourcePool.Open(sourcePath)
 		if err != nil {
@@ -204,7 +207,12 @@ func (storage *PublishedStorage) LinkFromPool(publishedDirectory, fileName strin
 			return err
 		}
 
-		_, err = io.Copy(dst, r)
+		if storage.linkMethod == LinkMethodReflink {
+			// copy-on-write clone, falls back to copy if not supported
+			err = cloneOrCopy(dst, r)
+		} else {
+			_, err = io.Copy(dst, r)
+		}
 		if err != nil {
 			r.Close()
 			dst.Close()
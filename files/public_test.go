@@ -18,6 +18,7 @@ type PublishedStorageSuite struct {
 	storageSymlink  *PublishedStorage
 	storageCopy     *PublishedStorage
 	storageCopySize *PublishedStorage
+	storageReflink  *PublishedStorage
 	cs              aptly.ChecksumStorage
 }
 
@@ -29,6 +30,7 @@ func (s *PublishedStorageSuite) SetUpTest(c *C) {
 	s.storageSymlink = NewPublishedStorage(filepath.Join(s.root, "public_symlink"), "symlink", "")
 	s.storageCopy = NewPublishedStorage(filepath.Join(s.root, "public_copy"), "copy", "")
 	s.storageCopySize = NewPublishedStorage(filepath.Join(s.root, "public_copysize"), "copy", "size")
+	s.storageReflink = NewPublishedStorage(filepath.Join(s.root, "public_reflink"), "reflink", "")
 	s.cs = NewMockChecksumStorage()
 }
 
@@ -37,6 +39,7 @@ func (s *PublishedStorageSuite) TestLinkMethodField(c *C) {
 	c.Assert(s.storageSymlink.linkMethod, Equals, LinkMethodSymLink)
 	c.Assert(s.storageCopy.linkMethod, Equals, LinkMethodCopy)
 	c.Assert(s.storageCopySize.linkMethod, Equals, LinkMethodCopy)
+	c.Assert(s.storageReflink.linkMethod, Equals, LinkMethodReflink)
 }
 
 func (s *PublishedStorageSuite) TestVerifyMethodField(c *C) {
@@ -272,6 +275,24 @@ func (s *PublishedStorageSuite) TestLinkFromPool(c *C) {
 
 		info = st.Sys().(*syscall.Stat_t)
 		c.Check(int(info.Nlink), Equals, 1)
+
+		// Test using reflink (falls back to copy if filesystem doesn't support it)
+		err = s.storageReflink.LinkFromPool(filepath.Join(t.prefix, t.publishedDirectory), t.sourcePath, pool, srcPoolPath, sourceChecksum, false)
+		c.Assert(err, IsNil)
+
+		st, err = os.Stat(filepath.Join(s.storageReflink.rootPath, t.prefix, t.expectedFilename))
+		c.Assert(err, IsNil)
+
+		info = st.Sys().(*syscall.Stat_t)
+		c.Check(int(info.Nlink), Equals, 1)
+
+		contents, err := ioutil.ReadFile(filepath.Join(s.storageReflink.rootPath, t.prefix, t.expectedFilename))
+		c.Assert(err, IsNil)
+		c.Check(string(contents), Equals, "Contents")
+
+		// linking again is no-op
+		err = s.storageReflink.LinkFromPool(filepath.Join(t.prefix, t.publishedDirectory), t.sourcePath, pool, srcPoolPath, sourceChecksum, false)
+		c.Check(err, IsNil)
 	}
 
 	// test linking files to duplicate final name
@@ -0,0 +1,19 @@
+package files
+
+import (
+	"io"
+	"os"
+)
+
+// cloneOrCopy fills dst with contents of src: as copy-on-write clone (reflink) if both are files
+// on filesystem supporting it, falling back to regular copy
+func cloneOrCopy(dst *os.File, src io.Reader) error {
+	if srcFile, ok := src.(*os.File); ok {
+		if reflink(dst, srcFile) == nil {
+			return nil
+		}
+	}
+
+	_, err := io.Copy(dst, src)
+	return err
+}
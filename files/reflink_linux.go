@@ -0,0 +1,22 @@
+//go:build linux
+// +build linux
+
+package files
+
+import (
+	"os"
+	"syscall"
+)
+
+// ficlone is FICLONE ioctl request (_IOW(0x94, 9, int))
+const ficlone = 0x40049409
+
+// reflink makes copy-on-write clone of src at dst (btrfs, XFS and other filesystems supporting FICLONE)
+func reflink(dst, src *os.File) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dst.Fd(), ficlone, src.Fd())
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
@@ -2,6 +2,8 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -16,7 +18,6 @@ import (
 
 	"github.com/aptly-dev/aptly/aptly"
 	"github.com/aptly-dev/aptly/utils"
-	"github.com/mxk/go-flowrate/flowrate"
 	"github.com/pkg/errors"
 	"github.com/smira/go-ftp-protocol/protocol"
 )
@@ -30,13 +31,45 @@ var (
 type downloaderImpl struct {
 	progress  aptly.Progress
 	aggWriter io.Writer
+	limiter   *Limiter
 	maxTries  int
 	client    *http.Client
+	options   utils.DownloadOptions
 }
 
 // NewDownloader creates new instance of Downloader which specified number
 // of threads and download limit in bytes/sec
 func NewDownloader(downLimit int64, maxTries int, progress aptly.Progress) aptly.Downloader {
+	// empty options can't fail
+	downloader, _ := NewDownloaderWithOptions(downLimit, maxTries, utils.DownloadOptions{}, progress)
+	return downloader
+}
+
+// NewDownloaderWithOptions creates new instance of Downloader with transport options
+// (proxy, TLS settings, credentials and headers)
+func NewDownloaderWithOptions(downLimit int64, maxTries int, options utils.DownloadOptions, progress aptly.Progress) (aptly.Downloader, error) {
+	var limiter *Limiter
+	if downLimit > 0 {
+		// static limit can't fail
+		limiter, _ = NewLimiter(downLimit, nil, 0)
+	}
+
+	return NewDownloaderWithLimiter(limiter, maxTries, options, progress)
+}
+
+// NewDownloaderWithLimiter creates new instance of Downloader with transport options
+// and (possibly shared) speed & quota limiter, limiter could be nil
+func NewDownloaderWithLimiter(limiter *Limiter, maxTries int, options utils.DownloadOptions, progress aptly.Progress) (aptly.Downloader, error) {
+	return newDownloader(limiter, maxTries, options, false, progress)
+}
+
+// NewLocalDownloaderWithLimiter creates new instance of Downloader which, in addition to
+// network protocols, can access local filesystem via file:// URLs (for mirrors of local archives)
+func NewLocalDownloaderWithLimiter(limiter *Limiter, maxTries int, options utils.DownloadOptions, progress aptly.Progress) (aptly.Downloader, error) {
+	return newDownloader(limiter, maxTries, options, true, progress)
+}
+
+func newDownloader(limiter *Limiter, maxTries int, options utils.DownloadOptions, local bool, progress aptly.Progress) (aptly.Downloader, error) {
 	transport := http.Transport{}
 	transport.Proxy = http.DefaultTransport.(*http.Transport).Proxy
 	transport.ResponseHeaderTimeout = 30 * time.Second
@@ -45,6 +78,19 @@ func NewDownloader(downLimit int64, maxTries int, progress aptly.Progress) aptly
 	transport.DisableCompression = true
 	initTransport(&transport)
 	transport.RegisterProtocol("ftp", &protocol.FTPRoundTripper{})
+	if local {
+		transport.RegisterProtocol("file", http.NewFileTransport(http.Dir("/")))
+	}
+
+	err := configureTransport(&transport, options)
+	if err != nil {
+		return nil, err
+	}
+
+	options.Password, err = options.GetPassword()
+	if err != nil {
+		return nil, err
+	}
 
 	downloader := &downloaderImpl{
 		progress: progress,
@@ -52,6 +98,8 @@ func NewDownloader(downLimit int64, maxTries int, progress aptly.Progress) aptly
 		client: &http.Client{
 			Transport: &transport,
 		},
+		options: options,
+		limiter: limiter,
 	}
 
 	progressWriter := io.Writer(progress)
@@ -60,13 +108,65 @@ func NewDownloader(downLimit int64, maxTries int, progress aptly.Progress) aptly
 	}
 
 	downloader.client.CheckRedirect = downloader.checkRedirect
-	if downLimit > 0 {
-		downloader.aggWriter = flowrate.NewWriter(progressWriter, downLimit)
+	if limiter != nil {
+		downloader.aggWriter = limiter.Writer(progressWriter)
 	} else {
 		downloader.aggWriter = progressWriter
 	}
 
-	return downloader
+	return downloader, nil
+}
+
+// configureTransport applies proxy and TLS options to the transport
+func configureTransport(transport *http.Transport, options utils.DownloadOptions) error {
+	if options.Proxy != "" {
+		proxyURL, err := url.Parse(options.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL %s: %s", options.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if options.CACertFile == "" && options.ClientCertFile == "" {
+		return nil
+	}
+
+	tlsConfig := &tls.Config{}
+
+	if options.CACertFile != "" {
+		pem, err := ioutil.ReadFile(options.CACertFile)
+		if err != nil {
+			return fmt.Errorf("unable to load CA certificates: %s", err)
+		}
+
+		tlsConfig.RootCAs, err = x509.SystemCertPool()
+		if err != nil || tlsConfig.RootCAs == nil {
+			tlsConfig.RootCAs = x509.NewCertPool()
+		}
+
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("unable to load CA certificates: no certificates found in %s", options.CACertFile)
+		}
+	}
+
+	if options.ClientCertFile != "" {
+		keyFile := options.ClientKeyFile
+		if keyFile == "" {
+			// key could be bundled with the certificate
+			keyFile = options.ClientCertFile
+		}
+
+		cert, err := tls.LoadX509KeyPair(options.ClientCertFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("unable to load client certificate: %s", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport.TLSClientConfig = tlsConfig
+
+	return nil
 }
 
 func (downloader *downloaderImpl) checkRedirect(req *http.Request, via []*http.Request) error {
@@ -74,6 +174,14 @@ func (downloader *downloaderImpl) checkRedirect(req *http.Request, via []*http.R
 		downloader.progress.Printf("Following redirect to %s...\n", req.URL)
 	}
 
+	// credentials and custom headers are meant for the original host only
+	if len(via) > 0 && req.URL.Host != via[0].URL.Host {
+		req.Header.Del("Authorization")
+		for name := range downloader.options.Headers {
+			req.Header.Del(name)
+		}
+	}
+
 	return nil
 }
 
@@ -158,6 +266,13 @@ func (downloader *downloaderImpl) newRequest(ctx context.Context, method, url st
 	req.Close = true
 	req = req.WithContext(ctx)
 
+	for name, value := range downloader.options.Headers {
+		req.Header.Set(name, value)
+	}
+	if downloader.options.Username != "" {
+		req.SetBasicAuth(downloader.options.Username, downloader.options.Password)
+	}
+
 	proxyURL, _ := downloader.client.Transport.(*http.Transport).Proxy(req)
 	if proxyURL == nil && (req.URL.Scheme == "http" || req.URL.Scheme == "https") {
 		req.URL.Opaque = strings.Replace(req.URL.RequestURI(), "+", "%2b", -1)
@@ -168,9 +283,20 @@ func (downloader *downloaderImpl) newRequest(ctx context.Context, method, url st
 }
 
 // DownloadWithChecksum starts new download task with checksum verification
+//
+// File is downloaded to destination + ".down" first: if transfer is interrupted, retries
+// (up to maxTries) resume it with Range request. Partial file is removed when all the
+// tries fail or download quota is exceeded, so interrupted download is never resumed
+// by later calls.
 func (downloader *downloaderImpl) DownloadWithChecksum(ctx context.Context, url string, destination string,
 	expected *utils.ChecksumInfo, ignoreMismatch bool) error {
 
+	if downloader.limiter != nil {
+		if err := downloader.limiter.Check(); err != nil {
+			return errors.Wrap(err, url)
+		}
+	}
+
 	if downloader.progress != nil {
 		downloader.progress.Printf("Downloading %s...\n", url)
 	}
@@ -191,6 +317,7 @@ func (downloader *downloaderImpl) DownloadWithChecksum(ctx context.Context, url
 
 	// still an error after retrying, giving up
 	if err != nil {
+		os.Remove(destination + ".down")
 		return err
 	}
 
@@ -204,6 +331,20 @@ func (downloader *downloaderImpl) DownloadWithChecksum(ctx context.Context, url
 }
 
 func (downloader *downloaderImpl) download(req *http.Request, url, destination string, expected *utils.ChecksumInfo, ignoreMismatch bool) (string, error) {
+	temppath := destination + ".down"
+
+	// partially downloaded file (left by previous attempt) is resumed with Range request
+	var offset int64
+	if info, err := os.Stat(temppath); err == nil && info.Mode().IsRegular() {
+		offset = info.Size()
+	}
+
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	} else {
+		req.Header.Del("Range")
+	}
+
 	resp, err := downloader.client.Do(req)
 	if err != nil {
 		return "", errors.Wrap(err, url)
@@ -212,6 +353,13 @@ func (downloader *downloaderImpl) download(req *http.Request, url, destination s
 		defer resp.Body.Close()
 	}
 
+	if offset > 0 && (resp.StatusCode == http.StatusRequestedRangeNotSatisfiable ||
+		resp.StatusCode == http.StatusPartialContent && !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset))) {
+		// partial file doesn't match remote one, start from scratch
+		os.Remove(temppath)
+		return downloader.download(req, url, destination, expected, ignoreMismatch)
+	}
+
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		return "", &Error{Code: resp.StatusCode, URL: url}
 	}
@@ -221,16 +369,34 @@ func (downloader *downloaderImpl) download(req *http.Request, url, destination s
 		return "", errors.Wrap(err, url)
 	}
 
-	temppath := destination + ".down"
+	checksummer := utils.NewChecksumWriter()
 
-	outfile, err := os.Create(temppath)
+	var outfile *os.File
+	if resp.StatusCode == http.StatusPartialContent {
+		outfile, err = os.OpenFile(temppath, os.O_RDWR, 0666)
+		if err == nil && expected != nil {
+			// checksum should cover already downloaded part
+			_, err = io.Copy(checksummer, outfile)
+		}
+		if err == nil {
+			_, err = outfile.Seek(offset, io.SeekStart)
+		}
+		if err == nil && downloader.progress != nil {
+			downloader.progress.Printf("Resuming download of %s from byte %d...\n", url, offset)
+		}
+	} else {
+		outfile, err = os.Create(temppath)
+	}
 	if err != nil {
+		if outfile != nil {
+			outfile.Close()
+		}
 		return "", errors.Wrap(err, url)
 	}
 	defer outfile.Close()
 
-	checksummer := utils.NewChecksumWriter()
-	writers := []io.Writer{outfile, downloader.aggWriter}
+	// limiter goes first, so that no data is written past the quota
+	writers := []io.Writer{downloader.aggWriter, outfile}
 
 	if expected != nil {
 		writers = append(writers, checksummer)
@@ -240,25 +406,16 @@ func (downloader *downloaderImpl) download(req *http.Request, url, destination s
 
 	_, err = io.Copy(w, resp.Body)
 	if err != nil {
-		os.Remove(temppath)
+		if !retryableError(err) {
+			os.Remove(temppath)
+		}
 		return "", errors.Wrap(err, url)
 	}
 
 	if expected != nil {
 		actual := checksummer.Sum()
 
-		if actual.Size != expected.Size {
-			err = fmt.Errorf("%s: size check mismatch %d != %d", url, actual.Size, expected.Size)
-		} else if expected.MD5 != "" && actual.MD5 != expected.MD5 {
-			err = fmt.Errorf("%s: md5 hash mismatch %#v != %#v", url, actual.MD5, expected.MD5)
-		} else if expected.SHA1 != "" && actual.SHA1 != expected.SHA1 {
-			err = fmt.Errorf("%s: sha1 hash mismatch %#v != %#v", url, actual.SHA1, expected.SHA1)
-		} else if expected.SHA256 != "" && actual.SHA256 != expected.SHA256 {
-			err = fmt.Errorf("%s: sha256 hash mismatch %#v != %#v", url, actual.SHA256, expected.SHA256)
-		} else if expected.SHA512 != "" && actual.SHA512 != expected.SHA512 {
-			err = fmt.Errorf("%s: sha512 hash mismatch %#v != %#v", url, actual.SHA512, expected.SHA512)
-		}
-
+		err = checksumMismatch(url, expected, actual)
 		if err != nil {
 			if ignoreMismatch {
 				downloader.progress.Printf("WARNING: %s\n", err.Error())
@@ -274,3 +431,51 @@ func (downloader *downloaderImpl) download(req *http.Request, url, destination s
 
 	return temppath, nil
 }
+
+// checksumMismatch returns error if actual checksums don't match expected ones
+func checksumMismatch(url string, expected *utils.ChecksumInfo, actual utils.ChecksumInfo) error {
+	if actual.Size != expected.Size {
+		return fmt.Errorf("%s: size check mismatch %d != %d", url, actual.Size, expected.Size)
+	} else if expected.MD5 != "" && actual.MD5 != expected.MD5 {
+		return fmt.Errorf("%s: md5 hash mismatch %#v != %#v", url, actual.MD5, expected.MD5)
+	} else if expected.SHA1 != "" && actual.SHA1 != expected.SHA1 {
+		return fmt.Errorf("%s: sha1 hash mismatch %#v != %#v", url, actual.SHA1, expected.SHA1)
+	} else if expected.SHA256 != "" && actual.SHA256 != expected.SHA256 {
+		return fmt.Errorf("%s: sha256 hash mismatch %#v != %#v", url, actual.SHA256, expected.SHA256)
+	} else if expected.SHA512 != "" && actual.SHA512 != expected.SHA512 {
+		return fmt.Errorf("%s: sha512 hash mismatch %#v != %#v", url, actual.SHA512, expected.SHA512)
+	}
+
+	return nil
+}
+
+// VerifyLocalFile verifies checksums of the file in local (file://) archive in place,
+// so that it could be linked into the package pool instead of being downloaded
+//
+// On success expected checksums are updated, just like with downloads
+func VerifyLocalFile(path string, expected *utils.ChecksumInfo, ignoreMismatch bool, progress aptly.Progress) error {
+	actual, err := utils.ChecksumsForFile(path)
+	if err != nil {
+		return err
+	}
+
+	if progress != nil {
+		progress.AddBar(int(actual.Size))
+	}
+
+	err = checksumMismatch(path, expected, actual)
+	if err != nil {
+		if !ignoreMismatch {
+			return err
+		}
+
+		if progress != nil {
+			progress.Printf("WARNING: %s\n", err.Error())
+		}
+		return nil
+	}
+
+	*expected = actual
+
+	return nil
+}
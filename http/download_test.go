@@ -2,11 +2,18 @@ package http
 
 import (
 	"context"
+	"crypto/md5"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aptly-dev/aptly/aptly"
 	"github.com/aptly-dev/aptly/console"
@@ -77,6 +84,172 @@ func (s *DownloaderSuite) TestDownloadOK(c *C) {
 	c.Assert(s.d.Download(s.ctx, s.url+"/test", s.tempfile.Name()), IsNil)
 }
 
+func (s *DownloaderSuite) TestDownloadFileURL(c *C) {
+	dir := c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "Release"), []byte("Origin: local\n"), 0644), IsNil)
+
+	// file:// URLs are not available for regular downloaders
+	c.Check(s.d.Download(s.ctx, "file://"+filepath.Join(dir, "Release"), s.tempfile.Name()), NotNil)
+
+	d, err := NewLocalDownloaderWithLimiter(nil, 1, utils.DownloadOptions{}, s.progress)
+	c.Assert(err, IsNil)
+
+	c.Assert(d.Download(s.ctx, "file://"+filepath.Join(dir, "Release"), s.tempfile.Name()), IsNil)
+
+	contents, _ := ioutil.ReadFile(s.tempfile.Name())
+	c.Check(string(contents), Equals, "Origin: local\n")
+
+	err = d.Download(s.ctx, "file://"+filepath.Join(dir, "InRelease"), s.tempfile.Name())
+	c.Check(err, FitsTypeOf, &Error{})
+	c.Check(err.(*Error).Code, Equals, 404)
+}
+
+func (s *DownloaderSuite) TestDownloadWithOptions(c *C) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		fmt.Fprintf(w, "%s:%s %s", user, password, r.Header.Get("X-Token"))
+	}))
+	defer ts.Close()
+
+	d, err := NewDownloaderWithOptions(0, 1, utils.DownloadOptions{
+		Username: "aptly",
+		Password: "secret",
+		Headers:  map[string]string{"X-Token": "abc"},
+	}, s.progress)
+	c.Assert(err, IsNil)
+	c.Assert(d.Download(s.ctx, ts.URL+"/file", s.tempfile.Name()), IsNil)
+
+	contents, _ := ioutil.ReadFile(s.tempfile.Name())
+	c.Check(string(contents), Equals, "aptly:secret abc")
+
+	_, err = NewDownloaderWithOptions(0, 1, utils.DownloadOptions{Proxy: "http://prox%y"}, s.progress)
+	c.Check(err, ErrorMatches, "invalid proxy URL .*")
+
+	_, err = NewDownloaderWithOptions(0, 1, utils.DownloadOptions{CACertFile: "/no/such/file"}, s.progress)
+	c.Check(err, ErrorMatches, "unable to load CA certificates: .*")
+
+	_, err = NewDownloaderWithOptions(0, 1, utils.DownloadOptions{ClientCertFile: "/no/such/file"}, s.progress)
+	c.Check(err, ErrorMatches, "unable to load client certificate: .*")
+}
+
+func (s *DownloaderSuite) TestDownloadRedirectCredentials(c *C) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		fmt.Fprintf(w, "%s:%s %s", user, password, r.Header.Get("X-Token"))
+	}))
+	defer other.Close()
+
+	otherURL := strings.Replace(other.URL, "127.0.0.1", "localhost", 1)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/same" {
+			user, password, _ := r.BasicAuth()
+			fmt.Fprintf(w, "%s:%s %s", user, password, r.Header.Get("X-Token"))
+			return
+		}
+		if r.URL.Path == "/local" {
+			http.Redirect(w, r, "/same", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, otherURL+"/file", http.StatusFound)
+	}))
+	defer ts.Close()
+
+	passwordFile := filepath.Join(c.MkDir(), "password")
+	c.Assert(ioutil.WriteFile(passwordFile, []byte("secret\n"), 0600), IsNil)
+
+	d, err := NewDownloaderWithOptions(0, 1, utils.DownloadOptions{
+		Username:     "aptly",
+		PasswordFile: passwordFile,
+		Headers:      map[string]string{"X-Token": "abc"},
+	}, s.progress)
+	c.Assert(err, IsNil)
+
+	c.Assert(d.Download(s.ctx, ts.URL+"/local", s.tempfile.Name()), IsNil)
+	contents, _ := ioutil.ReadFile(s.tempfile.Name())
+	c.Check(string(contents), Equals, "aptly:secret abc")
+
+	c.Assert(d.Download(s.ctx, ts.URL+"/file", s.tempfile.Name()), IsNil)
+	contents, _ = ioutil.ReadFile(s.tempfile.Name())
+	c.Check(string(contents), Equals, ": ")
+
+	_, err = NewDownloaderWithOptions(0, 1, utils.DownloadOptions{Username: "aptly", PasswordFile: "/no/such/file"}, s.progress)
+	c.Check(err, ErrorMatches, "unable to read password file: .*")
+}
+
+func (s *DownloaderSuite) TestDownloadCustomCA(c *C) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "secure")
+	}))
+	defer ts.Close()
+
+	c.Check(s.d.Download(s.ctx, ts.URL+"/file", s.tempfile.Name()), ErrorMatches, ".*certificate.*")
+
+	caFile := filepath.Join(c.MkDir(), "ca.pem")
+	c.Assert(ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0644), IsNil)
+
+	d, err := NewDownloaderWithOptions(0, 1, utils.DownloadOptions{CACertFile: caFile}, s.progress)
+	c.Assert(err, IsNil)
+	c.Assert(d.Download(s.ctx, ts.URL+"/file", s.tempfile.Name()), IsNil)
+
+	contents, _ := ioutil.ReadFile(s.tempfile.Name())
+	c.Check(string(contents), Equals, "secure")
+}
+
+func (s *DownloaderSuite) TestDownloadResume(c *C) {
+	content := strings.Repeat("0123456789", 1000)
+	requests := []string{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Get("Range"))
+
+		if len(requests) == 1 {
+			// send half of the file and drop connection
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write([]byte(content[:len(content)/2]))
+			w.(http.Flusher).Flush()
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+	}))
+	defer ts.Close()
+
+	d := NewDownloader(0, 2, s.progress)
+	expected := utils.ChecksumInfo{Size: int64(len(content)), MD5: fmt.Sprintf("%x", md5.Sum([]byte(content)))}
+
+	c.Assert(d.DownloadWithChecksum(s.ctx, ts.URL+"/file", s.tempfile.Name(), &expected, false), IsNil)
+	c.Check(requests, DeepEquals, []string{"", fmt.Sprintf("bytes=%d-", len(content)/2)})
+
+	contents, _ := ioutil.ReadFile(s.tempfile.Name())
+	c.Check(string(contents), Equals, content)
+
+	// partial file which doesn't match remote file is downloaded from scratch
+	c.Assert(ioutil.WriteFile(s.tempfile.Name()+".down", []byte(content+"garbage"), 0644), IsNil)
+	requests = requests[:1]
+
+	c.Assert(d.DownloadWithChecksum(s.ctx, ts.URL+"/file", s.tempfile.Name(), &expected, false), IsNil)
+	c.Check(requests[1:], DeepEquals, []string{fmt.Sprintf("bytes=%d-", len(content)+7), ""})
+}
+
+func (s *DownloaderSuite) TestVerifyLocalFile(c *C) {
+	path := filepath.Join(c.MkDir(), "file")
+	c.Assert(ioutil.WriteFile(path, []byte("abc"), 0644), IsNil)
+
+	expected := utils.ChecksumInfo{Size: 3, MD5: "900150983cd24fb0d6963f7d28e17f72"}
+	c.Assert(VerifyLocalFile(path, &expected, false, nil), IsNil)
+	c.Check(expected.SHA256, Equals, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad")
+
+	expected = utils.ChecksumInfo{Size: 3, MD5: "00000000000000000000000000000000"}
+	c.Check(VerifyLocalFile(path, &expected, false, nil), ErrorMatches, ".*md5 hash mismatch.*")
+	c.Check(VerifyLocalFile(path, &expected, true, nil), IsNil)
+
+	expected = utils.ChecksumInfo{Size: 4}
+	c.Check(VerifyLocalFile(path, &expected, false, nil), ErrorMatches, ".*size check mismatch 3 != 4")
+}
+
 func (s *DownloaderSuite) TestDownloadWithChecksum(c *C) {
 	c.Assert(s.d.DownloadWithChecksum(s.ctx, s.url+"/test", s.tempfile.Name(), &utils.ChecksumInfo{}, false),
 		ErrorMatches, ".*size check mismatch 12 != 0")
@@ -0,0 +1,160 @@
+package http
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aptly-dev/aptly/aptly"
+	"github.com/aptly-dev/aptly/utils"
+)
+
+// Check interface
+var (
+	_ aptly.Downloader = (*FailoverDownloader)(nil)
+)
+
+// FailoverDownloader wraps Downloader to download files from the list of
+// alternate archive roots (mirrors of the same archive)
+//
+// URLs under the first root are tried against all the roots, either in order
+// or round-robin; roots which fail are demoted for the rest of the session
+type FailoverDownloader struct {
+	downloader aptly.Downloader
+	roots      []string
+	roundRobin bool
+
+	mu       sync.Mutex
+	next     int
+	failures []int
+}
+
+// NewFailoverDownloader creates FailoverDownloader for the list of archive roots,
+// first root is the primary one
+func NewFailoverDownloader(downloader aptly.Downloader, roots []string, roundRobin bool) *FailoverDownloader {
+	normalized := make([]string, len(roots))
+	for i := range roots {
+		normalized[i] = roots[i]
+		if !strings.HasSuffix(normalized[i], "/") {
+			normalized[i] += "/"
+		}
+	}
+
+	return &FailoverDownloader{
+		downloader: downloader,
+		roots:      normalized,
+		roundRobin: roundRobin,
+		failures:   make([]int, len(roots)),
+	}
+}
+
+// candidates returns list of roots to try: in configured order (rotated for round-robin),
+// roots with fewer failures first
+func (f *FailoverDownloader) candidates() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	start := 0
+	if f.roundRobin {
+		start = f.next
+		f.next = (f.next + 1) % len(f.roots)
+	}
+
+	result := make([]int, len(f.roots))
+	for i := range result {
+		result[i] = (start + i) % len(f.roots)
+	}
+
+	failures := append([]int(nil), f.failures...)
+	sort.SliceStable(result, func(i, j int) bool {
+		return failures[result[i]] < failures[result[j]]
+	})
+
+	return result
+}
+
+func (f *FailoverDownloader) recordFailure(idx int) {
+	f.mu.Lock()
+	f.failures[idx]++
+	f.mu.Unlock()
+}
+
+// Failures returns number of failed downloads per archive root
+func (f *FailoverDownloader) Failures() map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := make(map[string]int, len(f.roots))
+	for i, root := range f.roots {
+		result[root] = f.failures[i]
+	}
+
+	return result
+}
+
+// try runs download function for each candidate root until one succeeds
+func (f *FailoverDownloader) try(ctx context.Context, url string, download func(url string) error) error {
+	if !strings.HasPrefix(url, f.roots[0]) {
+		return download(url)
+	}
+
+	relative := url[len(f.roots[0]):]
+
+	var err error
+	for _, idx := range f.candidates() {
+		tryURL := f.roots[idx] + relative
+
+		err = download(tryURL)
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return err
+		}
+
+		// missing file is not a failure of the mirror itself
+		if httpErr, ok := err.(*Error); !ok || (httpErr.Code != 404 && httpErr.Code != 403) {
+			f.recordFailure(idx)
+		}
+
+		if progress := f.downloader.GetProgress(); progress != nil && len(f.roots) > 1 {
+			progress.Printf("Download from %s failed: %s\n", f.roots[idx], err)
+		}
+	}
+
+	return err
+}
+
+// Download starts new download task
+func (f *FailoverDownloader) Download(ctx context.Context, url string, destination string) error {
+	return f.try(ctx, url, func(url string) error {
+		return f.downloader.Download(ctx, url, destination)
+	})
+}
+
+// DownloadWithChecksum starts new download task with checksum verification
+func (f *FailoverDownloader) DownloadWithChecksum(ctx context.Context, url string, destination string, expected *utils.ChecksumInfo, ignoreMismatch bool) error {
+	return f.try(ctx, url, func(url string) error {
+		return f.downloader.DownloadWithChecksum(ctx, url, destination, expected, ignoreMismatch)
+	})
+}
+
+// GetProgress returns Progress object
+func (f *FailoverDownloader) GetProgress() aptly.Progress {
+	return f.downloader.GetProgress()
+}
+
+// GetLength returns size by heading object with url
+func (f *FailoverDownloader) GetLength(ctx context.Context, url string) (int64, error) {
+	var length int64
+
+	err := f.try(ctx, url, func(url string) error {
+		var err error
+		length, err = f.downloader.GetLength(ctx, url)
+		return err
+	})
+
+	return length, err
+}
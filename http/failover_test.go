@@ -0,0 +1,67 @@
+package http
+
+import (
+	"context"
+
+	. "gopkg.in/check.v1"
+)
+
+type FailoverDownloaderSuite struct {
+	ctx context.Context
+}
+
+var _ = Suite(&FailoverDownloaderSuite{})
+
+func (s *FailoverDownloaderSuite) SetUpTest(c *C) {
+	s.ctx = context.Background()
+}
+
+func (s *FailoverDownloaderSuite) TestInOrder(c *C) {
+	fake := NewFakeDownloader()
+	d := NewFailoverDownloader(fake, []string{"http://a/debian", "http://b/debian/"}, false)
+
+	fake.ExpectResponse("http://a/debian/dists/Release", "a")
+	c.Assert(d.Download(s.ctx, "http://a/debian/dists/Release", c.MkDir()+"/f"), IsNil)
+
+	// failed download is retried from fallback, failing root is demoted
+	fake.ExpectError("http://a/debian/pool/1.deb", &Error{Code: 500})
+	fake.ExpectResponse("http://b/debian/pool/1.deb", "b")
+	c.Assert(d.Download(s.ctx, "http://a/debian/pool/1.deb", c.MkDir()+"/f"), IsNil)
+
+	fake.ExpectResponse("http://b/debian/pool/2.deb", "b")
+	c.Assert(d.Download(s.ctx, "http://a/debian/pool/2.deb", c.MkDir()+"/f"), IsNil)
+
+	// missing file isn't counted as failure
+	fake.ExpectError("http://b/debian/pool/3.deb", &Error{Code: 404})
+	fake.ExpectError("http://a/debian/pool/3.deb", &Error{Code: 404})
+	c.Assert(d.Download(s.ctx, "http://a/debian/pool/3.deb", c.MkDir()+"/f"), ErrorMatches, "HTTP code 404.*")
+
+	c.Check(d.Failures(), DeepEquals, map[string]int{"http://a/debian/": 1, "http://b/debian/": 0})
+
+	// other URLs are passed as is
+	fake.ExpectResponse("http://c/key.gpg", "c")
+	c.Assert(d.Download(s.ctx, "http://c/key.gpg", c.MkDir()+"/f"), IsNil)
+
+	c.Check(fake.Empty(), Equals, true)
+}
+
+func (s *FailoverDownloaderSuite) TestRoundRobin(c *C) {
+	fake := NewFakeDownloader()
+	d := NewFailoverDownloader(fake, []string{"http://a/", "http://b/", "http://c/"}, true)
+
+	fake.ExpectResponse("http://a/1", "a")
+	fake.ExpectResponse("http://b/2", "b")
+	fake.ExpectError("http://c/3", &Error{Code: 503})
+	fake.ExpectResponse("http://a/3", "a")
+	fake.ExpectResponse("http://a/4", "a")
+	// c is demoted
+	fake.ExpectResponse("http://b/5", "b")
+	fake.ExpectResponse("http://a/6", "a")
+
+	for _, path := range []string{"1", "2", "3", "4", "5", "6"} {
+		c.Assert(d.Download(s.ctx, "http://a/"+path, c.MkDir()+"/f"), IsNil)
+	}
+
+	c.Check(fake.Empty(), Equals, true)
+	c.Check(d.Failures()["http://c/"], Equals, 1)
+}
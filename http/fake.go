@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/aptly-dev/aptly/aptly"
 	"github.com/aptly-dev/aptly/utils"
@@ -20,6 +21,7 @@ type expectedRequest struct {
 // FakeDownloader is like Downloader, but it used in tests
 // to stub out results
 type FakeDownloader struct {
+	mu          sync.Mutex
 	expected    []expectedRequest
 	anyExpected map[string]expectedRequest
 }
@@ -71,6 +73,9 @@ func (f *FakeDownloader) GetLength(ctx context.Context, url string) (int64, erro
 }
 
 func (f *FakeDownloader) getExpectedRequest(url string) (*expectedRequest, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
 	var expectation expectedRequest
 	if len(f.expected) > 0 && f.expected[0].URL == url {
 		expectation, f.expected = f.expected[0], f.expected[1:]
@@ -0,0 +1,206 @@
+package http
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/aptly-dev/aptly/utils"
+	"github.com/mxk/go-flowrate/flowrate"
+	"github.com/pkg/errors"
+)
+
+// ErrQuotaExceeded is returned by downloads when download quota has been used up
+var ErrQuotaExceeded = errors.New("download quota exceeded")
+
+// IsQuotaExceeded checks whether error is caused by exceeded download quota
+func IsQuotaExceeded(err error) bool {
+	return errors.Cause(err) == ErrQuotaExceeded
+}
+
+// Limiter controls aggregate download speed and amount of data downloaded
+//
+// Speed limit is picked (in order of precedence) from the override set at runtime,
+// first matching window of the schedule or default limit. Limiter is shared by
+// all downloads, so limits apply to the total bandwidth.
+type Limiter struct {
+	sync.Mutex
+
+	monitor *flowrate.Monitor
+
+	defaultLimit int64
+	schedule     []utils.BandwidthWindow
+	override     *int64
+	quota        int64
+	downloaded   int64
+
+	// for tests
+	now func() time.Time
+}
+
+// LimiterStatus is a snapshot of limiter settings and usage
+type LimiterStatus struct {
+	// Effective speed limit (bytes/sec), 0 is unlimited
+	Limit int64
+	// Whether limit was overridden at runtime
+	Override bool
+	// Download quota (bytes), 0 is unlimited
+	Quota int64
+	// Bytes downloaded since last usage reset
+	Downloaded int64
+	// Current transfer rate (bytes/sec)
+	Rate int64
+}
+
+// NewLimiter creates new instance of Limiter with default limit (bytes/sec), schedule
+// (limits in kbytes/sec, as in configuration file) and quota (bytes), zero values mean no limit
+func NewLimiter(defaultLimit int64, schedule []utils.BandwidthWindow, quota int64) (*Limiter, error) {
+	for _, window := range schedule {
+		if err := window.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Limiter{
+		monitor:      flowrate.New(0, 0),
+		defaultLimit: defaultLimit,
+		schedule:     schedule,
+		quota:        quota,
+		now:          time.Now,
+	}, nil
+}
+
+// currentLimit returns speed limit effective now, should be called with lock held
+func (limiter *Limiter) currentLimit() int64 {
+	if limiter.override != nil {
+		return *limiter.override
+	}
+
+	now := limiter.now()
+	for _, window := range limiter.schedule {
+		if window.Matches(now) {
+			return window.Limit * 1024
+		}
+	}
+
+	return limiter.defaultLimit
+}
+
+// Check returns ErrQuotaExceeded if download quota has been used up
+func (limiter *Limiter) Check() error {
+	limiter.Lock()
+	defer limiter.Unlock()
+
+	if limiter.quota > 0 && limiter.downloaded >= limiter.quota {
+		return ErrQuotaExceeded
+	}
+
+	return nil
+}
+
+// SetLimitOverride overrides speed limit (bytes/sec, 0 is unlimited) ignoring the schedule
+func (limiter *Limiter) SetLimitOverride(limit int64) {
+	limiter.Lock()
+	defer limiter.Unlock()
+
+	limiter.override = &limit
+}
+
+// ClearLimitOverride returns to the scheduled (or default) speed limit
+func (limiter *Limiter) ClearLimitOverride() {
+	limiter.Lock()
+	defer limiter.Unlock()
+
+	limiter.override = nil
+}
+
+// SetQuota changes download quota (bytes, 0 is unlimited)
+func (limiter *Limiter) SetQuota(quota int64) {
+	limiter.Lock()
+	defer limiter.Unlock()
+
+	limiter.quota = quota
+}
+
+// ResetUsage resets amount of downloaded data counted against the quota
+func (limiter *Limiter) ResetUsage() {
+	limiter.Lock()
+	defer limiter.Unlock()
+
+	limiter.downloaded = 0
+}
+
+// Status returns current settings and usage
+func (limiter *Limiter) Status() LimiterStatus {
+	limiter.Lock()
+	defer limiter.Unlock()
+
+	return LimiterStatus{
+		Limit:      limiter.currentLimit(),
+		Override:   limiter.override != nil,
+		Quota:      limiter.quota,
+		Downloaded: limiter.downloaded,
+		Rate:       limiter.monitor.Status().CurRate,
+	}
+}
+
+// reserve returns number of bytes (up to want) which could be written now,
+// waiting for the speed limit
+func (limiter *Limiter) reserve(want int) (int, error) {
+	limiter.Lock()
+	if limiter.quota > 0 {
+		left := limiter.quota - limiter.downloaded
+		if left <= 0 {
+			limiter.Unlock()
+			return 0, ErrQuotaExceeded
+		}
+		if int64(want) > left {
+			want = int(left)
+		}
+	}
+	limit := limiter.currentLimit()
+	limiter.Unlock()
+
+	// limit is re-evaluated for every chunk, so changes are picked up by running downloads
+	return limiter.monitor.Limit(want, limit, true), nil
+}
+
+func (limiter *Limiter) account(n int) {
+	limiter.monitor.Update(n)
+
+	limiter.Lock()
+	limiter.downloaded += int64(n)
+	limiter.Unlock()
+}
+
+// Writer wraps w so that writes are subject to the limits
+func (limiter *Limiter) Writer(w io.Writer) io.Writer {
+	return &limitedWriter{limiter: limiter, w: w}
+}
+
+type limitedWriter struct {
+	limiter *Limiter
+	w       io.Writer
+}
+
+func (lw *limitedWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		var c int
+
+		c, err = lw.limiter.reserve(len(p))
+		if err != nil {
+			return
+		}
+
+		c, err = lw.w.Write(p[:c])
+		lw.limiter.account(c)
+		n += c
+		if err != nil {
+			return
+		}
+
+		p = p[c:]
+	}
+
+	return
+}
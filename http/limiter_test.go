@@ -0,0 +1,135 @@
+package http
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aptly-dev/aptly/utils"
+
+	. "gopkg.in/check.v1"
+)
+
+type LimiterSuite struct {
+	DownloaderSuiteBase
+}
+
+var _ = Suite(&LimiterSuite{})
+
+func (s *LimiterSuite) SetUpTest(c *C) {
+	s.DownloaderSuiteBase.SetUpTest(c)
+}
+
+func (s *LimiterSuite) TearDownTest(c *C) {
+	s.DownloaderSuiteBase.TearDownTest(c)
+}
+
+func (s *LimiterSuite) TestSchedule(c *C) {
+	_, err := NewLimiter(0, []utils.BandwidthWindow{{Start: "25:00", End: "01:00"}}, 0)
+	c.Check(err, ErrorMatches, "invalid time.*")
+
+	limiter, err := NewLimiter(1024, []utils.BandwidthWindow{
+		{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "18:00", Limit: 10240},
+		{Start: "22:00", End: "06:00", Limit: 0},
+	}, 0)
+	c.Assert(err, IsNil)
+
+	// 2024-01-01 is Monday
+	limiter.now = func() time.Time { return time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local) }
+	c.Check(limiter.Status().Limit, Equals, int64(10240*1024))
+
+	limiter.now = func() time.Time { return time.Date(2024, 1, 1, 23, 0, 0, 0, time.Local) }
+	c.Check(limiter.Status().Limit, Equals, int64(0))
+
+	limiter.now = func() time.Time { return time.Date(2024, 1, 6, 10, 0, 0, 0, time.Local) }
+	c.Check(limiter.Status().Limit, Equals, int64(1024))
+
+	limiter.SetLimitOverride(2048)
+	c.Check(limiter.Status().Limit, Equals, int64(2048))
+	c.Check(limiter.Status().Override, Equals, true)
+
+	limiter.ClearLimitOverride()
+	c.Check(limiter.Status().Limit, Equals, int64(1024))
+	c.Check(limiter.Status().Override, Equals, false)
+}
+
+func (s *LimiterSuite) TestWriterQuota(c *C) {
+	limiter, _ := NewLimiter(0, nil, 10)
+
+	var buf bytes.Buffer
+	w := limiter.Writer(&buf)
+
+	n, err := w.Write([]byte("0123456"))
+	c.Check(n, Equals, 7)
+	c.Check(err, IsNil)
+	c.Check(limiter.Check(), IsNil)
+
+	n, err = w.Write([]byte("789abc"))
+	c.Check(n, Equals, 3)
+	c.Check(err, Equals, ErrQuotaExceeded)
+	c.Check(buf.String(), Equals, "0123456789")
+	c.Check(limiter.Check(), Equals, ErrQuotaExceeded)
+	c.Check(limiter.Status().Downloaded, Equals, int64(10))
+
+	limiter.ResetUsage()
+	c.Check(limiter.Check(), IsNil)
+}
+
+func (s *LimiterSuite) TestDownloadQuota(c *C) {
+	content := strings.Repeat("0123456789", 1000)
+	requests := []string{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Get("Range"))
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+	}))
+	defer ts.Close()
+
+	limiter, _ := NewLimiter(0, nil, 4000)
+	d, err := NewDownloaderWithLimiter(limiter, 3, utils.DownloadOptions{}, s.progress)
+	c.Assert(err, IsNil)
+
+	err = d.Download(s.ctx, ts.URL+"/file", s.tempfile.Name())
+	c.Assert(err, NotNil)
+	c.Check(IsQuotaExceeded(err), Equals, true)
+	// quota errors are not retried
+	c.Check(requests, DeepEquals, []string{""})
+
+	// partial download is discarded
+	_, err = os.Stat(s.tempfile.Name() + ".down")
+	c.Check(os.IsNotExist(err), Equals, true)
+
+	// no more requests once quota is exceeded
+	c.Check(IsQuotaExceeded(d.Download(s.ctx, ts.URL+"/file", s.tempfile.Name())), Equals, true)
+	c.Check(requests, HasLen, 1)
+
+	// raising the quota at runtime, download starts from scratch
+	limiter.SetQuota(0)
+	c.Assert(d.Download(s.ctx, ts.URL+"/file", s.tempfile.Name()), IsNil)
+	c.Check(requests[1:], DeepEquals, []string{""})
+
+	contents, _ := ioutil.ReadFile(s.tempfile.Name())
+	c.Check(string(contents), Equals, content)
+}
+
+func (s *LimiterSuite) TestDownloadSpeed(c *C) {
+	content := strings.Repeat("0123456789", 2000)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+	}))
+	defer ts.Close()
+
+	limiter, _ := NewLimiter(0, nil, 0)
+	limiter.SetLimitOverride(40000)
+	d, _ := NewDownloaderWithLimiter(limiter, 1, utils.DownloadOptions{}, s.progress)
+
+	start := time.Now()
+	c.Assert(d.Download(s.ctx, ts.URL+"/file", s.tempfile.Name()), IsNil)
+	c.Check(time.Since(start) > 300*time.Millisecond, Equals, true)
+	c.Check(limiter.Status().Downloaded, Equals, int64(len(content)))
+}
@@ -64,3 +64,16 @@ class ConfigShowTest(BaseTest):
     """
     runCmd = ["aptly", "config", "show"]
     gold_processor = BaseTest.expand_environ
+
+
+class ConfigShowPasswordTest(BaseTest):
+    """
+    config showing: download password is masked
+    """
+    runCmd = ["aptly", "config", "show"]
+    configOverride = {"downloadOptions": {"username": "aptly", "password": "secret"}}
+
+    def check(self):
+        self.check_in('"password": "******"', self.output)
+        if "secret" in self.output:
+            raise Exception("password is not masked in %s" % self.output)
@@ -415,3 +415,11 @@ class CreateMirror32Test(BaseTest):
     def check(self):
         self.check_output()
         self.check_cmd_output("aptly mirror show mirror32", "mirror_show")
+
+
+class CreateMirror33Test(BaseTest):
+    """
+    create mirror: archive url without scheme is not a local path
+    """
+    runCmd = "aptly mirror create -ignore-signatures mirror33 deb.debian.org/debian stable main"
+    expectedCode = 1
@@ -1,5 +1,6 @@
 import re
 from lib import BaseTest
+from show import prepareLocalUpstream
 
 
 class EditMirror1Test(BaseTest):
@@ -114,3 +115,16 @@ class EditMirror10Test(BaseTest):
     requiresFTP = True
     fixtureCmds = ["aptly mirror create -ignore-signatures mirror10 ftp://ftp.ru.debian.org/debian stretch main"]
     runCmd = "aptly mirror edit -ignore-signatures -archive-url ftp://ftp.ch.debian.org/debian mirror10"
+
+
+class EditMirror11Test(BaseTest):
+    """
+    edit mirror: archive url without scheme is not a local path
+    """
+    runCmd = "aptly mirror edit -archive-url=deb.debian.org/debian mirror11"
+    expectedCode = 1
+
+    def prepare(self):
+        super(EditMirror11Test, self).prepare()
+        prepareLocalUpstream(self)
+        self.run_cmd("aptly mirror create -ignore-signatures mirror11 ${aptlyroot}/upstream/ stable main")
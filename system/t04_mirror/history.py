@@ -0,0 +1,65 @@
+import re
+from lib import BaseTest
+
+
+def filterOutDates(_, s):
+    return re.sub(r'\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}[^:]*: (.*) in [0-9.a-z]+', r'<date>: \1 in <duration>', s)
+
+
+class MirrorHistory1Test(BaseTest):
+    """
+    mirror history: added and upgraded packages
+    """
+    fixtureCmds = [
+        "aptly repo create local-repo",
+        "aptly repo add local-repo ${files}/libboost-program-options-dev_1.49.0.1_i386.deb",
+        "aptly publish repo -skip-signing -distribution=stable local-repo",
+        "aptly mirror create -ignore-signatures local-mirror ${aptlyroot}/public/ stable main",
+        "aptly mirror update -ignore-signatures local-mirror",
+        "aptly repo remove local-repo libboost-program-options-dev",
+        "aptly repo add local-repo ${files}/libboost-program-options-dev_1.62.0.1_i386.deb",
+        "aptly publish update -skip-signing stable",
+        "aptly mirror update -ignore-signatures local-mirror",
+    ]
+    runCmd = "aptly mirror history -with-packages local-mirror"
+    outputMatchPrepare = filterOutDates
+
+
+class MirrorHistory2Test(BaseTest):
+    """
+    mirror history: downgraded and removed packages
+    """
+    fixtureCmds = [
+        "aptly repo create local-repo",
+        "aptly repo add local-repo ${files}/libboost-program-options-dev_1.62.0.1_i386.deb ${changes}/hardlink_0.2.1_amd64.deb",
+        "aptly publish repo -skip-signing -architectures=i386,amd64 -distribution=stable local-repo",
+        "aptly mirror create -ignore-signatures local-mirror ${aptlyroot}/public/ stable main",
+        "aptly mirror update -ignore-signatures local-mirror",
+        "aptly repo remove local-repo libboost-program-options-dev hardlink",
+        "aptly repo add local-repo ${files}/libboost-program-options-dev_1.49.0.1_i386.deb",
+        "aptly publish update -skip-signing stable",
+        "aptly mirror update -ignore-signatures local-mirror",
+    ]
+    runCmd = "aptly mirror history -with-packages local-mirror"
+    outputMatchPrepare = filterOutDates
+
+
+class MirrorHistory3Test(BaseTest):
+    """
+    mirror history: without packages, mirror hasn't been updated
+    """
+    fixtureCmds = [
+        "aptly repo create local-repo",
+        "aptly repo add local-repo ${files}/libboost-program-options-dev_1.49.0.1_i386.deb",
+        "aptly publish repo -skip-signing -distribution=stable local-repo",
+        "aptly mirror create -ignore-signatures local-mirror ${aptlyroot}/public/ stable main",
+    ]
+    runCmd = "aptly mirror history local-mirror"
+
+
+class MirrorHistory4Test(BaseTest):
+    """
+    mirror history: no such mirror
+    """
+    runCmd = "aptly mirror history mirror-xyz"
+    expectedCode = 1
@@ -1,5 +1,22 @@
 from lib import BaseTest
+import os
 import re
+import shutil
+
+
+def prepareLocalUpstream(self):
+    """
+    local archive: published local repo copied into ~/.aptly/upstream
+    """
+    self.run_cmd("aptly repo create -distribution=stable upstream")
+    self.run_cmd("aptly repo add upstream ${changes}/hardlink_0.2.1_amd64.deb")
+    self.run_cmd("aptly publish repo -skip-signing -architectures=amd64 upstream")
+
+    shutil.copytree(os.path.join(os.environ["HOME"], ".aptly", "public"),
+                    os.path.join(os.environ["HOME"], ".aptly", "upstream"))
+
+    self.run_cmd("aptly publish drop stable")
+    self.run_cmd("aptly repo drop upstream")
 
 
 class ShowMirror1Test(BaseTest):
@@ -40,3 +57,52 @@ class ShowMirror4Test(BaseTest):
 
     def outputMatchPrepare(self, s):
         return re.sub(r"(Date|Valid-Until): [,0-9:+A-Za-z -]+\n", "", s)
+
+
+class ShowMirror5Test(BaseTest):
+    """
+    show mirror: local archive mirror with translations
+    """
+    runCmd = "aptly mirror show mirror5"
+    gold_processor = BaseTest.expand_environ
+
+    def prepare(self):
+        super(ShowMirror5Test, self).prepare()
+        prepareLocalUpstream(self)
+        self.run_cmd("aptly mirror create -ignore-signatures -with-translations mirror5 ${aptlyroot}/upstream/ stable main")
+
+    def outputMatchPrepare(self, s):
+        return re.sub(r"Date: [,0-9:+A-Za-z -]+\n", "", s)
+
+
+class ShowMirror6Test(BaseTest):
+    """
+    show mirror: local archive mirror without translations
+    """
+    runCmd = "aptly mirror show mirror6"
+    gold_processor = BaseTest.expand_environ
+
+    def prepare(self):
+        super(ShowMirror6Test, self).prepare()
+        prepareLocalUpstream(self)
+        self.run_cmd("aptly mirror create -ignore-signatures mirror6 ${aptlyroot}/upstream/ stable main")
+
+    def outputMatchPrepare(self, s):
+        return re.sub(r"Date: [,0-9:+A-Za-z -]+\n", "", s)
+
+
+class ShowMirror7Test(BaseTest):
+    """
+    show mirror: empty header resets headers given before it
+    """
+    runCmd = "aptly mirror show mirror7"
+    gold_processor = BaseTest.expand_environ
+
+    def prepare(self):
+        super(ShowMirror7Test, self).prepare()
+        prepareLocalUpstream(self)
+        self.run_cmd("aptly mirror create -ignore-signatures -header 'X-First: 1' -header '' -header 'X-Second: 2' "
+                     "mirror7 ${aptlyroot}/upstream/ stable main")
+
+    def outputMatchPrepare(self, s):
+        return re.sub(r"Date: [,0-9:+A-Za-z -]+\n", "", s)
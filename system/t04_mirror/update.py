@@ -422,3 +422,48 @@ class UpdateMirror24Test(BaseTest):
 
     def output_processor(self, output):
         return "\n".join(sorted(output.split("\n")))
+
+
+class UpdateMirror25Test(BaseTest):
+    """
+    update mirrors: files are fetched from pool peers, falling back to upstream
+    """
+    configOverride = {"downloadConcurrency": 1}
+    runCmd = "aptly mirror update -ignore-signatures peers"
+
+    def prepare(self):
+        super(UpdateMirror25Test, self).prepare()
+
+        # upstream archive is published local repo, package files are removed from the pool afterwards
+        self.run_cmd("aptly repo create -distribution=stable upstream")
+        self.run_cmd("aptly repo add upstream ${changes}/hardlink_0.2.1_amd64.deb ${files}/libboost-program-options-dev_1.49.0.1_i386.deb")
+        self.run_cmd("aptly publish repo -skip-signing -architectures=amd64,i386 upstream")
+
+        upstream = os.path.join(os.environ["HOME"], ".aptly", "upstream")
+        shutil.copytree(os.path.join(os.environ["HOME"], ".aptly", "public"), upstream)
+
+        self.run_cmd("aptly publish drop stable")
+        self.run_cmd("aptly repo drop upstream")
+        self.run_cmd("aptly db cleanup")
+
+        # peer (served by the same web server) has only one of the files
+        peer = os.path.join(upstream, "api", "pool", "sha256", "668399580590bf1ffcd9eb161b6e574751e15f71820c6e08245dac7c5111a0ee")
+        os.makedirs(peer)
+        shutil.copy(os.path.join(os.path.dirname(inspect.getsourcefile(BaseTest)), "changes", "hardlink_0.2.1_amd64.deb"), peer)
+
+        self.webServerUrl = self.start_webserver(upstream)
+        self.configOverride = dict(self.configOverride, poolPeers=[self.webServerUrl])
+        self.prepare_default_config()
+
+        self.run_cmd("aptly mirror create -ignore-signatures -architectures=amd64,i386 peers %s stable main" % self.webServerUrl)
+
+    def output_processor(self, output):
+        return "\n".join(sorted(output.split("\n")))
+
+    def gold_processor(self, gold):
+        return string.Template(gold).substitute({'url': self.webServerUrl})
+
+    def check(self):
+        super(UpdateMirror25Test, self).check()
+        self.check_exists('pool/66/83/99580590bf1ffcd9eb161b6e5747_hardlink_0.2.1_amd64.deb')
+        self.check_exists('pool/c7/6b/4bd12fd92e4dfe1b55b18a67a669_libboost-program-options-dev_1.49.0.1_i386.deb')
@@ -96,3 +96,53 @@ class FilterSnapshot7Test(BaseTest):
 
         self.check_output()
         self.check_cmd_output("aptly snapshot show --with-packages snap2", "snapshot_show", match_prepare=remove_created_at)
+
+
+class FilterSnapshot8Test(BaseTest):
+    """
+    filter snapshot: keep latest versions
+    """
+    fixtureCmds = [
+        "aptly repo create local-repo",
+        "aptly repo add local-repo ${files}",
+        "aptly snapshot create snap1 from repo local-repo",
+    ]
+    runCmd = "aptly snapshot filter -keep-versions=1 snap1 snap2 'Name'"
+
+    def check(self):
+        def remove_created_at(s):
+            return re.sub(r"Created At: [0-9:A-Za-z -]+\n", "", s)
+
+        self.check_output()
+        self.check_cmd_output("aptly snapshot show -with-packages snap2", "snapshot_show", match_prepare=remove_created_at)
+
+
+class FilterSnapshot9Test(BaseTest):
+    """
+    filter snapshot: recently imported packages are kept by age
+    """
+    fixtureCmds = [
+        "aptly repo create local-repo",
+        "aptly repo add local-repo ${files}",
+        "aptly snapshot create snap1 from repo local-repo",
+    ]
+    runCmd = "aptly snapshot filter -keep-days=30 snap1 snap2 'Name'"
+
+    def check(self):
+        def remove_created_at(s):
+            return re.sub(r"Created At: [0-9:A-Za-z -]+\n", "", s)
+
+        self.check_output()
+        self.check_cmd_output("aptly snapshot show -with-packages snap2", "snapshot_show", match_prepare=remove_created_at)
+
+
+class FilterSnapshot10Test(BaseTest):
+    """
+    filter snapshot: negative retention
+    """
+    fixtureCmds = [
+        "aptly repo create local-repo",
+        "aptly snapshot create snap1 from repo local-repo",
+    ]
+    runCmd = "aptly snapshot filter -keep-days=-1 snap1 snap2 'Name'"
+    expectedCode = 1
@@ -1,6 +1,19 @@
+import os
+import inspect
+
 from lib import BaseTest
 
 
+def prepareInstallability(self, *packages):
+    """
+    local repo with conflicting libraries and snapshot snap1 of it
+    """
+    base = os.path.join(os.path.dirname(inspect.getsourcefile(prepareInstallability)), "installability")
+    self.run_cmd("aptly repo create inst")
+    self.run_cmd(["aptly", "repo", "add", "inst"] + [os.path.join(base, package + "_1.0_amd64.deb") for package in packages])
+    self.run_cmd("aptly snapshot create snap1 from repo inst")
+
+
 class VerifySnapshot1Test(BaseTest):
     """
     verify snapshot: from wheezy
@@ -97,3 +110,36 @@ class VerifySnapshot10Test(BaseTest):
         "aptly snapshot create snap3 from mirror wheezy-non-free-src",
     ]
     runCmd = "aptly -dep-follow-source snapshot verify snap1 snap2 snap3"
+
+
+class VerifySnapshot11Test(BaseTest):
+    """
+    verify snapshot: installability, all packages installable
+    """
+    runCmd = "aptly snapshot verify -installability snap1"
+
+    def prepare(self):
+        super(VerifySnapshot11Test, self).prepare()
+        prepareInstallability(self, "libfoo1", "libbar1", "app-ok", "app-alt")
+
+
+class VerifySnapshot12Test(BaseTest):
+    """
+    verify snapshot: installability, dependencies conflict with each other
+    """
+    runCmd = "aptly snapshot verify -installability snap1"
+
+    def prepare(self):
+        super(VerifySnapshot12Test, self).prepare()
+        prepareInstallability(self, "libfoo1", "libbar1", "app-ok", "app-alt", "app-broken")
+
+
+class VerifySnapshot13Test(BaseTest):
+    """
+    verify snapshot: installability with missing dependencies
+    """
+    runCmd = "aptly snapshot verify -installability snap1"
+
+    def prepare(self):
+        super(VerifySnapshot13Test, self).prepare()
+        prepareInstallability(self, "libbar1", "app-ok", "app-alt")
@@ -0,0 +1,61 @@
+from lib import BaseTest
+
+
+def sorted_processor(s):
+    return "\n".join(sorted(s.split("\n")))
+
+
+class OverrideImport1Test(BaseTest):
+    """
+    override import: binary overrides
+    """
+    fixtureCmds = [
+        "aptly repo create local-repo",
+        "aptly repo add local-repo ${files}",
+        "aptly publish repo -skip-signing -distribution=maverick local-repo",
+    ]
+    runCmd = "aptly override import -type=binary maverick ${testfiles}/override.binary"
+
+    def check(self):
+        self.check_output()
+        self.check_cmd_output("aptly override show maverick", "show")
+        self.check_cmd_output("aptly override show -type=extra maverick", "show_extra")
+
+
+class OverrideImport2Test(BaseTest):
+    """
+    override import: overrides applied on publish update
+    """
+    fixtureCmds = [
+        "aptly repo create local-repo",
+        "aptly repo add local-repo ${files}",
+        "aptly publish repo -skip-signing -distribution=maverick local-repo",
+        "aptly override import -type=binary maverick ${testfiles}/override.binary",
+        "aptly override import -type=extra -component=main maverick ${testfiles}/override.extra",
+    ]
+    runCmd = "aptly publish update -skip-signing maverick"
+
+    def check(self):
+        self.check_output()
+        self.check_file_contents('public/dists/maverick/main/binary-i386/Packages', 'binary', match_prepare=sorted_processor)
+
+
+class OverrideImport3Test(BaseTest):
+    """
+    override import: no such published repository
+    """
+    runCmd = "aptly override import -type=binary maverick ${files}/aptly.pub"
+    expectedCode = 1
+
+
+class OverrideImport4Test(BaseTest):
+    """
+    override import: wrong component
+    """
+    fixtureCmds = [
+        "aptly repo create local-repo",
+        "aptly repo add local-repo ${files}",
+        "aptly publish repo -skip-signing -distribution=maverick local-repo",
+    ]
+    runCmd = "aptly override import -component=contrib maverick ${files}/aptly.pub"
+    expectedCode = 1
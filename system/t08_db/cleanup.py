@@ -1,4 +1,5 @@
 from lib import BaseTest
+import os
 
 
 class CleanupDB1Test(BaseTest):
@@ -150,3 +151,36 @@ class CleanupDB12Test(BaseTest):
         "aptly mirror drop gnuplot-maverick",
     ]
     runCmd = "aptly db cleanup -verbose -dry-run"
+
+
+class CleanupDB13Test(BaseTest):
+    """
+    cleanup db: files moved to trash with grace period, restored by import
+    """
+    configOverride = {"poolGracePeriod": 1}
+    fixtureCmds = [
+        "aptly repo create a",
+        "aptly repo add a ${changes}/hardlink_0.2.1_amd64.deb",
+        "aptly repo drop a",
+    ]
+    runCmd = "aptly db cleanup"
+
+    def poolFiles(self, root):
+        result = []
+        for dirpath, _, filenames in os.walk(os.path.join(os.environ["HOME"], ".aptly", root)):
+            result.extend(filenames)
+        return result
+
+    def check(self):
+        self.check_output()
+        self.check_equal(self.poolFiles("pool"), [])
+        self.check_equal(self.poolFiles("trash"), ["99580590bf1ffcd9eb161b6e5747_hardlink_0.2.1_amd64.deb"])
+
+        # file still within grace period is not purged
+        self.run_cmd("aptly db cleanup")
+        self.check_equal(self.poolFiles("trash"), ["99580590bf1ffcd9eb161b6e5747_hardlink_0.2.1_amd64.deb"])
+
+        self.run_cmd("aptly repo create b")
+        self.run_cmd("aptly repo add b ${changes}/hardlink_0.2.1_amd64.deb")
+        self.check_equal(self.poolFiles("pool"), ["99580590bf1ffcd9eb161b6e5747_hardlink_0.2.1_amd64.deb"])
+        self.check_equal(self.poolFiles("trash"), [])
@@ -0,0 +1,130 @@
+import os
+import shutil
+import inspect
+
+from lib import BaseTest
+from fs_endpoint_lib import FileSystemEndpointTest
+
+
+def prepareLegacyMirror(self):
+    """
+    mirror with package file at legacy (MD5-based) pool location
+    """
+    # upstream archive is published local repo, package files are removed from the pool afterwards
+    self.run_cmd("aptly repo create -distribution=stable upstream")
+    self.run_cmd("aptly repo add upstream ${files}/libboost-program-options-dev_1.49.0.1_i386.deb")
+    self.run_cmd("aptly publish repo -skip-signing -architectures=i386 upstream")
+
+    upstream = os.path.join(os.environ["HOME"], ".aptly", "upstream")
+    shutil.copytree(os.path.join(os.environ["HOME"], ".aptly", "public"), upstream)
+
+    self.run_cmd("aptly publish drop stable")
+    self.run_cmd("aptly repo drop upstream")
+    self.run_cmd("aptly db cleanup")
+
+    os.makedirs(os.path.join(os.environ["HOME"], ".aptly", "pool", "00", "35"))
+    shutil.copy(os.path.join(os.path.dirname(inspect.getsourcefile(BaseTest)), "files", "libboost-program-options-dev_1.49.0.1_i386.deb"),
+                os.path.join(os.environ["HOME"], ".aptly", "pool", "00", "35"))
+
+    self.run_cmd("aptly mirror create -ignore-signatures legacy %s/ stable main" % upstream)
+    self.run_cmd("aptly mirror update -ignore-signatures legacy")
+
+
+class MigratePoolDB1Test(BaseTest):
+    """
+    migrate pool db: nothing to migrate
+    """
+    fixtureCmds = [
+        "aptly repo create local",
+        "aptly repo add local ${files}/libboost-program-options-dev_1.49.0.1_i386.deb",
+    ]
+    runCmd = "aptly db migrate-pool"
+
+
+class MigratePoolDB2Test(BaseTest):
+    """
+    migrate pool db: dry run
+    """
+    runCmd = "aptly db migrate-pool -dry-run"
+
+    def prepare(self):
+        super(MigratePoolDB2Test, self).prepare()
+        prepareLegacyMirror(self)
+
+    def check(self):
+        self.check_output()
+        self.check_exists("pool/00/35/libboost-program-options-dev_1.49.0.1_i386.deb")
+        self.check_not_exists("pool/c7/6b/4bd12fd92e4dfe1b55b18a67a669_libboost-program-options-dev_1.49.0.1_i386.deb")
+
+
+class MigratePoolDB3Test(BaseTest):
+    """
+    migrate pool db: legacy file is moved to the new location
+    """
+    runCmd = "aptly db migrate-pool"
+
+    def prepare(self):
+        super(MigratePoolDB3Test, self).prepare()
+        prepareLegacyMirror(self)
+
+    def check(self):
+        self.check_output()
+        self.check_not_exists("pool/00/35/libboost-program-options-dev_1.49.0.1_i386.deb")
+        self.check_exists("pool/c7/6b/4bd12fd92e4dfe1b55b18a67a669_libboost-program-options-dev_1.49.0.1_i386.deb")
+        self.check_cmd_output("aptly db verify", "verify")
+        # migration is idempotent
+        self.check_cmd_output("aptly db migrate-pool", "migrate_again")
+
+
+def publishLegacyMirror(self, endpoint):
+    """
+    mirror with package file at legacy pool location published to filesystem endpoint
+    """
+    prepareLegacyMirror(self)
+    self.run_cmd("aptly snapshot create legacy from mirror legacy")
+    self.run_cmd("aptly publish snapshot -skip-signing legacy filesystem:%s:" % endpoint)
+
+
+class MigratePoolDB4Test(FileSystemEndpointTest):
+    """
+    migrate pool db: refused while published repositories link to pool with symlinks
+    """
+    runCmd = "aptly db migrate-pool"
+    expectedCode = 1
+
+    def prepare(self):
+        super(MigratePoolDB4Test, self).prepare()
+        publishLegacyMirror(self, "symlink")
+
+    def check(self):
+        self.check_output()
+        self.check_exists("pool/00/35/libboost-program-options-dev_1.49.0.1_i386.deb")
+        self.check_is_symlink("public_symlink/pool/main/b/boost-defaults/libboost-program-options-dev_1.49.0.1_i386.deb")
+        self.check_exists("public_symlink/pool/main/b/boost-defaults/libboost-program-options-dev_1.49.0.1_i386.deb")
+
+
+class MigratePoolDB5Test(FileSystemEndpointTest):
+    """
+    migrate pool db: dry run warns about published repositories linking to pool with symlinks
+    """
+    runCmd = "aptly db migrate-pool -dry-run"
+
+    def prepare(self):
+        super(MigratePoolDB5Test, self).prepare()
+        publishLegacyMirror(self, "symlink")
+
+
+class MigratePoolDB6Test(FileSystemEndpointTest):
+    """
+    migrate pool db: published repositories with hardlinks don't prevent migration
+    """
+    runCmd = "aptly db migrate-pool"
+
+    def prepare(self):
+        super(MigratePoolDB6Test, self).prepare()
+        publishLegacyMirror(self, "hardlink")
+
+    def check(self):
+        self.check_output()
+        self.check_not_exists("pool/00/35/libboost-program-options-dev_1.49.0.1_i386.deb")
+        self.check_exists("public_hardlink/pool/main/b/boost-defaults/libboost-program-options-dev_1.49.0.1_i386.deb")
@@ -0,0 +1,32 @@
+from lib import BaseTest
+
+
+class UsageDB1Test(BaseTest):
+    """
+    usage db: empty database
+    """
+    runCmd = "aptly db usage"
+
+
+class UsageDB2Test(BaseTest):
+    """
+    usage db: repos, snapshots and published repos sharing files
+    """
+    fixtureCmds = [
+        "aptly repo create -distribution=stable local1",
+        "aptly repo add local1 ${changes}/hardlink_0.2.1_amd64.deb ${files}/libboost-program-options-dev_1.49.0.1_i386.deb",
+        "aptly repo create local2",
+        "aptly repo add local2 ${files}/libboost-program-options-dev_1.62.0.1_i386.deb",
+        "aptly snapshot create snap1 from repo local1",
+        "aptly repo remove local1 hardlink",
+        "aptly publish repo -skip-signing -architectures=amd64,i386 local1",
+    ]
+    runCmd = "aptly db usage"
+
+
+class UsageDB3Test(BaseTest):
+    """
+    usage db: machine-readable output
+    """
+    fixtureCmds = UsageDB2Test.fixtureCmds
+    runCmd = "aptly db usage -raw"
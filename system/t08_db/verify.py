@@ -0,0 +1,65 @@
+import os
+
+from lib import BaseTest
+
+
+class VerifyDB1Test(BaseTest):
+    """
+    verify db: all files are fine
+    """
+    fixtureCmds = [
+        "aptly repo create local",
+        "aptly repo add local ${changes}/hardlink_0.2.1_amd64.deb ${files}/libboost-program-options-dev_1.49.0.1_i386.deb",
+    ]
+    runCmd = "aptly db verify"
+
+
+class VerifyDB2Test(BaseTest):
+    """
+    verify db: missing and corrupt files
+    """
+    fixtureCmds = [
+        "aptly repo create local",
+        "aptly repo add local ${changes}/hardlink_0.2.1_amd64.deb ${files}/libboost-program-options-dev_1.49.0.1_i386.deb",
+    ]
+    runCmd = "aptly db verify -concurrency=1"
+    expectedCode = 1
+
+    def prepare(self):
+        super(VerifyDB2Test, self).prepare()
+
+        self.delete_file("pool/66/83/99580590bf1ffcd9eb161b6e5747_hardlink_0.2.1_amd64.deb")
+        # pool file is hardlink to the original file, so it's replaced rather than overwritten
+        self.delete_file("pool/c7/6b/4bd12fd92e4dfe1b55b18a67a669_libboost-program-options-dev_1.49.0.1_i386.deb")
+        with open(os.path.join(os.environ["HOME"], ".aptly",
+                               "pool/c7/6b/4bd12fd92e4dfe1b55b18a67a669_libboost-program-options-dev_1.49.0.1_i386.deb"), "w") as f:
+            f.write("corrupt")
+
+
+class VerifyDB3Test(BaseTest):
+    """
+    verify db: repair files of mirrored packages, files of local repos can't be repaired
+    """
+    fixtureCmds = [
+        "aptly repo create -distribution=stable upstream",
+        "aptly repo add upstream ${changes}/hardlink_0.2.1_amd64.deb",
+        "aptly publish repo -skip-signing -architectures=amd64 upstream",
+        "aptly mirror create -ignore-signatures mirror ${aptlyroot}/public/ stable main",
+        "aptly mirror update -ignore-signatures mirror",
+        "aptly repo create local",
+        "aptly repo add local ${files}/libboost-program-options-dev_1.49.0.1_i386.deb",
+    ]
+    runCmd = "aptly db verify -repair -concurrency=1"
+    expectedCode = 1
+    gold_processor = BaseTest.expand_environ
+
+    def prepare(self):
+        super(VerifyDB3Test, self).prepare()
+
+        self.delete_file("pool/66/83/99580590bf1ffcd9eb161b6e5747_hardlink_0.2.1_amd64.deb")
+        self.delete_file("pool/c7/6b/4bd12fd92e4dfe1b55b18a67a669_libboost-program-options-dev_1.49.0.1_i386.deb")
+
+    def check(self):
+        self.check_output()
+        self.check_exists("pool/66/83/99580590bf1ffcd9eb161b6e5747_hardlink_0.2.1_amd64.deb")
+        self.check_not_exists("pool/c7/6b/4bd12fd92e4dfe1b55b18a67a669_libboost-program-options-dev_1.49.0.1_i386.deb")
@@ -344,3 +344,31 @@ class AddRepo16Test(BaseTest):
         self.check_cmd_output("aptly repo show repo2", "repo_show")
 
         shutil.rmtree(self.tempSrcDir)
+
+
+class AddRepo17Test(BaseTest):
+    """
+    add package to local repo: unsigned .deb allowed by uploaders rule allowing '*'
+    """
+    fixtureCmds = [
+        "aptly repo create -uploaders-file=${changes}/uploaders5.json repo17",
+    ]
+    runCmd = "aptly repo add repo17 ${changes}/hardlink_0.2.1_amd64.deb"
+
+    def check(self):
+        self.check_output()
+        self.check_cmd_output("aptly repo show -with-packages repo17", "repo_show")
+
+
+class AddRepo18Test(BaseTest):
+    """
+    add package to local repo: unsigned .deb denied by uploaders rule denying '*'
+    """
+    fixtureCmds = [
+        "aptly repo create -uploaders-file=${changes}/uploaders5.json repo18",
+    ]
+    runCmd = "aptly repo add repo18 ${files}/libboost-program-options-dev_1.49.0.1_i386.deb"
+    expectedCode = 1
+
+    def outputMatchPrepare(self, s):
+        return s.replace(os.path.join(os.path.dirname(inspect.getsourcefile(BaseTest)), "files"), "")
@@ -61,3 +61,76 @@ class RemoveRepo4Test(BaseTest):
 
     def output_processor(self, output):
         return "\n".join(sorted(output.split("\n")))
+
+
+class RemoveRepo5Test(BaseTest):
+    """
+    remove from local repo: keep latest versions, no query
+    """
+    fixtureCmds = [
+        "aptly repo create -comment=Cool -distribution=squeeze local-repo",
+        "aptly repo add local-repo ${files}"
+    ]
+    runCmd = "aptly repo remove -keep-versions=1 local-repo"
+
+    def check(self):
+        self.check_output()
+        self.check_cmd_output("aptly repo show -with-packages local-repo", "repo_show")
+
+    def output_processor(self, output):
+        return "\n".join(sorted(output.split("\n")))
+
+
+class RemoveRepo6Test(BaseTest):
+    """
+    remove from local repo: keep latest versions of packages matching query
+    """
+    fixtureCmds = [
+        "aptly repo create -comment=Cool -distribution=squeeze local-repo",
+        "aptly repo add local-repo ${files}"
+    ]
+    runCmd = "aptly repo remove -keep-versions=1 local-repo pyspi"
+
+    def check(self):
+        self.check_output()
+        self.check_cmd_output("aptly repo show -with-packages local-repo", "repo_show")
+
+    def output_processor(self, output):
+        return "\n".join(sorted(output.split("\n")))
+
+
+class RemoveRepo7Test(BaseTest):
+    """
+    remove from local repo: recently imported packages are kept by age
+    """
+    fixtureCmds = [
+        "aptly repo create -comment=Cool -distribution=squeeze local-repo",
+        "aptly repo add local-repo ${files}"
+    ]
+    runCmd = "aptly repo remove -keep-days=30 local-repo"
+
+    def check(self):
+        self.check_output()
+        self.check_cmd_output("aptly repo show -with-packages local-repo", "repo_show")
+
+
+class RemoveRepo8Test(BaseTest):
+    """
+    remove from local repo: negative retention
+    """
+    fixtureCmds = [
+        "aptly repo create local-repo",
+    ]
+    runCmd = "aptly repo remove -keep-versions=-1 local-repo"
+    expectedCode = 1
+
+
+class RemoveRepo9Test(BaseTest):
+    """
+    remove from local repo: query is required without retention
+    """
+    fixtureCmds = [
+        "aptly repo create local-repo",
+    ]
+    runCmd = "aptly repo remove local-repo"
+    expectedCode = 2
@@ -0,0 +1,71 @@
+import os
+import inspect
+
+from lib import BaseTest
+
+
+def prepareRdepends(self):
+    """
+    local repo with packages depending on libfoo1 in different ways
+    """
+    self.run_cmd("aptly repo create foo")
+    self.run_cmd(["aptly", "repo", "add", "foo", os.path.join(os.path.dirname(inspect.getsourcefile(prepareRdepends)), "rdepends")])
+
+
+class RdependsPackage1Test(BaseTest):
+    """
+    rdepends package: dependencies by name, version and virtual package
+    """
+    runCmd = "aptly package rdepends libfoo1"
+
+    def prepare(self):
+        super(RdependsPackage1Test, self).prepare()
+        prepareRdepends(self)
+
+
+class RdependsPackage2Test(BaseTest):
+    """
+    rdepends package: following recommends
+    """
+    runCmd = "aptly package rdepends -dep-follow-recommends -repo=foo libfoo1"
+
+    def prepare(self):
+        super(RdependsPackage2Test, self).prepare()
+        prepareRdepends(self)
+
+
+class RdependsPackage3Test(BaseTest):
+    """
+    rdepends package: $ReverseDepends query matches the same packages
+    """
+    runCmd = "aptly package search '$$ReverseDepends (libfoo1)'"
+
+    def prepare(self):
+        super(RdependsPackage3Test, self).prepare()
+        prepareRdepends(self)
+
+    def output_processor(self, output):
+        return "\n".join(sorted(output.split("\n")))
+
+
+class RdependsPackage4Test(BaseTest):
+    """
+    rdepends package: no reverse dependencies, no matching packages
+    """
+    runCmd = "aptly package rdepends 'Name (~ ^foo-)'"
+
+    def prepare(self):
+        super(RdependsPackage4Test, self).prepare()
+        prepareRdepends(self)
+
+    def check(self):
+        self.check_output()
+        self.check_cmd_output("aptly package rdepends libbar1", "no_results", expected_code=1)
+
+
+class RdependsPackage5Test(BaseTest):
+    """
+    rdepends package: conflicting options
+    """
+    runCmd = "aptly package rdepends -repo=foo -snapshot=foo libfoo1"
+    expectedCode = 1
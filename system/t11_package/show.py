@@ -1,10 +1,15 @@
 from lib import BaseTest
+import re
 
 
 def sortLines(_, s):
     return "\n".join(sorted(s.split("\n")))
 
 
+def sortLinesNoDate(_, s):
+    return sortLines(_, re.sub(r" at [0-9T:+Z-]+", " at DATE", s))
+
+
 class ShowPackage1Test(BaseTest):
     """
     show package: regular show
@@ -92,3 +97,38 @@ class ShowPackage8Test(BaseTest):
     ]
     outputMatchPrepare = sortLines
     runCmd = "aptly package show -with-references pyspi_0.6.1-1.3_source"
+
+
+class ShowPackage9Test(BaseTest):
+    """
+    show package: provenance of package added to local repo, the first repo is kept
+    """
+    fixtureCmds = [
+        "aptly repo create a",
+        "aptly repo create b",
+        "aptly repo add a ${files}/libboost-program-options-dev_1.49.0.1_i386.deb",
+        "aptly repo add b ${files}/libboost-program-options-dev_1.49.0.1_i386.deb",
+    ]
+    outputMatchPrepare = sortLinesNoDate
+    runCmd = "aptly package show -provenance 'libboost-program-options-dev'"
+
+
+class ShowPackage10Test(BaseTest):
+    """
+    show package: provenance of package included from signed .changes
+    """
+    fixtureCmds = [
+        "aptly repo create -distribution=unstable unstable",
+        "aptly repo include -no-remove-files -keyring=${files}/aptly.pub ${changes}/hardlink_0.2.1_amd64.changes",
+    ]
+    outputMatchPrepare = sortLinesNoDate
+    runCmd = "aptly package show -provenance 'hardlink (= 0.2.1), $$Architecture (amd64)'"
+
+
+class ShowPackage11Test(BaseTest):
+    """
+    show package: provenance of package added before provenance tracking
+    """
+    fixtureDB = True
+    outputMatchPrepare = sortLines
+    runCmd = "aptly package show -provenance nginx-full_1.2.1-2.2+wheezy2_amd64"
@@ -0,0 +1,36 @@
+from api_lib import APITest
+
+
+class DownloadsAPITestLimits(APITest):
+    """
+    GET /api/downloads/limits, PUT /api/downloads/limits
+    """
+    def check(self):
+        resp = self.get("/api/downloads/limits")
+        self.check_equal(resp.status_code, 200)
+        self.check_equal(resp.json()["Limit"], 0)
+        self.check_equal(resp.json()["Quota"], 0)
+        self.check_equal(resp.json()["Override"], False)
+
+        # values are in bytes both ways
+        resp = self.put("/api/downloads/limits", json={"Limit": 1048576, "Quota": 104857600})
+        self.check_equal(resp.status_code, 200)
+        self.check_equal(resp.json()["Limit"], 1048576)
+        self.check_equal(resp.json()["Quota"], 104857600)
+        self.check_equal(resp.json()["Override"], True)
+
+        resp = self.get("/api/downloads/limits")
+        self.check_equal(resp.json()["Limit"], 1048576)
+        self.check_equal(resp.json()["Quota"], 104857600)
+
+        # invalid values
+        self.check_equal(self.put("/api/downloads/limits", json={"Limit": -1}).status_code, 400)
+        self.check_equal(self.put("/api/downloads/limits", json={"Quota": -1}).status_code, 400)
+        self.check_equal(self.put("/api/downloads/limits", json={"Limit": 0, "ResetLimit": True}).status_code, 400)
+        self.check_equal(self.get("/api/downloads/limits").json()["Limit"], 1048576)
+
+        resp = self.put("/api/downloads/limits", json={"ResetLimit": True, "Quota": 0})
+        self.check_equal(resp.status_code, 200)
+        self.check_equal(resp.json()["Limit"], 0)
+        self.check_equal(resp.json()["Quota"], 0)
+        self.check_equal(resp.json()["Override"], False)
@@ -45,3 +45,70 @@ class GraphAPITest(APITest):
         # remove the repos again
         for repo in tempRepos:
             self.check_equal(self.delete("/api/repos/" + repo, params={"force": "1"}).status_code, 200)
+
+
+class GraphAPITestJSON(APITest):
+    """
+    GET /graph.json, GET /graph.html, ?name= filtering
+    """
+
+    def check(self):
+        repo_name = self.random_name()
+        other_repo_name = self.random_name()
+        snapshot_name = self.random_name()
+        prefix = self.random_name()
+
+        self.check_equal(self.post("/api/repos", json={"Name": repo_name, "Comment": "graph repo",
+                                                       "DefaultDistribution": "wheezy"}).status_code, 201)
+        self.check_equal(self.post("/api/repos", json={"Name": other_repo_name}).status_code, 201)
+        self.check_equal(self.post("/api/repos/" + repo_name + "/snapshots", json={"Name": snapshot_name}).status_code, 201)
+        self.check_equal(self.post("/api/publish/" + prefix,
+                                   json={
+                                       "SourceKind": "snapshot",
+                                       "Sources": [{"Name": snapshot_name}],
+                                       "Architectures": ["i386"],
+                                       "Signing": {"Skip": True},
+                                   }).status_code, 201)
+
+        resp = self.get("/api/graph.json")
+        self.check_equal(resp.status_code, 200)
+        self.check_equal(resp.headers["Content-Type"], "application/json; charset=utf-8")
+        names = [node["Name"] for node in resp.json()["Nodes"]]
+        self.check_in(repo_name, names)
+        self.check_in(other_repo_name, names)
+
+        # lineage of snapshot: repo it was created from and published repository
+        resp = self.get("/api/graph.json", params={"name": snapshot_name})
+        self.check_equal(resp.status_code, 200)
+        graph = resp.json()
+        nodes = dict((node["Name"], node) for node in graph["Nodes"])
+        self.check_equal(sorted(nodes.keys()), sorted([repo_name, snapshot_name, prefix + "/wheezy"]))
+        self.check_equal(nodes[repo_name]["Kind"], "repo")
+        self.check_equal(nodes[repo_name]["Meta"], {"comment": "graph repo", "packages": "0"})
+        self.check_equal(nodes[snapshot_name]["Kind"], "snapshot")
+        self.check_equal(nodes[prefix + "/wheezy"]["Kind"], "publish")
+        self.check_equal(nodes[prefix + "/wheezy"]["Meta"]["prefix"], prefix)
+        self.check_equal(sorted((edge["From"], edge["To"]) for edge in graph["Edges"]),
+                         sorted([(nodes[repo_name]["UUID"], nodes[snapshot_name]["UUID"]),
+                                 (nodes[snapshot_name]["UUID"], nodes[prefix + "/wheezy"]["UUID"])]))
+
+        # same lineage is found by name of published repository
+        resp = self.get("/api/graph.json", params={"name": prefix + "/wheezy"})
+        self.check_equal(resp.status_code, 200)
+        self.check_equal(resp.json(), graph)
+
+        resp = self.get("/api/graph.json", params={"name": other_repo_name})
+        self.check_equal(resp.status_code, 200)
+        self.check_equal([node["Name"] for node in resp.json()["Nodes"]], [other_repo_name])
+        self.check_equal(resp.json()["Edges"], [])
+
+        self.check_equal(self.get("/api/graph.json", params={"name": self.random_name()}).status_code, 404)
+
+        resp = self.get("/api/graph.html", params={"name": snapshot_name})
+        self.check_equal(resp.status_code, 200)
+        self.check_equal(resp.headers["Content-Type"], "text/html; charset=utf-8")
+        self.check_in(snapshot_name, resp.content)
+        self.check_in(repo_name, resp.content)
+        self.check_equal(other_repo_name in resp.content, False)
+
+        self.check_equal(self.get("/api/graph.html", params={"name": self.random_name()}).status_code, 404)
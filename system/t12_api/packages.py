@@ -42,3 +42,44 @@ class PackagesAPITestShow(APITest):
 
         resp = self.get("/api/packages/" + urllib.quote('Pamd64 no-such-package 1.0 3a8b37cbd9a3559e'))
         self.check_equal(resp.status_code, 404)
+
+
+class PackagesAPITestShowProvenance(APITest):
+    """
+    GET /api/packages/:key?provenance=1
+    """
+    def check(self):
+        repo_name = self.random_name()
+        self.check_equal(self.post("/api/repos", json={"Name": repo_name}).status_code, 201)
+
+        d = self.random_name()
+        self.check_equal(self.upload("/api/files/" + d, "libboost-program-options-dev_1.49.0.1_i386.deb").status_code, 200)
+        self.check_equal(self.post("/api/repos/" + repo_name + "/file/" + d).status_code, 200)
+
+        key = urllib.quote('Pi386 libboost-program-options-dev 1.49.0.1 918d2f433384e378')
+
+        resp = self.get("/api/packages/" + key)
+        self.check_equal(resp.status_code, 200)
+        self.check_equal("Provenance" in resp.json(), False)
+
+        resp = self.get("/api/packages/" + key, params={"provenance": "1"})
+        self.check_equal(resp.status_code, 200)
+        self.check_equal(resp.json()["Package"], "libboost-program-options-dev")
+        provenance = resp.json()["Provenance"]
+        self.check_equal(sorted(provenance.keys()), ["Action", "Date", "Origin", "OriginUUID"])
+        self.check_equal(provenance["Action"], "repo add")
+        self.check_equal(provenance["OriginUUID"] != "", True)
+
+        # adding package to another repo doesn't change provenance
+        other_repo_name = self.random_name()
+        self.check_equal(self.post("/api/repos", json={"Name": other_repo_name}).status_code, 201)
+        d = self.random_name()
+        self.check_equal(self.upload("/api/files/" + d, "libboost-program-options-dev_1.49.0.1_i386.deb").status_code, 200)
+        self.check_equal(self.post("/api/repos/" + other_repo_name + "/file/" + d).status_code, 200)
+
+        resp = self.get("/api/packages/" + key, params={"provenance": "1"})
+        self.check_equal(resp.status_code, 200)
+        self.check_equal(resp.json()["Provenance"], provenance)
+
+        resp = self.get("/api/packages/" + urllib.quote('Pamd64 no-such-package 1.0 3a8b37cbd9a3559e'), params={"provenance": "1"})
+        self.check_equal(resp.status_code, 404)
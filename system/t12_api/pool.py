@@ -0,0 +1,40 @@
+import hashlib
+import os
+import inspect
+
+from api_lib import APITest
+from lib import BaseTest
+
+
+class PoolAPITestFile(APITest):
+    """
+    GET /api/pool/sha256/:sha256/:filename
+    """
+    def check(self):
+        repo_name = self.random_name()
+        self.check_equal(self.post("/api/repos", json={"Name": repo_name}).status_code, 201)
+
+        d = self.random_name()
+        self.check_equal(self.upload("/api/files/" + d, "hardlink_0.2.1_amd64.deb", directory="changes").status_code, 200)
+        self.check_equal(self.post("/api/repos/" + repo_name + "/file/" + d).status_code, 200)
+
+        contents = open(os.path.join(os.path.dirname(inspect.getsourcefile(BaseTest)), "changes",
+                                     "hardlink_0.2.1_amd64.deb"), "rb").read()
+        sha256 = hashlib.sha256(contents).hexdigest()
+        uri = "/api/pool/sha256/%s/hardlink_0.2.1_amd64.deb" % sha256
+
+        resp = self.get(uri, params={"size": len(contents)})
+        self.check_equal(resp.status_code, 200)
+        self.check_equal(resp.content, contents)
+
+        # size should match as well
+        self.check_equal(self.get(uri, params={"size": len(contents) + 1}).status_code, 404)
+        self.check_equal(self.get(uri).status_code, 400)
+
+        # unknown & invalid checksums
+        self.check_equal(self.get("/api/pool/sha256/%s/hardlink_0.2.1_amd64.deb" % ("0" * 64),
+                                  params={"size": len(contents)}).status_code, 404)
+        self.check_equal(self.get("/api/pool/sha256/%s/hardlink_0.2.1_amd64.deb" % ("z" * 64),
+                                  params={"size": len(contents)}).status_code, 400)
+        self.check_equal(self.get("/api/pool/sha256/%s/hardlink_0.2.1_amd64.deb" % sha256[:32],
+                                  params={"size": len(contents)}).status_code, 400)
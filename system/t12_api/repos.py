@@ -329,3 +329,55 @@ class ReposAPITestPackagesAddDelete(APITest):
         self.check_equal(sorted(self.get("/api/repos/" + repo_name2 + "/packages").json()),
                          ['Pi386 libboost-program-options-dev 1.49.0.1 918d2f433384e378',
                           'Psource pyspi 0.6.1-1.4 f8f1daa806004e89'])
+
+
+class ReposAPITestRetain(APITest):
+    """
+    POST /api/repos/:name/retain
+    """
+    def check(self):
+        repo_name = self.random_name()
+
+        self.check_equal(self.post("/api/repos", json={"Name": repo_name}).status_code, 201)
+
+        d = self.random_name()
+        self.check_equal(
+            self.upload("/api/files/" + d,
+                        "libboost-program-options-dev_1.49.0.1_i386.deb", "libboost-program-options-dev_1.62.0.1_i386.deb",
+                        "pyspi_0.6.1-1.3.dsc", "pyspi_0.6.1-1.3.diff.gz", "pyspi_0.6.1.orig.tar.gz",
+                        "pyspi-0.6.1-1.3.stripped.dsc").status_code, 200)
+
+        self.check_equal(self.post("/api/repos/" + repo_name + "/file/" + d).status_code, 200)
+
+        # validation
+        self.check_equal(self.post("/api/repos/" + repo_name + "/retain", json={}).status_code, 400)
+        self.check_equal(self.post("/api/repos/" + repo_name + "/retain", json={"KeepVersions": -1}).status_code, 400)
+        self.check_equal(self.post("/api/repos/" + repo_name + "/retain",
+                                   json={"KeepVersions": 1, "Queries": ["pyspi ("]}).status_code, 400)
+        self.check_equal(self.post("/api/repos/no-such-repo/retain", json={"KeepVersions": 1}).status_code, 404)
+
+        # packages have been just imported, so age rule keeps everything
+        resp = self.post("/api/repos/" + repo_name + "/retain", json={"KeepDays": 30})
+        self.check_equal(resp.status_code, 200)
+        self.check_equal(resp.json()["Removed"], [])
+
+        # dry run
+        resp = self.post("/api/repos/" + repo_name + "/retain", json={"KeepVersions": 1, "DryRun": True})
+        self.check_equal(resp.status_code, 200)
+        self.check_equal(sorted(resp.json()["Removed"]),
+                         ['libboost-program-options-dev_1.49.0.1_i386', 'pyspi_0.6.1-1.3_source'])
+        self.check_equal(len(self.get("/api/repos/" + repo_name + "/packages").json()), 4)
+
+        # only packages matching query
+        resp = self.post("/api/repos/" + repo_name + "/retain", json={"KeepVersions": 1, "Queries": ["pyspi"]})
+        self.check_equal(resp.status_code, 200)
+        self.check_equal(resp.json()["Removed"], ['pyspi_0.6.1-1.3_source'])
+
+        # all packages
+        resp = self.post("/api/repos/" + repo_name + "/retain", json={"KeepVersions": 1})
+        self.check_equal(resp.status_code, 200)
+        self.check_equal(resp.json()["Removed"], ['libboost-program-options-dev_1.49.0.1_i386'])
+
+        self.check_equal(sorted(self.get("/api/repos/" + repo_name + "/packages").json()),
+                         ['Pi386 libboost-program-options-dev 1.62.0.1 7760e62f99c551cb',
+                          'Psource pyspi 0.6.1-1.4 f8f1daa806004e89'])
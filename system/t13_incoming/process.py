@@ -0,0 +1,136 @@
+import shutil
+import os
+import inspect
+import re
+from lib import BaseTest
+
+
+def gpgRemove(_, s):
+    return re.sub(r'Signature made .* using|gpgv: keyblock resource .*$|gpgv: Can\'t check signature: .*$', '', s, flags=re.MULTILINE)
+
+
+incomingDir = os.path.join(os.environ["HOME"], ".aptly", "incoming")
+
+
+def prepareIncoming(self, *subdirs):
+    """
+    copies upload into subdirectories of the incoming queue
+    """
+    for subdir in subdirs:
+        shutil.copytree(os.path.join(os.path.dirname(inspect.getsourcefile(BaseTest)), "changes"),
+                        os.path.join(incomingDir, subdir))
+
+
+class IncomingProcess1Test(BaseTest):
+    """
+    incoming process: upload from subdirectory is imported
+    """
+    fixtureCmds = [
+        "aptly repo create unstable",
+    ]
+    configOverride = {
+        "gpgProvider": "internal",
+        "IncomingQueues": {
+            "main": {
+                "dir": incomingDir,
+            },
+        },
+    }
+    outputMatchPrepare = gpgRemove
+    runCmd = "aptly incoming process -keyring=${files}/aptly.pub"
+
+    def prepare(self):
+        super(IncomingProcess1Test, self).prepare()
+        prepareIncoming(self, "01")
+
+    def check(self):
+        self.check_output()
+        self.check_cmd_output("aptly repo show -with-packages unstable", "repo_show")
+
+        self.check_not_exists("incoming/01/hardlink_0.2.1_amd64.changes")
+        self.check_not_exists("incoming/01/hardlink_0.2.1_amd64.deb")
+        self.check_exists('pool/66/83/99580590bf1ffcd9eb161b6e5747_hardlink_0.2.1_amd64.deb')
+
+
+class IncomingProcess2Test(BaseTest):
+    """
+    incoming process: upload rejected by uploaders is quarantined
+    """
+    fixtureCmds = [
+        "aptly repo create unstable",
+    ]
+    configOverride = {
+        "gpgProvider": "internal",
+        "IncomingQueues": {
+            "main": {
+                "dir": incomingDir,
+                "uploadersFile": os.path.join(os.path.dirname(inspect.getsourcefile(BaseTest)), "changes", "uploaders1.json"),
+            },
+        },
+    }
+    outputMatchPrepare = gpgRemove
+    runCmd = "aptly incoming process -keyring=${files}/aptly.pub"
+
+    def prepare(self):
+        super(IncomingProcess2Test, self).prepare()
+        prepareIncoming(self, "01")
+
+    def check(self):
+        self.check_output()
+        self.check_cmd_output("aptly repo show -with-packages unstable", "repo_show")
+
+        for name in ["hardlink_0.2.1_amd64.changes", "hardlink_0.2.1_amd64.deb", "hardlink_0.2.1.dsc", "hardlink_0.2.1.tar.gz"]:
+            self.check_not_exists(os.path.join("incoming", "01", name))
+            self.check_exists(os.path.join("incoming-quarantine", "01", name))
+
+        self.check_file_contents("incoming-quarantine/01/hardlink_0.2.1_amd64.changes.reason", "reason")
+
+
+class IncomingProcess3Test(BaseTest):
+    """
+    incoming process: unknown queue
+    """
+    runCmd = "aptly incoming process nosuchqueue"
+    configOverride = {
+        "IncomingQueues": {
+            "main": {
+                "dir": incomingDir,
+            },
+        },
+    }
+    expectedCode = 1
+
+
+class IncomingProcess4Test(BaseTest):
+    """
+    incoming process: no queues configured
+    """
+    runCmd = "aptly incoming process"
+    expectedCode = 1
+
+
+class IncomingProcess5Test(BaseTest):
+    """
+    incoming process: rejected uploads with same file names from different subdirectories don't clash
+    """
+    fixtureCmds = [
+        "aptly repo create unstable",
+    ]
+    configOverride = IncomingProcess2Test.configOverride
+    outputMatchPrepare = gpgRemove
+    runCmd = "aptly incoming process -keyring=${files}/aptly.pub"
+
+    def prepare(self):
+        super(IncomingProcess5Test, self).prepare()
+        prepareIncoming(self, "01", "02")
+
+    def check(self):
+        self.check_output()
+
+        for subdir in ["01", "02"]:
+            for name in ["hardlink_0.2.1_amd64.changes", "hardlink_0.2.1_amd64.deb", "hardlink_0.2.1.dsc", "hardlink_0.2.1.tar.gz"]:
+                self.check_not_exists(os.path.join("incoming", subdir, name))
+                self.check_exists(os.path.join("incoming-quarantine", subdir, name))
+
+            self.check_file_contents(os.path.join("incoming-quarantine", subdir, "hardlink_0.2.1_amd64.changes.reason"), "reason")
+
@@ -0,0 +1,31 @@
+import os
+import subprocess
+import time
+from lib import BaseTest
+
+
+incomingDir = os.path.join(os.environ["HOME"], ".aptly", "incoming")
+
+
+class IncomingServe1Test(BaseTest):
+    """
+    incoming serve: stops on SIGTERM
+    """
+    configOverride = {
+        "IncomingQueues": {
+            "main": {
+                "dir": incomingDir,
+            },
+        },
+    }
+
+    def run(self):
+        os.makedirs(incomingDir)
+
+        proc = self._start_process("aptly incoming serve -poll-interval=1s", stdout=subprocess.PIPE)
+        time.sleep(1)
+        proc.terminate()
+
+        output, _ = proc.communicate()
+        self.check_equal(proc.returncode, 0)
+        self.output = output
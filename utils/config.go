@@ -2,8 +2,12 @@ package utils
 
 import (
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 // ConfigStructure is structure of main configuration
@@ -11,7 +15,12 @@ type ConfigStructure struct { // nolint: maligned
 	RootDir                string                           `json:"rootDir"`
 	DownloadConcurrency    int                              `json:"downloadConcurrency"`
 	DownloadLimit          int64                            `json:"downloadSpeedLimit"`
+	DownloadSchedule       []BandwidthWindow                `json:"downloadSchedule"`
+	DownloadQuota          int64                            `json:"downloadQuota"`
 	DownloadRetries        int                              `json:"downloadRetries"`
+	DownloadOptions        DownloadOptions                  `json:"downloadOptions"`
+	MirrorUpdateWebhook    string                           `json:"mirrorUpdateWebhook"`
+	PoolPeers              []string                         `json:"poolPeers"`
 	DatabaseOpenAttempts   int                              `json:"databaseOpenAttempts"`
 	Architectures          []string                         `json:"architectures"`
 	DepFollowSuggests      bool                             `json:"dependencyFollowSuggests"`
@@ -24,12 +33,187 @@ type ConfigStructure struct { // nolint: maligned
 	GpgProvider            string                           `json:"gpgProvider"`
 	DownloadSourcePackages bool                             `json:"downloadSourcePackages"`
 	SkipLegacyPool         bool                             `json:"skipLegacyPool"`
+	PoolGracePeriod        int                              `json:"poolGracePeriod"`
 	PpaDistributorID       string                           `json:"ppaDistributorID"`
 	PpaCodename            string                           `json:"ppaCodename"`
 	SkipContentsPublishing bool                             `json:"skipContentsPublishing"`
+	UploadersUserHeader    string                           `json:"uploadersUserHeader"`
 	FileSystemPublishRoots map[string]FileSystemPublishRoot `json:"FileSystemPublishEndpoints"`
 	S3PublishRoots         map[string]S3PublishRoot         `json:"S3PublishEndpoints"`
 	SwiftPublishRoots      map[string]SwiftPublishRoot      `json:"SwiftPublishEndpoints"`
+	IncomingQueues         map[string]IncomingQueue         `json:"IncomingQueues"`
+}
+
+// BandwidthWindow is download speed limit applied during time window (local time)
+type BandwidthWindow struct {
+	// Days of week (mon, tue, ...), empty means every day
+	Days []string `json:"days"`
+	// Start and end of the window (HH:MM), window could span midnight
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Speed limit in kbytes/sec, 0 is unlimited
+	Limit int64 `json:"limit"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %#v, expected HH:MM", value)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Validate checks window for errors
+func (window BandwidthWindow) Validate() error {
+	for _, day := range window.Days {
+		if _, ok := weekdays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("invalid day of week %#v", day)
+		}
+	}
+
+	if _, err := parseClock(window.Start); err != nil {
+		return err
+	}
+
+	_, err := parseClock(window.End)
+	return err
+}
+
+// Matches checks whether time t is within the window
+func (window BandwidthWindow) Matches(t time.Time) bool {
+	start, err := parseClock(window.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(window.End)
+	if err != nil {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+
+	if start > end && minute < end {
+		// window spans midnight, t is in the part after midnight which started previous day
+		day = (day + 6) % 7
+	} else if !(start <= minute && (minute < end || start > end)) {
+		return false
+	}
+
+	if len(window.Days) == 0 {
+		return true
+	}
+
+	for _, d := range window.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DownloadOptions configures HTTP transport for downloads, globally or per mirror
+type DownloadOptions struct {
+	Proxy          string            `json:"proxy"`
+	CACertFile     string            `json:"caCertFile"`
+	ClientCertFile string            `json:"clientCertFile"`
+	ClientKeyFile  string            `json:"clientKeyFile"`
+	Username       string            `json:"username"`
+	Password       string            `json:"password"`
+	PasswordFile   string            `json:"passwordFile"`
+	PasswordEnv    string            `json:"passwordEnv"`
+	Headers        map[string]string `json:"headers"`
+}
+
+// maskedPassword replaces password when options are displayed
+const maskedPassword = "******"
+
+// IsEmpty checks whether any of the options is set
+func (options DownloadOptions) IsEmpty() bool {
+	return options.Proxy == "" && options.CACertFile == "" && options.ClientCertFile == "" && options.ClientKeyFile == "" &&
+		options.Username == "" && options.Password == "" && options.PasswordFile == "" && options.PasswordEnv == "" &&
+		len(options.Headers) == 0
+}
+
+// GetPassword returns password for HTTP basic authentication, which is either
+// set directly, read from the file or taken from the environment variable
+func (options DownloadOptions) GetPassword() (string, error) {
+	if options.Password != "" {
+		return options.Password, nil
+	}
+
+	if options.PasswordFile != "" {
+		contents, err := ioutil.ReadFile(options.PasswordFile)
+		if err != nil {
+			return "", fmt.Errorf("unable to read password file: %s", err)
+		}
+
+		return strings.TrimRight(string(contents), "\r\n"), nil
+	}
+
+	if options.PasswordEnv != "" {
+		password, ok := os.LookupEnv(options.PasswordEnv)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s with password is not set", options.PasswordEnv)
+		}
+
+		return password, nil
+	}
+
+	return "", nil
+}
+
+// MarshalJSON masks password, so that it doesn't show up in config dumps and API responses
+func (options DownloadOptions) MarshalJSON() ([]byte, error) {
+	type plainOptions DownloadOptions
+
+	masked := plainOptions(options)
+	if masked.Password != "" {
+		masked.Password = maskedPassword
+	}
+
+	return json.Marshal(masked)
+}
+
+// Merge returns options with non-empty fields of override applied on top, headers are merged
+func (options DownloadOptions) Merge(override DownloadOptions) DownloadOptions {
+	result := options
+
+	if override.Proxy != "" {
+		result.Proxy = override.Proxy
+	}
+	if override.CACertFile != "" {
+		result.CACertFile = override.CACertFile
+	}
+	if override.ClientCertFile != "" {
+		result.ClientCertFile = override.ClientCertFile
+		result.ClientKeyFile = override.ClientKeyFile
+	}
+	if override.Username != "" {
+		result.Username = override.Username
+		result.Password = override.Password
+		result.PasswordFile = override.PasswordFile
+		result.PasswordEnv = override.PasswordEnv
+	}
+
+	if len(override.Headers) > 0 {
+		result.Headers = make(map[string]string, len(options.Headers)+len(override.Headers))
+		for k, v := range options.Headers {
+			result.Headers[k] = v
+		}
+		for k, v := range override.Headers {
+			result.Headers[k] = v
+		}
+	}
+
+	return result
 }
 
 // FileSystemPublishRoot describes single filesystem publishing entry point
@@ -72,11 +256,25 @@ type SwiftPublishRoot struct {
 	Container      string `json:"container"`
 }
 
+// IncomingQueue describes single incoming directory processed by 'aptly incoming'
+type IncomingQueue struct {
+	Dir              string `json:"dir"`
+	Repo             string `json:"repo"`
+	QuarantineDir    string `json:"quarantineDir"`
+	UploadersFile    string `json:"uploadersFile"`
+	AcceptUnsigned   bool   `json:"acceptUnsigned"`
+	IgnoreSignatures bool   `json:"ignoreSignatures"`
+	ForceReplace     bool   `json:"forceReplace"`
+	PublishUpdate    bool   `json:"publishUpdate"`
+}
+
 // Config is configuration for aptly, shared by all modules
 var Config = ConfigStructure{
 	RootDir:                filepath.Join(os.Getenv("HOME"), ".aptly"),
 	DownloadConcurrency:    4,
 	DownloadLimit:          0,
+	DownloadSchedule:       []BandwidthWindow{},
+	PoolPeers:              []string{},
 	DatabaseOpenAttempts:   -1,
 	Architectures:          []string{},
 	DepFollowSuggests:      false,
@@ -93,6 +291,7 @@ var Config = ConfigStructure{
 	FileSystemPublishRoots: map[string]FileSystemPublishRoot{},
 	S3PublishRoots:         map[string]S3PublishRoot{},
 	SwiftPublishRoots:      map[string]SwiftPublishRoot{},
+	IncomingQueues:         map[string]IncomingQueue{},
 }
 
 // LoadConfig loads configuration from json file
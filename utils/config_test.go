@@ -1,8 +1,11 @@
 package utils
 
 import (
+	"encoding/json"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	. "gopkg.in/check.v1"
 )
@@ -59,7 +62,22 @@ func (s *ConfigSuite) TestSaveConfig(c *C) {
 		"  \"rootDir\": \"/tmp/aptly\",\n"+
 		"  \"downloadConcurrency\": 5,\n"+
 		"  \"downloadSpeedLimit\": 0,\n"+
+		"  \"downloadSchedule\": null,\n"+
+		"  \"downloadQuota\": 0,\n"+
 		"  \"downloadRetries\": 0,\n"+
+		"  \"downloadOptions\": {\n"+
+		"    \"proxy\": \"\",\n"+
+		"    \"caCertFile\": \"\",\n"+
+		"    \"clientCertFile\": \"\",\n"+
+		"    \"clientKeyFile\": \"\",\n"+
+		"    \"username\": \"\",\n"+
+		"    \"password\": \"\",\n"+
+		"    \"passwordFile\": \"\",\n"+
+		"    \"passwordEnv\": \"\",\n"+
+		"    \"headers\": null\n"+
+		"  },\n"+
+		"  \"mirrorUpdateWebhook\": \"\",\n"+
+		"  \"poolPeers\": null,\n"+
 		"  \"databaseOpenAttempts\": 5,\n"+
 		"  \"architectures\": null,\n"+
 		"  \"dependencyFollowSuggests\": false,\n"+
@@ -72,9 +90,11 @@ func (s *ConfigSuite) TestSaveConfig(c *C) {
 		"  \"gpgProvider\": \"gpg\",\n"+
 		"  \"downloadSourcePackages\": false,\n"+
 		"  \"skipLegacyPool\": false,\n"+
+		"  \"poolGracePeriod\": 0,\n"+
 		"  \"ppaDistributorID\": \"\",\n"+
 		"  \"ppaCodename\": \"\",\n"+
 		"  \"skipContentsPublishing\": false,\n"+
+		"  \"uploadersUserHeader\": \"\",\n"+
 		"  \"FileSystemPublishEndpoints\": {\n"+
 		"    \"test\": {\n"+
 		"      \"rootDir\": \"/opt/aptly-publish\",\n"+
@@ -114,8 +134,88 @@ func (s *ConfigSuite) TestSaveConfig(c *C) {
 		"      \"prefix\": \"\",\n"+
 		"      \"container\": \"repo\"\n"+
 		"    }\n"+
-		"  }\n"+
+		"  },\n"+
+		"  \"IncomingQueues\": null\n"+
 		"}")
 }
 
 const configFile = `{"rootDir": "/opt/aptly/", "downloadConcurrency": 33, "databaseOpenAttempts": 33}`
+
+func (s *ConfigSuite) TestBandwidthWindow(c *C) {
+	// 2024-01-01 is Monday
+	at := func(day int, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.Local)
+	}
+
+	business := BandwidthWindow{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "18:00", Limit: 10240}
+	c.Check(business.Validate(), IsNil)
+	c.Check(business.Matches(at(1, 9, 0)), Equals, true)
+	c.Check(business.Matches(at(1, 17, 59)), Equals, true)
+	c.Check(business.Matches(at(1, 18, 0)), Equals, false)
+	c.Check(business.Matches(at(1, 8, 59)), Equals, false)
+	c.Check(business.Matches(at(6, 12, 0)), Equals, false)
+
+	night := BandwidthWindow{Days: []string{"Fri"}, Start: "22:00", End: "06:00"}
+	c.Check(night.Matches(at(5, 23, 0)), Equals, true)
+	c.Check(night.Matches(at(6, 5, 0)), Equals, true)
+	c.Check(night.Matches(at(6, 23, 0)), Equals, false)
+	c.Check(night.Matches(at(5, 5, 0)), Equals, false)
+	c.Check(night.Matches(at(5, 12, 0)), Equals, false)
+
+	c.Check(BandwidthWindow{Start: "9am", End: "18:00"}.Validate(), ErrorMatches, "invalid time.*")
+	c.Check(BandwidthWindow{Days: []string{"someday"}, Start: "09:00", End: "18:00"}.Validate(), ErrorMatches, "invalid day of week.*")
+}
+
+func (s *ConfigSuite) TestDownloadOptionsMerge(c *C) {
+	global := DownloadOptions{Proxy: "http://proxy:3128", Username: "global", Password: "secret", Headers: map[string]string{"X-A": "1", "X-B": "2"}}
+	c.Check(global.IsEmpty(), Equals, false)
+	c.Check(DownloadOptions{}.IsEmpty(), Equals, true)
+
+	c.Check(global.Merge(DownloadOptions{}), DeepEquals, global)
+	c.Check(global.Merge(DownloadOptions{Username: "mirror", CACertFile: "/ca.pem", Headers: map[string]string{"X-B": "3"}}), DeepEquals,
+		DownloadOptions{Proxy: "http://proxy:3128", CACertFile: "/ca.pem", Username: "mirror", Headers: map[string]string{"X-A": "1", "X-B": "3"}})
+	c.Check(global.Headers, DeepEquals, map[string]string{"X-A": "1", "X-B": "2"})
+}
+
+func (s *ConfigSuite) TestDownloadOptionsPassword(c *C) {
+	password, err := DownloadOptions{Password: "secret"}.GetPassword()
+	c.Check(err, IsNil)
+	c.Check(password, Equals, "secret")
+
+	passwordFile := filepath.Join(c.MkDir(), "password")
+	c.Assert(ioutil.WriteFile(passwordFile, []byte("from-file\n"), 0600), IsNil)
+
+	password, err = DownloadOptions{PasswordFile: passwordFile}.GetPassword()
+	c.Check(err, IsNil)
+	c.Check(password, Equals, "from-file")
+
+	_, err = DownloadOptions{PasswordFile: "/no/such/file"}.GetPassword()
+	c.Check(err, ErrorMatches, "unable to read password file: .*")
+
+	os.Setenv("APTLY_TEST_PASSWORD", "from-env")
+	defer os.Unsetenv("APTLY_TEST_PASSWORD")
+
+	password, err = DownloadOptions{PasswordEnv: "APTLY_TEST_PASSWORD"}.GetPassword()
+	c.Check(err, IsNil)
+	c.Check(password, Equals, "from-env")
+
+	_, err = DownloadOptions{PasswordEnv: "APTLY_TEST_NO_PASSWORD"}.GetPassword()
+	c.Check(err, ErrorMatches, "environment variable APTLY_TEST_NO_PASSWORD with password is not set")
+
+	password, err = DownloadOptions{}.GetPassword()
+	c.Check(err, IsNil)
+	c.Check(password, Equals, "")
+}
+
+func (s *ConfigSuite) TestDownloadOptionsMarshalJSON(c *C) {
+	options := DownloadOptions{Username: "aptly", Password: "secret"}
+
+	encoded, err := json.Marshal(options)
+	c.Assert(err, IsNil)
+	c.Check(string(encoded), Matches, `.*"username":"aptly","password":"\*\*\*\*\*\*".*`)
+	c.Check(options.Password, Equals, "secret")
+
+	encoded, err = json.Marshal(DownloadOptions{})
+	c.Assert(err, IsNil)
+	c.Check(string(encoded), Matches, `.*"password":"".*`)
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"context"
+	"time"
+)
+
+// DirWatcher waits for new files to appear in the set of directories
+type DirWatcher interface {
+	// Wait blocks until some files are created (or moved) in watched directories
+	// (including subdirectories), or until timeout expires, whichever happens first;
+	// if ctx is cancelled, Wait returns ctx.Err()
+	Wait(ctx context.Context, timeout time.Duration) error
+	// Close releases resources allocated by watcher
+	Close() error
+}
+
+// NewDirWatcher creates DirWatcher for the list of directories
+//
+// On platforms which support it, kernel notifications are used, otherwise
+// watcher falls back to polling (Wait always waits for the full timeout)
+func NewDirWatcher(dirs []string) DirWatcher {
+	watcher, err := newNotifyDirWatcher(dirs)
+	if err != nil {
+		return &pollingDirWatcher{}
+	}
+
+	return watcher
+}
+
+// pollingDirWatcher is fallback implementation of DirWatcher
+type pollingDirWatcher struct{}
+
+func (w *pollingDirWatcher) Wait(ctx context.Context, timeout time.Duration) error {
+	select {
+	case <-time.After(timeout):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+func (w *pollingDirWatcher) Close() error {
+	return nil
+}
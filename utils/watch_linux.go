@@ -0,0 +1,137 @@
+//go:build linux
+// +build linux
+
+package utils
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// inotifyDirWatcher implements DirWatcher with Linux inotify
+//
+// inotify watches are not recursive, so every subdirectory gets its own watch,
+// including subdirectories created after watcher has been started
+type inotifyDirWatcher struct {
+	fd     int
+	paths  map[int32]string
+	events chan struct{}
+}
+
+const inotifyDirMask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_CREATE | syscall.IN_ONLYDIR
+
+func newNotifyDirWatcher(dirs []string) (DirWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &inotifyDirWatcher{
+		fd:     fd,
+		paths:  make(map[int32]string),
+		events: make(chan struct{}, 1),
+	}
+
+	for _, dir := range dirs {
+		err = w.addTree(dir)
+		if err != nil {
+			syscall.Close(fd)
+			return nil, err
+		}
+	}
+
+	go w.loop()
+
+	return w, nil
+}
+
+// addTree adds watches for directory and all its subdirectories
+func (w *inotifyDirWatcher) addTree(root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path != root && os.IsNotExist(err) {
+				// directory removed while walking
+				return nil
+			}
+			return err
+		}
+
+		if !info.IsDir() {
+			return nil
+		}
+
+		wd, err := syscall.InotifyAddWatch(w.fd, path, inotifyDirMask)
+		if err != nil {
+			return err
+		}
+		w.paths[int32(wd)] = path
+
+		return nil
+	})
+}
+
+func (w *inotifyDirWatcher) loop() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+
+	for {
+		n, err := syscall.Read(w.fd, buf)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || n <= 0 {
+			close(w.events)
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+			if event.Mask&syscall.IN_IGNORED != 0 {
+				delete(w.paths, event.Wd)
+				continue
+			}
+
+			if event.Mask&syscall.IN_ISDIR != 0 && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+				parent, ok := w.paths[event.Wd]
+				if ok {
+					name := strings.TrimRight(string(nameBytes), "\x00")
+
+					// errors are ignored: directory might be gone already, it would be rescanned anyway
+					_ = w.addTree(filepath.Join(parent, name))
+				}
+			}
+		}
+
+		// coalesce events, consumer rescans directories anyway
+		select {
+		case w.events <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (w *inotifyDirWatcher) Wait(ctx context.Context, timeout time.Duration) error {
+	select {
+	case _, ok := <-w.events:
+		if !ok {
+			// watcher is broken, degrade to polling
+			return (&pollingDirWatcher{}).Wait(ctx, timeout)
+		}
+	case <-time.After(timeout):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+func (w *inotifyDirWatcher) Close() error {
+	return syscall.Close(w.fd)
+}
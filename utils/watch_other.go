@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package utils
+
+import (
+	"errors"
+)
+
+func newNotifyDirWatcher(dirs []string) (DirWatcher, error) {
+	return nil, errors.New("directory notifications are not supported")
+}
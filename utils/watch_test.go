@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type WatchSuite struct{}
+
+var _ = Suite(&WatchSuite{})
+
+func (s *WatchSuite) TestTimeout(c *C) {
+	w := NewDirWatcher([]string{c.MkDir()})
+	defer w.Close()
+
+	start := time.Now()
+	c.Check(w.Wait(context.Background(), 50*time.Millisecond), IsNil)
+	c.Check(time.Since(start) >= 50*time.Millisecond, Equals, true)
+}
+
+func (s *WatchSuite) TestCancel(c *C) {
+	for _, w := range []DirWatcher{NewDirWatcher([]string{c.MkDir()}), &pollingDirWatcher{}} {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+		}()
+
+		start := time.Now()
+		c.Check(w.Wait(ctx, 10*time.Second), Equals, context.Canceled)
+		c.Check(time.Since(start) < 10*time.Second, Equals, true)
+		c.Check(w.Close(), IsNil)
+	}
+}
+
+func (s *WatchSuite) TestMissingDirFallback(c *C) {
+	w := NewDirWatcher([]string{filepath.Join(c.MkDir(), "missing")})
+	defer w.Close()
+
+	c.Check(w, FitsTypeOf, &pollingDirWatcher{})
+	c.Check(w.Wait(context.Background(), time.Millisecond), IsNil)
+}
+
+func (s *WatchSuite) TestNewFile(c *C) {
+	dir := c.MkDir()
+	w := NewDirWatcher([]string{dir})
+	defer w.Close()
+
+	if _, ok := w.(*pollingDirWatcher); ok {
+		c.Skip("no directory notifications on this platform")
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ioutil.WriteFile(filepath.Join(dir, "a.changes"), []byte("test"), 0644)
+	}()
+
+	start := time.Now()
+	c.Check(w.Wait(context.Background(), 10*time.Second), IsNil)
+	c.Check(time.Since(start) < 10*time.Second, Equals, true)
+}
+
+func (s *WatchSuite) TestNewFileInSubdirectory(c *C) {
+	dir := c.MkDir()
+	c.Assert(os.MkdirAll(filepath.Join(dir, "existing"), 0755), IsNil)
+
+	w := NewDirWatcher([]string{dir})
+	defer w.Close()
+
+	if _, ok := w.(*pollingDirWatcher); ok {
+		c.Skip("no directory notifications on this platform")
+	}
+
+	// file in subdirectory which existed when watcher was started
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ioutil.WriteFile(filepath.Join(dir, "existing", "a.changes"), []byte("test"), 0644)
+	}()
+
+	start := time.Now()
+	c.Check(w.Wait(context.Background(), 10*time.Second), IsNil)
+	c.Check(time.Since(start) < 10*time.Second, Equals, true)
+
+	// subdirectory created after watcher was started
+	c.Assert(os.MkdirAll(filepath.Join(dir, "new"), 0755), IsNil)
+	c.Check(w.Wait(context.Background(), 10*time.Second), IsNil)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ioutil.WriteFile(filepath.Join(dir, "new", "b.changes"), []byte("test"), 0644)
+	}()
+
+	start = time.Now()
+	c.Check(w.Wait(context.Background(), 10*time.Second), IsNil)
+	c.Check(time.Since(start) < 10*time.Second, Equals, true)
+}